@@ -0,0 +1,49 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"encoding/binary"
+	"math/rand"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// hashSource is a math/rand source whose n-th output is drawn from blake2b(seed || n)
+type hashSource struct {
+	seed    [32]byte
+	counter uint64
+}
+
+// NewHashSource returns a source of pseudo-random numbers derived from the seed bytes
+// The same seed yields the same sequence on every platform and Go version, unlike a rand.NewSource seeded with an
+// int64 whose algorithm is up to the standard library. The source is not safe for concurrent use.
+func NewHashSource(seed []byte) rand.Source64 {
+	return &hashSource{seed: blake2b.Sum256(seed)}
+}
+
+// Uint64 returns the next pseudo-random 64-bit value
+func (s *hashSource) Uint64() uint64 {
+	var b [40]byte
+	copy(b[:], s.seed[:])
+	binary.BigEndian.PutUint64(b[32:], s.counter)
+	s.counter++
+	digest := blake2b.Sum256(b[:])
+	return binary.BigEndian.Uint64(digest[:8])
+}
+
+// Int63 returns the next pseudo-random non-negative 63-bit value
+func (s *hashSource) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// Seed restarts the source from the big-endian bytes of the seed
+func (s *hashSource) Seed(seed int64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(seed))
+	s.seed, s.counter = blake2b.Sum256(b[:]), 0
+}
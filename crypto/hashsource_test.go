@@ -0,0 +1,37 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashSource(t *testing.T) {
+	a := rand.New(NewHashSource([]byte("seed")))
+	b := rand.New(NewHashSource([]byte("seed")))
+	c := rand.New(NewHashSource([]byte("seeds")))
+	same := true
+	for i := 0; i < 100; i++ {
+		x := a.Int63()
+		assert.True(t, x >= 0)
+		assert.Equal(t, x, b.Int63())
+		same = same && x == c.Int63()
+	}
+	assert.False(t, same)
+	assert.Equal(t, a.Perm(10), b.Perm(10))
+
+	// reseeding restarts the sequence
+	s := NewHashSource(nil)
+	s.Seed(7)
+	first := s.Uint64()
+	s.Uint64()
+	s.Seed(7)
+	assert.Equal(t, first, s.Uint64())
+}
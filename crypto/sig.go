@@ -21,6 +21,11 @@ func NewKeyPair() ([]byte, []byte, error) {
 	return pub, priv, nil
 }
 
+// IsValidPublicKey checks the public key has the ed25519 public key size, the curve point is not checked for now.
+func IsValidPublicKey(pub []byte) bool {
+	return len(pub) == ed25519.PublicKeySize
+}
+
 // Sign wraps ed25519.Sign() for now.
 func Sign(priv []byte, msg []byte) []byte {
 	p := ed25519.PrivateKey(priv)
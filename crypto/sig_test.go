@@ -25,3 +25,12 @@ func TestSignVerify(t *testing.T) {
 	wrongMessage := []byte("wrong message")
 	assert.False(t, Verify(pub, wrongMessage, sig))
 }
+
+func TestIsValidPublicKey(t *testing.T) {
+	pub, _, err := NewKeyPair()
+	assert.Nil(t, err)
+	assert.True(t, IsValidPublicKey(pub))
+	assert.False(t, IsValidPublicKey(pub[:len(pub)-1]))
+	assert.False(t, IsValidPublicKey(append(pub, 0)))
+	assert.False(t, IsValidPublicKey(nil))
+}
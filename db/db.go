@@ -7,6 +7,8 @@
 package db
 
 import (
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -39,6 +41,39 @@ type KVStore interface {
 	Delete(string, []byte) error
 }
 
+// Compacter is implemented by KV stores that can reclaim the disk space of deleted records
+type Compacter interface {
+	// Compact compacts the underlying storage
+	Compact() error
+}
+
+// NamespaceDeleter is implemented by KV stores that can remove all records of a namespace at once
+type NamespaceDeleter interface {
+	// DeleteNamespace deletes every record in the namespace, leaving other namespaces untouched
+	DeleteNamespace(string) error
+}
+
+// Walker is implemented by KV stores that can read the records of a namespace in key order
+type Walker interface {
+	// Walk calls fn with the records of the namespace in ascending key order until fn returns false, the key and value
+	// passed to fn are only valid during the call
+	Walk(string, func([]byte, []byte) bool) error
+}
+
+// ReadOnlyStore is implemented by KV stores that may be opened read-only
+type ReadOnlyStore interface {
+	// ReadOnly returns whether the store rejects writes
+	ReadOnly() bool
+}
+
+// Syncer is implemented by KV stores that can defer flushing their writes to disk
+type Syncer interface {
+	// SetNoSync sets whether writes return before they are flushed to disk, leaving it to Sync or the OS
+	SetNoSync(bool)
+	// Sync flushes the writes made so far to disk
+	Sync() error
+}
+
 const (
 	keyDelimiter = "."
 )
@@ -96,6 +131,41 @@ func (m *memKVStore) Delete(namespace string, key []byte) error {
 	return nil
 }
 
+// DeleteNamespace deletes all records in the namespace
+func (m *memKVStore) DeleteNamespace(namespace string) error {
+	prefix := namespace + keyDelimiter
+	m.data.Range(func(k, _ interface{}) bool {
+		if strings.HasPrefix(k.(string), prefix) {
+			m.data.Delete(k)
+		}
+		return true
+	})
+	return nil
+}
+
+// Walk calls fn with the records of the namespace in ascending key order until fn returns false
+func (m *memKVStore) Walk(namespace string, fn func([]byte, []byte) bool) error {
+	prefix := namespace + keyDelimiter
+	var keys []string
+	m.data.Range(func(k, _ interface{}) bool {
+		if strings.HasPrefix(k.(string), prefix) {
+			keys = append(keys, k.(string))
+		}
+		return true
+	})
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, ok := m.data.Load(k)
+		if !ok {
+			continue
+		}
+		if !fn([]byte(k[len(prefix):]), value.([]byte)) {
+			break
+		}
+	}
+	return nil
+}
+
 const (
 	fileMode = 0600
 )
@@ -106,6 +176,7 @@ type boltDB struct {
 	db      *bolt.DB
 	path    string
 	options *bolt.Options
+	noSync  bool
 }
 
 // NewBoltDB instantiates a boltdb based KV store
@@ -119,6 +190,7 @@ func (b *boltDB) Start() error {
 	if err != nil {
 		return err
 	}
+	db.NoSync = b.noSync
 	b.db = db
 	return nil
 }
@@ -128,6 +200,24 @@ func (b *boltDB) Stop() error {
 	return b.db.Close()
 }
 
+// SetNoSync sets whether write transactions commit without fsync, an OS crash may then lose or corrupt them until Sync
+func (b *boltDB) SetNoSync(noSync bool) {
+	b.noSync = noSync
+	if b.db != nil {
+		b.db.NoSync = noSync
+	}
+}
+
+// Sync fsyncs the BoltDB file
+func (b *boltDB) Sync() error {
+	return b.db.Sync()
+}
+
+// ReadOnly returns whether the BoltDB is opened read-only
+func (b *boltDB) ReadOnly() bool {
+	return b.options != nil && b.options.ReadOnly
+}
+
 // Put inserts a <key, value> record
 func (b *boltDB) Put(namespace string, key []byte, value []byte) error {
 	return b.db.Update(func(tx *bolt.Tx) error {
@@ -194,7 +284,40 @@ func (b *boltDB) Get(namespace string, key []byte) ([]byte, error) {
 
 // Delete deletes a record
 func (b *boltDB) Delete(namespace string, key []byte) error {
-	return nil
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.Delete(key)
+	})
+}
+
+// DeleteNamespace deletes the bucket of the namespace
+func (b *boltDB) DeleteNamespace(namespace string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(namespace)); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		return nil
+	})
+}
+
+// Walk calls fn with the records of the namespace in ascending key order until fn returns false
+func (b *boltDB) Walk(namespace string, fn func([]byte, []byte) bool) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(namespace))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if !fn(k, v) {
+				break
+			}
+		}
+		return nil
+	})
 }
 
 //======================================
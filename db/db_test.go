@@ -11,6 +11,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/boltdb/bolt"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/iotexproject/iotex-core/common/utils"
@@ -58,6 +59,16 @@ func TestKVStorePutGet(t *testing.T) {
 		value, err = kvStore.Get(bucket, testK[0])
 		assert.Nil(err)
 		assert.Equal(testV[0], value)
+
+		err = kvStore.Delete(bucket, testK[0])
+		assert.Nil(err)
+		value, err = kvStore.Get(bucket, testK[0])
+		assert.NotNil(err)
+		assert.Nil(value)
+		err = kvStore.PutIfNotExists(bucket, testK[0], testV[1])
+		assert.Nil(err)
+		err = kvStore.Delete("test_ns_1", testK[0])
+		assert.Nil(err)
 	}
 
 	t.Run("In-memory KV Store", func(t *testing.T) {
@@ -79,6 +90,175 @@ func TestKVStorePutGet(t *testing.T) {
 	})
 }
 
+func TestDeleteNamespace(t *testing.T) {
+	testDeleteNamespace := func(kvStore KVStore, t *testing.T) {
+		assert := assert.New(t)
+
+		assert.Nil(kvStore.Start())
+		defer func() {
+			assert.Nil(kvStore.Stop())
+		}()
+
+		assert.Nil(kvStore.BatchPut(bucket, testK[:], testV[:]))
+		assert.Nil(kvStore.Put("test_ns_1", testK[0], testV[0]))
+		deleter, ok := kvStore.(NamespaceDeleter)
+		assert.True(ok)
+		assert.Nil(deleter.DeleteNamespace(bucket))
+		for _, k := range testK {
+			_, err := kvStore.Get(bucket, k)
+			assert.NotNil(err)
+		}
+		value, err := kvStore.Get("test_ns_1", testK[0])
+		assert.Nil(err)
+		assert.Equal(testV[0], value)
+		// deleting a namespace without records is fine
+		assert.Nil(deleter.DeleteNamespace(bucket))
+	}
+
+	t.Run("In-memory KV Store", func(t *testing.T) {
+		testDeleteNamespace(NewMemKVStore(), t)
+	})
+
+	path := "/tmp/test-kv-store-" + string(rand.Int())
+	t.Run("Bolt DB", func(t *testing.T) {
+		cleanup := func() {
+			if utils.FileExists(path) {
+				err := os.Remove(path)
+				assert.Nil(t, err)
+			}
+		}
+
+		cleanup()
+		defer cleanup()
+		testDeleteNamespace(NewBoltDB(path, nil), t)
+	})
+}
+
+func TestWalk(t *testing.T) {
+	testWalk := func(kvStore KVStore, t *testing.T) {
+		assert := assert.New(t)
+
+		assert.Nil(kvStore.Start())
+		defer func() {
+			assert.Nil(kvStore.Stop())
+		}()
+
+		walker, ok := kvStore.(Walker)
+		assert.True(ok)
+		// walking a namespace without records calls nothing
+		assert.Nil(walker.Walk(bucket, func([]byte, []byte) bool {
+			assert.Fail("walked an empty namespace")
+			return true
+		}))
+
+		// the records are walked in key order, other namespaces are skipped
+		assert.Nil(kvStore.BatchPut(bucket, [][]byte{testK[2], testK[0], testK[1]},
+			[][]byte{testV[2], testV[0], testV[1]}))
+		assert.Nil(kvStore.Put("test_ns_1", testK[0], testV[0]))
+		var keys, values [][]byte
+		assert.Nil(walker.Walk(bucket, func(k, v []byte) bool {
+			keys = append(keys, append([]byte(nil), k...))
+			values = append(values, append([]byte(nil), v...))
+			return true
+		}))
+		assert.Equal(testK[:], keys)
+		assert.Equal(testV[:], values)
+
+		// the walk stops once fn returns false
+		keys = nil
+		assert.Nil(walker.Walk(bucket, func(k, _ []byte) bool {
+			keys = append(keys, append([]byte(nil), k...))
+			return len(keys) < 2
+		}))
+		assert.Equal(testK[:2], keys)
+	}
+
+	t.Run("In-memory KV Store", func(t *testing.T) {
+		testWalk(NewMemKVStore(), t)
+	})
+
+	path := "/tmp/test-kv-store-" + string(rand.Int())
+	t.Run("Bolt DB", func(t *testing.T) {
+		cleanup := func() {
+			if utils.FileExists(path) {
+				err := os.Remove(path)
+				assert.Nil(t, err)
+			}
+		}
+
+		cleanup()
+		defer cleanup()
+		testWalk(NewBoltDB(path, nil), t)
+	})
+}
+
+func TestBoltDBSync(t *testing.T) {
+	assert := assert.New(t)
+	path := "/tmp/test-kv-store-" + string(rand.Int())
+	cleanup := func() {
+		if utils.FileExists(path) {
+			assert.Nil(os.Remove(path))
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	// set before Start, kept across the open
+	kvStore := NewBoltDB(path, nil)
+	syncer, ok := kvStore.(Syncer)
+	assert.True(ok)
+	syncer.SetNoSync(true)
+	assert.Nil(kvStore.Start())
+	assert.True(kvStore.(*boltDB).db.NoSync)
+	assert.Nil(kvStore.BatchPut(bucket, testK[:], testV[:]))
+	assert.Nil(syncer.Sync())
+	syncer.SetNoSync(false)
+	assert.False(kvStore.(*boltDB).db.NoSync)
+	assert.Nil(kvStore.Stop())
+
+	// the writes are there after reopening
+	kvStore = NewBoltDB(path, nil)
+	assert.Nil(kvStore.Start())
+	defer func() {
+		assert.Nil(kvStore.Stop())
+	}()
+	for i, k := range testK {
+		value, err := kvStore.Get(bucket, k)
+		assert.Nil(err)
+		assert.Equal(testV[i], value)
+	}
+}
+
+func TestBoltDBReadOnly(t *testing.T) {
+	assert := assert.New(t)
+	path := "/tmp/test-kv-store-" + string(rand.Int())
+	cleanup := func() {
+		if utils.FileExists(path) {
+			assert.Nil(os.Remove(path))
+		}
+	}
+	cleanup()
+	defer cleanup()
+
+	kvStore := NewBoltDB(path, nil)
+	assert.False(kvStore.(ReadOnlyStore).ReadOnly())
+	assert.Nil(kvStore.Start())
+	assert.Nil(kvStore.BatchPut(bucket, testK[:], testV[:]))
+	assert.Nil(kvStore.Stop())
+
+	// the records read, writes are refused
+	kvStore = NewBoltDB(path, &bolt.Options{ReadOnly: true})
+	assert.True(kvStore.(ReadOnlyStore).ReadOnly())
+	assert.Nil(kvStore.Start())
+	defer func() {
+		assert.Nil(kvStore.Stop())
+	}()
+	value, err := kvStore.Get(bucket, testK[0])
+	assert.Nil(err)
+	assert.Equal(testV[0], value)
+	assert.NotNil(kvStore.Put(bucket, testK[0], testV[1]))
+}
+
 func TestBatchRollback(t *testing.T) {
 	testBatchRollback := func(kvStore KVStore, t *testing.T) {
 		assert := assert.New(t)
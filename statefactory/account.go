@@ -0,0 +1,379 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/common/utils"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+var (
+	// accountCountKey is the trie key of the number of accounts
+	accountCountKey = func() []byte {
+		digest := blake2b.Sum256([]byte("accounts"))
+		return digest[7:27]
+	}()
+
+	// ErrAddressInUse is the error that the address already has an account
+	ErrAddressInUse = errors.New("the address already has an account")
+)
+
+// DeleteState removes the account, it is also dropped from the candidate and voter lists
+// A deleted account is not recreated by balance or nonce changes, which fail with ErrAccountNotExist. CreateState or a
+// transfer to it recreates it as a new account: nonce 0, no locked stake, no votes cast or received, not a candidate.
+// The account stays locked until it is out of the lists and the count, so a concurrent read sees it either whole or
+// gone.
+func (sf *stateFactory) DeleteState(addr *iotxaddress.Address) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	key := iotxaddress.HashPubKey(addr.PublicKey)
+	unlock := sf.lockAccounts(key)
+	defer unlock()
+	if _, err := sf.getState(addr); err != nil {
+		return err
+	}
+	if err := sf.trie.Delete(key); err != nil {
+		return err
+	}
+	sf.touch(addr, ChangeDeleted)
+	if err := sf.removeFromAddressList(candidateListKey, addr); err != nil {
+		return err
+	}
+	if err := sf.removeFromAddressList(voterListKey, addr); err != nil {
+		return err
+	}
+	return sf.addAccountCount(-1)
+}
+
+// ResetAccount zeroes the balances, of every asset, and nonce of the account and clears its allowances, candidacy, votes
+// and vesting
+// Unlike DeleteState the account stays in the trie, so it still exists and the account count is unchanged. Its votes
+// are unwound as withdrawing them does: the votes it cast leave the Voters and VotingWeight of their candidates, and
+// the votes it received are returned to their voters' VotedWeight. All the accounts change together or not at all.
+func (sf *stateFactory) ResetAccount(addr *iotxaddress.Address) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(addr); err != nil {
+		return err
+	}
+	candidates, err := sf.candidates()
+	if err != nil {
+		return err
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return err
+	}
+	keys := [][]byte{iotxaddress.HashPubKey(addr.PublicKey)}
+	for _, list := range [][]*iotxaddress.Address{candidates, voters} {
+		for _, a := range list {
+			keys = append(keys, iotxaddress.HashPubKey(a.PublicKey))
+		}
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	load := func(addr *iotxaddress.Address) (*State, error) {
+		key := AccountKeyOf(addr)
+		if state, ok := states[key]; ok {
+			return state, nil
+		}
+		state, err := sf.getState(addr)
+		if err != nil {
+			return nil, err
+		}
+		states[key], addrs[key] = state, addr
+		return state, nil
+	}
+	state, err := load(addr)
+	if err != nil {
+		return err
+	}
+	// only the account and the accounts a vote was withdrawn from or returned to are written
+	changed := map[AccountKey]bool{AccountKeyOf(addr): true}
+	key := voterKey(addr)
+	for _, candidate := range candidates {
+		cstate, err := load(candidate)
+		if err != nil {
+			return err
+		}
+		if vote, ok := cstate.Voters[key]; ok {
+			delete(cstate.Voters, key)
+			cstate.VotingWeight = new(big.Int).Sub(cstate.VotingWeight, vote)
+			changed[AccountKeyOf(candidate)] = true
+		}
+	}
+	for _, voter := range voters {
+		vote, ok := state.Voters[voterKey(voter)]
+		if !ok {
+			continue
+		}
+		vstate, err := load(voter)
+		if err != nil {
+			return err
+		}
+		vstate.VotedWeight = new(big.Int).Sub(vstate.votedWeight(), vote)
+		changed[AccountKeyOf(voter)] = true
+	}
+	state.Nonce = 0
+	state.Balance = big.NewInt(0)
+	state.LockedBalance = big.NewInt(0)
+	state.Assets = nil
+	state.Allowances = nil
+	state.IsCandidate = false
+	state.VotingWeight = big.NewInt(0)
+	state.Voters = nil
+	state.VotedWeight = nil
+	state.VestingSchedule = nil
+	for key := range states {
+		if !changed[key] {
+			delete(states, key)
+		}
+	}
+	if err := sf.writeStates(states, addrs, nil); err != nil {
+		return err
+	}
+	if err := sf.removeFromAddressList(candidateListKey, addr); err != nil {
+		return err
+	}
+	return sf.removeFromAddressList(voterListKey, addr)
+}
+
+// RotateKey moves the account of the old address to the new address, keeping balance, nonce, candidacy and votes
+// Votes the account received stay with it since they are kept in its own state. Votes the account cast are keyed by
+// the voter's public key hash in each candidate's Voters, and allowances granted to it by the spender key in each
+// owner's Allowances, those are re-keyed to the new address so the stake and the approvals still belong to it. The
+// owners are found by walking the trie as of the last commit and the accounts changed since, so the trie must be able
+// to take snapshots. The accounts involved are locked and written together, all the checks happen before any write.
+func (sf *stateFactory) RotateKey(oldAddr, newAddr *iotxaddress.Address) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(oldAddr, newAddr); err != nil {
+		return err
+	}
+	candidates, err := sf.candidates()
+	if err != nil {
+		return err
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return err
+	}
+	owners, err := sf.allowanceOwners(spenderKey(oldAddr))
+	if err != nil {
+		return err
+	}
+	oldKey, newKey := AccountKeyOf(oldAddr), AccountKeyOf(newAddr)
+	keys := [][]byte{oldKey.Bytes(), newKey.Bytes()}
+	for _, list := range [][]*iotxaddress.Address{candidates, owners} {
+		for _, a := range list {
+			keys = append(keys, iotxaddress.HashPubKey(a.PublicKey))
+		}
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+
+	state, err := sf.getState(oldAddr)
+	if err != nil {
+		return err
+	}
+	if _, err := sf.getState(newAddr); err == nil {
+		return ErrAddressInUse
+	} else if err != ErrAccountNotExist {
+		return err
+	}
+	isVoter := false
+	for _, voter := range voters {
+		if bytes.Equal(voter.PublicKey, oldAddr.PublicKey) {
+			isVoter = true
+			break
+		}
+	}
+
+	// only the moved account and the candidates and owners holding a key of the old address are written
+	states := map[AccountKey]*State{oldKey: state}
+	addrs := map[AccountKey]*iotxaddress.Address{}
+	for _, list := range [][]*iotxaddress.Address{candidates, owners} {
+		for _, a := range list {
+			key := AccountKeyOf(a)
+			if _, ok := states[key]; ok {
+				continue
+			}
+			s, err := sf.getState(a)
+			if err == ErrAccountNotExist {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			states[key], addrs[key] = s, a
+		}
+	}
+	for key, s := range states {
+		voted, approved := rekeyVoter(s, oldAddr, newAddr), rekeySpender(s, oldAddr, newAddr)
+		if !voted && !approved && key != oldKey {
+			delete(states, key)
+		}
+	}
+	delete(states, oldKey)
+	state.Address = newAddr
+	states[newKey], addrs[newKey] = state, newAddr
+	if err := sf.writeChanges(states, addrs, map[AccountKey]bool{newKey: true},
+		map[AccountKey]*iotxaddress.Address{oldKey: oldAddr}); err != nil {
+		return err
+	}
+	if state.IsCandidate {
+		if err := sf.removeFromAddressList(candidateListKey, oldAddr); err != nil {
+			return err
+		}
+		if err := sf.addToAddressList(candidateListKey, newAddr); err != nil {
+			return err
+		}
+	}
+	if !isVoter {
+		return nil
+	}
+	if err := sf.removeFromAddressList(voterListKey, oldAddr); err != nil {
+		return err
+	}
+	return sf.addToAddressList(voterListKey, newAddr)
+}
+
+// rekeyVoter moves the votes of the old voter in the candidate's Voters to the new voter, it returns whether there
+// were any
+func rekeyVoter(state *State, oldAddr, newAddr *iotxaddress.Address) bool {
+	v, ok := state.Voters[voterKey(oldAddr)]
+	if ok {
+		delete(state.Voters, voterKey(oldAddr))
+		state.Voters[voterKey(newAddr)] = v
+	}
+	return ok
+}
+
+// rekeySpender moves the allowance of the old spender in the owner's Allowances to the new spender, it returns
+// whether there was one
+func rekeySpender(state *State, oldAddr, newAddr *iotxaddress.Address) bool {
+	a, ok := state.Allowances[spenderKey(oldAddr)]
+	if ok {
+		delete(state.Allowances, spenderKey(oldAddr))
+		state.Allowances[spenderKey(newAddr)] = a
+	}
+	return ok
+}
+
+// AccountCount returns the number of accounts
+// The count is kept in the trie next to the accounts and updated as accounts are created and deleted. A state written
+// before the count was kept has no count yet, its accounts are then counted as of the last commit, and the count is
+// stored with the next account created or deleted. ErrNotSupported is returned if they cannot be counted.
+func (sf *stateFactory) AccountCount() (uint64, error) {
+	if err := sf.checkOpen(); err != nil {
+		return 0, err
+	}
+	return sf.accountCount()
+}
+
+// accountCount returns the number of accounts, recounting them if the count is not stored yet
+func (sf *stateFactory) accountCount() (uint64, error) {
+	value, err := sf.trie.Get(accountCountKey)
+	if errors.Cause(err) == trie.ErrNotExist {
+		return sf.countAccounts()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, errors.Errorf("invalid account count %x", value)
+	}
+	return common.MachineEndian.Uint64(value), nil
+}
+
+// countAccounts counts the accounts as of the last commit
+// A trie that cannot take snapshots cannot be counted, ErrNotSupported is returned rather than a count that may be
+// wrong, so none is ever stored for it.
+func (sf *stateFactory) countAccounts() (uint64, error) {
+	var count uint64
+	err := sf.walkAccounts(func(*State) error {
+		count++
+		return nil
+	})
+	if err == errNoSnapshots {
+		return 0, errors.Wrap(ErrNotSupported, "counting the accounts of a trie without snapshots")
+	}
+	return count, err
+}
+
+// walkAccounts calls fn with every account of the trie as of the last commit, in key order
+// An account is a State stored under the public key hash of its own Address. The walk fails with errNoSnapshots if
+// the trie cannot take snapshots.
+func (sf *stateFactory) walkAccounts(fn func(*State) error) error {
+	s, ok := sf.trie.(interface {
+		Snapshot() (*trie.Snapshot, error)
+	})
+	if !ok {
+		return errNoSnapshots
+	}
+	snapshot, err := s.Snapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+	if err := snapshot.Walk(func(key, value []byte) error {
+		if len(key) != AccountKeyLength {
+			return nil
+		}
+		state, err := bytesToState(value, sf.maxLeafSize)
+		if err != nil || state.Address == nil {
+			// not a State, or one that claims no account
+			return nil
+		}
+		if !bytes.Equal(iotxaddress.HashPubKey(state.Address.PublicKey), key) {
+			return nil
+		}
+		return fn(state)
+	}); err != nil {
+		return err
+	}
+	return snapshot.Release()
+}
+
+// addAccountCount adds the delta to the number of accounts, a delta taking the count below 0 is refused
+func (sf *stateFactory) addAccountCount(delta int64) error {
+	sf.countMu.Lock()
+	defer sf.countMu.Unlock()
+	count, err := sf.accountCount()
+	if err != nil {
+		return err
+	}
+	if delta < 0 && uint64(-delta) > count {
+		return errors.Errorf("account count %d cannot drop by %d", count, -delta)
+	}
+	return sf.putAccountCount(uint64(int64(count) + delta))
+}
+
+// putAccountCount stores the number of accounts
+func (sf *stateFactory) putAccountCount(count uint64) error {
+	return sf.trie.Upsert(accountCountKey, utils.Uint64ToBytes(count))
+}
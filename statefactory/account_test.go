@@ -0,0 +1,285 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAccountCount(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), count)
+
+	addrs := createAccounts(t, sf, 5, 10)
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), count)
+
+	// re-creating an existing account does not change the count
+	_, err = sf.CreateState(addrs[0], 20)
+	assert.Nil(t, err)
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), count)
+
+	assert.Nil(t, sf.DeleteState(addrs[1]))
+	assert.Nil(t, sf.DeleteState(addrs[3]))
+	assert.Equal(t, ErrAccountNotExist, sf.DeleteState(addrs[3]))
+	_, err = sf.Balance(addrs[3])
+	assert.Equal(t, ErrAccountNotExist, err)
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// the count is part of the state, a new factory on the same trie sees it
+	sf = NewStateFactory(tr)
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(addr, 0)
+	assert.Nil(t, err)
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), count)
+}
+
+func TestResetAccount(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(10)))
+	sfi := sf.(*stateFactory)
+
+	addrs := createAccounts(t, sf, 3, 100)
+	assert.Nil(t, sf.SetNonce(addrs[0], 4))
+	assert.Nil(t, sf.Lock(addrs[0], big.NewInt(20)))
+	assert.Nil(t, sf.RegisterCandidate(addrs[0], 1))
+	assert.Nil(t, sf.Vote(addrs[0], addrs[0], big.NewInt(50)))
+	assert.Nil(t, sf.Lock(addrs[2], big.NewInt(20)))
+	assert.Nil(t, sf.RegisterCandidate(addrs[2], 1))
+	assert.Nil(t, sf.Vote(addrs[0], addrs[2], big.NewInt(25)))
+	assert.Nil(t, sf.Vote(addrs[1], addrs[0], big.NewInt(30)))
+	assert.Nil(t, sf.Vote(addrs[1], addrs[2], big.NewInt(10)))
+	assert.Nil(t, sf.AddBalanceOf(addrs[0], AssetID(1), big.NewInt(5)))
+	assert.Nil(t, sf.(Approver).Approve(addrs[0], addrs[1], big.NewInt(5)))
+
+	assert.Nil(t, sf.ResetAccount(addrs[0]))
+	state, err := sfi.getState(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), state.Nonce)
+	assert.Equal(t, 0, state.Balance.Sign())
+	assert.Equal(t, 0, state.lockedBalance().Sign())
+	assert.False(t, state.IsCandidate)
+	assert.Equal(t, 0, state.VotingWeight.Sign())
+	assert.Equal(t, 0, len(state.Voters))
+	assert.Equal(t, 0, state.votedWeight().Sign())
+	assert.Nil(t, state.Assets)
+	assert.Nil(t, state.Allowances)
+	candidates, err := sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(candidates))
+
+	// the vote it cast is withdrawn from the other candidate, the vote it received is returned to the voter
+	state, err = sfi.getState(addrs[2])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(10)))
+	assert.Equal(t, 1, len(state.Voters))
+	state, err = sfi.getState(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.votedWeight().Cmp(big.NewInt(10)))
+	repaired, err := sf.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, repaired)
+	overStaked, err := sf.AuditVotingStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(overStaked))
+
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, ErrAccountNotExist, sf.ResetAccount(&iotxaddress.Address{PublicKey: []byte("missing")}))
+}
+
+func TestRecreateDeletedAccount(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(10)))
+	sfi := sf.(*stateFactory)
+
+	addrs := createAccounts(t, sf, 2, 100)
+	candidate, voter := addrs[0], addrs[1]
+	assert.Nil(t, sf.SetNonce(candidate, 4))
+	assert.Nil(t, sf.Lock(candidate, big.NewInt(20)))
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+	assert.Nil(t, sf.Vote(candidate, candidate, big.NewInt(50)))
+	assert.Nil(t, sf.Vote(voter, candidate, big.NewInt(30)))
+	assert.Nil(t, sf.DeleteState(candidate))
+
+	// a deleted account is not recreated by a balance change
+	assert.Equal(t, ErrAccountNotExist, sf.AddBalance(candidate, big.NewInt(7)))
+
+	// a transfer recreates it as a clean externally owned account, nothing of its former state comes back
+	assert.Nil(t, sf.ApplyTransferTx(voter, candidate, big.NewInt(7), 0))
+	state, err := sfi.getState(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), state.Nonce)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(7)))
+	assert.Equal(t, 0, state.lockedBalance().Sign())
+	assert.False(t, state.IsCandidate)
+	assert.Equal(t, uint64(0), state.RegistrationHeight)
+	assert.Equal(t, 0, state.VotingWeight.Sign())
+	assert.Equal(t, 0, len(state.Voters))
+	assert.Equal(t, 0, state.votedWeight().Sign())
+	assert.False(t, state.IsContract())
+	candidates, err := sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(candidates))
+	assert.Equal(t, ErrNotCandidate, sf.Vote(voter, candidate, big.NewInt(1)))
+}
+
+func TestRotateKey(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	addrs := createAccounts(t, sf, 3, 100)
+	oldAddr, candidate := addrs[0], addrs[1]
+	assert.Nil(t, sf.SetNonce(oldAddr, 7))
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+	assert.Nil(t, sf.Vote(oldAddr, candidate, big.NewInt(40)))
+	newAddr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	// one approval committed, one pending
+	assert.Nil(t, sf.(Approver).Approve(addrs[2], oldAddr, big.NewInt(30)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Nil(t, sf.(Approver).Approve(candidate, oldAddr, big.NewInt(20)))
+
+	assert.Equal(t, ErrAddressInUse, sf.RotateKey(oldAddr, addrs[2]))
+	assert.Nil(t, sf.RotateKey(oldAddr, newAddr))
+
+	balance, err := sf.Balance(newAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+	nonce, err := sf.Nonce(newAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(7), nonce)
+	_, err = sf.Balance(oldAddr)
+	assert.Equal(t, ErrAccountNotExist, err)
+	assert.Equal(t, ErrAccountNotExist, sf.RotateKey(oldAddr, newAddr))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// the cast votes now belong to the new key
+	state, err := sfi.getState(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(state.Voters))
+	assert.Equal(t, 0, state.Voters[voterKey(newAddr)].Cmp(big.NewInt(40)))
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(40)))
+	overStaked, err := sf.AuditVotingStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(overStaked))
+	assert.Nil(t, sf.Vote(newAddr, candidate, big.NewInt(60)))
+	assert.Equal(t, ErrInsufficientStake, sf.Vote(newAddr, candidate, big.NewInt(1)))
+
+	// so do the approvals granted to it
+	for owner, amount := range map[*iotxaddress.Address]int64{addrs[2]: 30, candidate: 20} {
+		allowance, err := sf.(Approver).Allowance(owner, newAddr)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, allowance.Cmp(big.NewInt(amount)))
+		allowance, err = sf.(Approver).Allowance(owner, oldAddr)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, allowance.Sign())
+	}
+}
+
+func TestRotateKeyConcurrentCredit(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	oldAddr := createAccounts(t, sf, 1, 100)[0]
+	newAddr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// a credit lands on the old address before the move or fails after it, it is never lost
+	var credited int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sf.AddBalance(oldAddr, big.NewInt(1)); err == nil {
+				atomic.AddInt64(&credited, 1)
+			} else {
+				assert.Equal(t, ErrAccountNotExist, err)
+			}
+		}()
+	}
+	assert.Nil(t, sf.RotateKey(oldAddr, newAddr))
+	wg.Wait()
+	balance, err := sf.Balance(newAddr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100+atomic.LoadInt64(&credited))))
+}
+
+func TestAccountCountRecount(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+	addrs := createAccounts(t, sf, 4, 10)
+	// a state written before the count was kept
+	assert.Nil(t, sfi.trie.Delete(accountCountKey))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// the accounts are counted, reading the count does not change the state
+	root := sf.RootHash()
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), count)
+	assert.Equal(t, root, sf.RootHash())
+
+	// the next deletion stores the count
+	assert.Nil(t, sf.DeleteState(addrs[0]))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	_, err = tr.Get(accountCountKey)
+	assert.Nil(t, err)
+	count, err = NewStateFactory(tr).AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// a count that would drop below 0 is refused
+	assert.Nil(t, sfi.putAccountCount(0))
+	assert.NotNil(t, sf.DeleteState(addrs[1]))
+	count, err = sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), count)
+}
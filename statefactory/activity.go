@@ -0,0 +1,71 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// BalanceTier is the bracket of an account's balance, 0 for an empty balance and 1 up for the tiers set with
+// BalanceTiersOption
+type BalanceTier int
+
+// ActivityInfo classifies an account by what it has done, see AccountActivity
+type ActivityInfo struct {
+	// HasSent is whether the account has sent a transaction, i.e. its nonce is above 0
+	HasSent bool
+	// ReceiveOnly is whether the account holds a balance without having sent a transaction
+	ReceiveOnly bool
+	// IsCandidate is whether the account is registered as a candidate
+	IsCandidate bool
+	// IsVoter is whether the account currently votes for a candidate
+	IsVoter bool
+	// Tier is the bracket of the account's spendable balance
+	Tier BalanceTier
+}
+
+// BalanceTiersOption sets the lower bounds of the balance tiers AccountActivity reports, in raw units
+// A nonzero balance is in tier 1 plus the number of bounds it reaches, so without the option every nonzero balance is
+// in tier 1. The bounds may be given in any order.
+func BalanceTiersOption(bounds ...*big.Int) Option {
+	return func(sf *stateFactory) {
+		sf.tiers = make([]*big.Int, len(bounds))
+		for i, bound := range bounds {
+			sf.tiers[i] = new(big.Int).Set(bound)
+		}
+		sort.Slice(sf.tiers, func(i, j int) bool { return sf.tiers[i].Cmp(sf.tiers[j]) < 0 })
+	}
+}
+
+// AccountActivity classifies the account as GetState reads it
+// It is read-only and the one place the classification is made, so the callers flagging accounts agree on it.
+func (sf *stateFactory) AccountActivity(addr *iotxaddress.Address) (ActivityInfo, error) {
+	state, err := sf.GetState(addr)
+	if err != nil {
+		return ActivityInfo{}, err
+	}
+	hasSent := state.Nonce > 0
+	return ActivityInfo{
+		HasSent:     hasSent,
+		ReceiveOnly: !hasSent && state.Balance.Sign() > 0,
+		IsCandidate: state.IsCandidate,
+		IsVoter:     state.votedWeight().Sign() > 0,
+		Tier:        sf.balanceTier(state.Balance),
+	}, nil
+}
+
+// balanceTier returns the tier of the balance
+func (sf *stateFactory) balanceTier(balance *big.Int) BalanceTier {
+	if balance.Sign() <= 0 {
+		return 0
+	}
+	reached := sort.Search(len(sf.tiers), func(i int) bool { return balance.Cmp(sf.tiers[i]) < 0 })
+	return BalanceTier(1 + reached)
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAccountActivity(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, BalanceTiersOption(big.NewInt(1000), big.NewInt(100)))
+	addrs := createAccounts(t, sf, 3, 0)
+	sender, receiver, candidate := addrs[0], addrs[1], addrs[2]
+
+	// a pristine account has done nothing and holds nothing
+	info, err := sf.AccountActivity(receiver)
+	assert.Nil(t, err)
+	assert.Equal(t, ActivityInfo{}, info)
+
+	// a receive-only account holds a balance with nonce 0
+	assert.Nil(t, sf.AddBalance(sender, big.NewInt(2000)))
+	assert.Nil(t, sf.ApplyTransferTx(sender, receiver, big.NewInt(50), 0))
+	info, err = sf.AccountActivity(receiver)
+	assert.Nil(t, err)
+	assert.Equal(t, ActivityInfo{ReceiveOnly: true, Tier: 1}, info)
+
+	// an active sender voting for a candidate
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+	assert.Nil(t, sf.Vote(sender, candidate, big.NewInt(100)))
+	info, err = sf.AccountActivity(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, ActivityInfo{HasSent: true, IsVoter: true, Tier: 3}, info)
+	assert.Nil(t, sf.ApplyTransferTx(sender, receiver, big.NewInt(1000), 1))
+	info, err = sf.AccountActivity(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, BalanceTier(2), info.Tier)
+	info, err = sf.AccountActivity(receiver)
+	assert.Nil(t, err)
+	assert.Equal(t, ActivityInfo{ReceiveOnly: true, Tier: 3}, info)
+	info, err = sf.AccountActivity(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, ActivityInfo{IsCandidate: true}, info)
+}
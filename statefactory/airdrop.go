@@ -0,0 +1,88 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInvalidAddress is the error that a raw address cannot be decoded into an account key
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Airdrop debits the source the total of the amounts and credits each recipient, keyed by raw address, its amount
+// The total is checked against the source's spendable balance, vesting included, before anything changes, and all the
+// accounts are written together or not at all. The recipients' accounts must exist: a raw address does not carry the
+// public key a new account is keyed by. Each credit is checked by the MutationPolicy as a transfer from the source.
+func (sf *stateFactory) Airdrop(from *iotxaddress.Address, recipients map[string]*big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(from); err != nil {
+		return err
+	}
+	raws := make([]string, 0, len(recipients))
+	for raw := range recipients {
+		raws = append(raws, raw)
+	}
+	// credited in address order, so a failing batch reports the same error on every node
+	sort.Strings(raws)
+	total := big.NewInt(0)
+	recipientKeys := make(map[string]AccountKey, len(raws))
+	keys := [][]byte{iotxaddress.HashPubKey(from.PublicKey)}
+	for _, raw := range raws {
+		amount := recipients[raw]
+		if amount == nil || amount.Sign() < 0 {
+			return errors.Wrapf(ErrInvalidAmount, "recipient %s", raw)
+		}
+		key, err := NewAccountKey(iotxaddress.GetPubkeyHash(raw))
+		if err != nil {
+			return errors.Wrapf(ErrInvalidAddress, "recipient %s", raw)
+		}
+		total.Add(total, amount)
+		recipientKeys[raw] = key
+		keys = append(keys, key.Bytes())
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+
+	source, err := sf.getState(from)
+	if err != nil {
+		return err
+	}
+	if err := source.SubBalance(total); err != nil {
+		return err
+	}
+	// a source airdropping to itself shares one State
+	fromKey := AccountKeyOf(from)
+	states := map[AccountKey]*State{fromKey: source}
+	addrs := map[AccountKey]*iotxaddress.Address{fromKey: from}
+	for _, raw := range raws {
+		key := recipientKeys[raw]
+		to, ok := states[key]
+		if !ok {
+			if to, err = sf.loadStateByKey(key, false); err != nil {
+				return errors.Wrapf(err, "recipient %s", raw)
+			}
+			states[key], addrs[key] = to, to.Address
+		}
+		amount := recipients[raw]
+		if err := sf.policy.AllowTransfer(from, addrs[key], amount); err != nil {
+			return err
+		}
+		if err := to.AddBalance(amount); err != nil {
+			return err
+		}
+	}
+	return sf.writeStates(states, addrs, nil)
+}
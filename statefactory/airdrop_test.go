@@ -0,0 +1,72 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAirdrop(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 4, 10)
+	source, recipients := addrs[0], addrs[1:]
+	amounts := func(a, b, c int64) map[string]*big.Int {
+		return map[string]*big.Int{
+			recipients[0].RawAddress: big.NewInt(a),
+			recipients[1].RawAddress: big.NewInt(b),
+			recipients[2].RawAddress: big.NewInt(c),
+		}
+	}
+	balances := func(expected ...int64) {
+		for i, addr := range addrs {
+			balance, err := sf.Balance(addr)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, balance.Cmp(big.NewInt(expected[i])))
+		}
+	}
+
+	// one Rau short, nothing applied
+	root := sf.RootHash()
+	err = sf.Airdrop(source, amounts(5, 3, 3))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(err))
+	assert.Equal(t, root, sf.RootHash())
+	balances(10, 10, 10, 10)
+
+	// exactly enough
+	assert.Nil(t, sf.Airdrop(source, amounts(5, 3, 2)))
+	balances(0, 15, 13, 12)
+
+	// a recipient without an account or a negative amount fails the whole airdrop
+	stranger, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	root = sf.RootHash()
+	drop := amounts(1, 1, 1)
+	drop[stranger.RawAddress] = big.NewInt(1)
+	err = sf.Airdrop(recipients[0], drop)
+	assert.Equal(t, ErrAccountNotExist, errors.Cause(err))
+	drop = amounts(1, 1, -1)
+	assert.Equal(t, ErrInvalidAmount, errors.Cause(sf.Airdrop(recipients[0], drop)))
+	drop = amounts(1, 1, 1)
+	drop["not an address"] = big.NewInt(1)
+	assert.Equal(t, ErrInvalidAddress, errors.Cause(sf.Airdrop(recipients[0], drop)))
+	assert.Equal(t, root, sf.RootHash())
+	balances(0, 15, 13, 12)
+
+	// the source may be among the recipients
+	assert.Nil(t, sf.Airdrop(recipients[0], amounts(4, 1, 1)))
+	balances(0, 13, 14, 13)
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInsufficientAllowance is the error that a spender moves more of an owner's funds than it was approved for
+var ErrInsufficientAllowance = errors.New("insufficient allowance")
+
+// Approve sets the amount of the owner's balance the spender may move with TransferFrom
+// The amount replaces any earlier approval of the spender rather than adding to it, zero revokes it.
+func (sf *stateFactory) Approve(owner, spender *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	return sf.updateState(owner, func(state *State) error {
+		state.setAllowance(spenderKey(spender), new(big.Int).Set(amount))
+		return nil
+	})
+}
+
+// Allowance returns the amount of the owner's balance the spender may still move, zero if it was never approved
+func (sf *stateFactory) Allowance(owner, spender *iotxaddress.Address) (*big.Int, error) {
+	state, err := sf.readState(owner)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(state.allowance(spenderKey(spender))), nil
+}
+
+// TransferFrom moves the amount from the owner to the recipient on behalf of the spender, using up its allowance
+// The allowance and the owner's balance, vesting included, are both checked before anything changes, and the
+// allowance is decremented in the same write as the owner's balance. A recipient without an account gets one
+// created. The spender's own account is neither read nor changed.
+func (sf *stateFactory) TransferFrom(spender, owner, recipient *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(owner, recipient); err != nil {
+		return err
+	}
+	ownerKey, recipientKey := AccountKeyOf(owner), AccountKeyOf(recipient)
+	unlock := sf.lockAccounts(ownerKey.Bytes(), recipientKey.Bytes())
+	defer unlock()
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	if err := sf.policy.AllowTransfer(owner, recipient, amount); err != nil {
+		return err
+	}
+	from, err := sf.getState(owner)
+	if err != nil {
+		return err
+	}
+	key := spenderKey(spender)
+	allowance := from.allowance(key)
+	if amount.Cmp(allowance) > 0 {
+		return errors.Wrapf(ErrInsufficientAllowance, "allowance %s, amount %s", allowance, amount)
+	}
+	if err := from.SubBalance(amount); err != nil {
+		return err
+	}
+	from.setAllowance(key, new(big.Int).Sub(allowance, amount))
+
+	// an owner paying itself shares one State
+	states := map[AccountKey]*State{ownerKey: from}
+	addrs := map[AccountKey]*iotxaddress.Address{ownerKey: owner}
+	var created map[AccountKey]bool
+	to, ok := states[recipientKey]
+	if !ok {
+		to, err = sf.getState(recipient)
+		if err == ErrAccountNotExist {
+			to = newState(recipient, 0)
+			created = map[AccountKey]bool{recipientKey: true}
+		} else if err != nil {
+			return err
+		}
+		states[recipientKey], addrs[recipientKey] = to, recipient
+	}
+	if err := to.AddBalance(amount); err != nil {
+		return err
+	}
+	return sf.writeStates(states, addrs, created)
+}
+
+// allowanceOwners returns the accounts that may have approved the spender: those that had as of the last commit and
+// those changed since, which are not locked and are to be checked again once they are
+func (sf *stateFactory) allowanceOwners(spender common.Hash32B) ([]*iotxaddress.Address, error) {
+	var owners []*iotxaddress.Address
+	if err := sf.walkAccounts(func(state *State) error {
+		if _, ok := state.Allowances[spender]; ok {
+			owners = append(owners, state.Address)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, change := range sf.PendingChanges() {
+		if change.Kind != ChangeDeleted {
+			owners = append(owners, change.Address)
+		}
+	}
+	return owners, nil
+}
+
+// spenderKey returns the key of the spender in Allowances
+func spenderKey(addr *iotxaddress.Address) common.Hash32B {
+	return blake2b.Sum256(addr.PublicKey)
+}
+
+// allowance returns the allowance of the spender, which must not be modified
+func (st *State) allowance(key common.Hash32B) *big.Int {
+	if allowance, ok := st.Allowances[key]; ok {
+		return allowance
+	}
+	return big.NewInt(0)
+}
+
+// setAllowance sets the allowance of the spender, a zero allowance is removed so a revoked approval leaves no trace
+// in the leaf
+func (st *State) setAllowance(key common.Hash32B, allowance *big.Int) {
+	if allowance.Sign() == 0 {
+		delete(st.Allowances, key)
+		if len(st.Allowances) == 0 {
+			st.Allowances = nil
+		}
+		return
+	}
+	if st.Allowances == nil {
+		st.Allowances = make(map[common.Hash32B]*big.Int)
+	}
+	st.Allowances[key] = allowance
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestTransferFrom(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	ap := sf.(Approver)
+	addrs := createAccounts(t, sf, 3, 100)
+	owner, spender, recipient := addrs[0], addrs[1], addrs[2]
+	check := func(allowance, ownerBalance, recipientBalance int64) {
+		left, err := ap.Allowance(owner, spender)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, left.Cmp(big.NewInt(allowance)))
+		balance, err := sf.Balance(owner)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(ownerBalance)))
+		balance, err = sf.Balance(recipient)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(recipientBalance)))
+	}
+
+	// nothing can be moved without an approval
+	assert.Equal(t, ErrInsufficientAllowance, errors.Cause(ap.TransferFrom(spender, owner, recipient, big.NewInt(1))))
+	assert.Equal(t, ErrInvalidAmount, ap.Approve(owner, spender, big.NewInt(-1)))
+
+	// re-approving replaces the allowance
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(50)))
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(40)))
+	check(40, 100, 100)
+
+	// a partial spend decrements the allowance
+	assert.Nil(t, ap.TransferFrom(spender, owner, recipient, big.NewInt(15)))
+	check(25, 85, 115)
+	balance, err := sf.Balance(spender)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+
+	// spending more than the allowance changes nothing
+	assert.Equal(t, ErrInsufficientAllowance, errors.Cause(ap.TransferFrom(spender, owner, recipient, big.NewInt(26))))
+	check(25, 85, 115)
+
+	// nor does spending more than the owner holds, whatever the allowance
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(1000)))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(ap.TransferFrom(spender, owner, recipient, big.NewInt(86))))
+	check(1000, 85, 115)
+
+	// spending the whole allowance, or approving zero, leaves no allowance in the owner's state
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(5)))
+	assert.Nil(t, ap.TransferFrom(spender, owner, recipient, big.NewInt(5)))
+	check(0, 80, 120)
+	state, err := sf.GetState(owner)
+	assert.Nil(t, err)
+	assert.Nil(t, state.Allowances)
+}
+
+func TestTransferFromNewRecipient(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	ap := sf.(Approver)
+	addrs := createAccounts(t, sf, 2, 100)
+	owner, spender := addrs[0], addrs[1]
+	recipient, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// an allowance belongs to the owner-spender pair, another spender has none
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(30)))
+	assert.Equal(t, ErrInsufficientAllowance, errors.Cause(ap.TransferFrom(recipient, owner, recipient, big.NewInt(1))))
+
+	assert.Nil(t, ap.TransferFrom(spender, owner, recipient, big.NewInt(30)))
+	balance, err := sf.Balance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(30)))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// an owner paying itself only uses up the allowance
+	assert.Nil(t, ap.Approve(owner, spender, big.NewInt(10)))
+	assert.Nil(t, ap.TransferFrom(spender, owner, owner, big.NewInt(4)))
+	balance, err = sf.Balance(owner)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(70)))
+	left, err := ap.Allowance(owner, spender)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, left.Cmp(big.NewInt(6)))
+}
+
+func TestAllowancesEncoding(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	ap := sf.(Approver)
+	addrs := createAccounts(t, sf, 6, 100)
+	owner, spenders := addrs[0], addrs[1:]
+	for i, spender := range spenders {
+		assert.Nil(t, ap.Approve(owner, spender, big.NewInt(int64(i+1))))
+	}
+	root := sf.RootHash()
+	leaf, err := tr.Get(iotxaddress.HashPubKey(owner.PublicKey))
+	assert.Nil(t, err)
+
+	// the same approvals in another order, encoded again and again, give the same leaf and root
+	for i := 0; i < 20; i++ {
+		other, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		osf := NewStateFactory(other)
+		for _, addr := range addrs {
+			_, err = osf.CreateState(addr, 100)
+			assert.Nil(t, err)
+		}
+		for j := len(spenders) - 1; j >= 0; j-- {
+			assert.Nil(t, osf.(Approver).Approve(owner, spenders[j], big.NewInt(int64(j+1))))
+		}
+		ss, err := other.Get(iotxaddress.HashPubKey(owner.PublicKey))
+		assert.Nil(t, err)
+		assert.Equal(t, leaf, ss)
+		assert.Equal(t, root, osf.RootHash())
+	}
+	for i, spender := range spenders {
+		allowance, err := ap.Allowance(owner, spender)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, allowance.Cmp(big.NewInt(int64(i+1))))
+	}
+}
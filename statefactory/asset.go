@@ -0,0 +1,125 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// NativeAsset is the ID of the native coin, its balance is State.Balance
+const NativeAsset AssetID = 0
+
+type (
+	// AssetID identifies a token type an account can hold
+	AssetID uint32
+
+	// AssetBalance is the balance of an asset other than the native coin
+	AssetBalance struct {
+		Asset   AssetID
+		Balance *big.Int
+	}
+)
+
+// BalanceOf returns the balance of the asset held by the account, zero if it never held any
+func (sf *stateFactory) BalanceOf(addr *iotxaddress.Address, asset AssetID) (*big.Int, error) {
+	if asset == NativeAsset {
+		return sf.Balance(addr)
+	}
+	state, err := sf.readState(addr)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).Set(state.assetBalance(asset)), nil
+}
+
+// AddBalanceOf credits the amount of the asset to the account, the native asset is credited as by AddBalance
+func (sf *stateFactory) AddBalanceOf(addr *iotxaddress.Address, asset AssetID, amount *big.Int) error {
+	if asset == NativeAsset {
+		return sf.AddBalance(addr, amount)
+	}
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	return sf.updateState(addr, func(state *State) error {
+		state.setAssetBalance(asset, new(big.Int).Add(state.assetBalance(asset), amount))
+		return nil
+	})
+}
+
+// SubBalanceOf debits the amount of the asset from the account, failing with ErrNotEnoughBalance if it holds less
+// The native asset is debited from Balance, subject to the mutation policy and vesting as any spending.
+func (sf *stateFactory) SubBalanceOf(addr *iotxaddress.Address, asset AssetID, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	if asset == NativeAsset {
+		if err := sf.policy.AllowBalanceChange(addr, new(big.Int).Neg(amount)); err != nil {
+			return err
+		}
+		return sf.updateState(addr, func(state *State) error {
+			return state.SubBalance(amount)
+		})
+	}
+	return sf.updateState(addr, func(state *State) error {
+		balance := state.assetBalance(asset)
+		if amount.Cmp(balance) > 0 {
+			return newInsufficientBalanceError(balance, amount)
+		}
+		state.setAssetBalance(asset, new(big.Int).Sub(balance, amount))
+		return nil
+	})
+}
+
+// assetBalance returns the balance of an asset other than the native coin, which must not be modified
+func (st *State) assetBalance(asset AssetID) *big.Int {
+	i := st.assetIndex(asset)
+	if i < len(st.Assets) && st.Assets[i].Asset == asset {
+		return st.Assets[i].Balance
+	}
+	return big.NewInt(0)
+}
+
+// setAssetBalance sets the balance of an asset other than the native coin
+// Assets stays sorted by ID and holds no zero balance, so an account's leaf does not depend on the order its assets
+// were credited in, nor on assets it held in the past.
+func (st *State) setAssetBalance(asset AssetID, balance *big.Int) {
+	i := st.assetIndex(asset)
+	found := i < len(st.Assets) && st.Assets[i].Asset == asset
+	switch {
+	case found && balance.Sign() == 0:
+		st.Assets = append(st.Assets[:i], st.Assets[i+1:]...)
+		if len(st.Assets) == 0 {
+			st.Assets = nil
+		}
+	case found:
+		st.Assets[i].Balance = balance
+	case balance.Sign() != 0:
+		st.Assets = append(st.Assets, AssetBalance{})
+		copy(st.Assets[i+1:], st.Assets[i:])
+		st.Assets[i] = AssetBalance{Asset: asset, Balance: balance}
+	}
+}
+
+// assetIndex returns the index in Assets the asset is at, or would be inserted at
+func (st *State) assetIndex(asset AssetID) int {
+	return sort.Search(len(st.Assets), func(i int) bool {
+		return st.Assets[i].Asset >= asset
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAssets(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 1, 100)
+	addr := addrs[0]
+	root := sf.RootHash()
+
+	// two assets credited to one account are kept apart, and apart from the native coin
+	assert.Nil(t, sf.AddBalanceOf(addr, 7, big.NewInt(30)))
+	assert.Nil(t, sf.AddBalanceOf(addr, 2, big.NewInt(50)))
+	assert.Nil(t, sf.SubBalanceOf(addr, 7, big.NewInt(10)))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(sf.SubBalanceOf(addr, 7, big.NewInt(21))))
+	assert.Equal(t, ErrInvalidAmount, sf.AddBalanceOf(addr, 2, big.NewInt(-1)))
+	for asset, expected := range map[AssetID]int64{NativeAsset: 100, 2: 50, 7: 20, 9: 0} {
+		balance, err := sf.BalanceOf(addr, asset)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(expected)), "asset %d", asset)
+	}
+
+	// the native asset is the balance
+	assert.Nil(t, sf.AddBalanceOf(addr, NativeAsset, big.NewInt(5)))
+	assert.Nil(t, sf.SubBalanceOf(addr, NativeAsset, big.NewInt(15)))
+	balance, err := sf.Balance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(90)))
+	balance, err = sf.BalanceOf(addr, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(50)))
+
+	// the leaf does not depend on the order the assets were credited in, nor on assets no longer held
+	tr2, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	other := NewStateFactory(tr2)
+	_, err = other.CreateState(addr, 90)
+	assert.Nil(t, err)
+	assert.Nil(t, other.AddBalanceOf(addr, 5, big.NewInt(1)))
+	assert.Nil(t, other.AddBalanceOf(addr, 7, big.NewInt(20)))
+	assert.Nil(t, other.AddBalanceOf(addr, 2, big.NewInt(50)))
+	assert.Nil(t, other.SubBalanceOf(addr, 5, big.NewInt(1)))
+	assert.Equal(t, sf.RootHash(), other.RootHash())
+
+	// an account holding only the native coin is stored as before assets existed
+	assert.Nil(t, sf.SubBalanceOf(addr, 2, big.NewInt(50)))
+	assert.Nil(t, sf.SubBalanceOf(addr, 7, big.NewInt(20)))
+	assert.Nil(t, sf.AddBalanceOf(addr, NativeAsset, big.NewInt(10)))
+	assert.Equal(t, root, sf.RootHash())
+}
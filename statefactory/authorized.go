@@ -0,0 +1,49 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common/utils"
+	"github.com/iotexproject/iotex-core/crypto"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInvalidSignature is the error that the signature does not verify against the sender's public key
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// AuthorizedTransfer applies the transfer if the signature is the sender's over TransferMessage
+// The signed message includes the chain ID of the factory, so a signature made for another network is rejected.
+func (sf *stateFactory) AuthorizedTransfer(sender, recipient *iotxaddress.Address, amount *big.Int, nonce uint64,
+	signature []byte) error {
+	msg := TransferMessage(sf.chainID, recipient, amount, nonce)
+	if !crypto.Verify(sender.PublicKey, msg, signature) {
+		return ErrInvalidSignature
+	}
+	return sf.ApplyTransferTx(sender, recipient, amount, nonce)
+}
+
+// TransferMessage returns the message the sender signs to authorize a transfer on the chain
+func TransferMessage(chainID uint32, recipient *iotxaddress.Address, amount *big.Int, nonce uint64) []byte {
+	var msg bytes.Buffer
+	msg.Write(utils.Uint32ToBytes(chainID))
+	msg.Write(iotxaddress.HashPubKey(recipient.PublicKey))
+	msg.Write(utils.Uint64ToBytes(nonce))
+	msg.Write(amount.Bytes())
+	return msg.Bytes()
+}
+
+// ChainIDOption sets the ID of the chain the factory keeps the state for, default is 0
+func ChainIDOption(chainID uint32) Option {
+	return func(sf *stateFactory) {
+		sf.chainID = chainID
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/crypto"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAuthorizedTransferChainID(t *testing.T) {
+	newFactory := func(chainID uint32) StateFactory {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		return NewStateFactory(tr, ChainIDOption(chainID))
+	}
+	sf1 := newFactory(1)
+	sf2 := newFactory(2)
+	addrs := createAccounts(t, sf1, 2, 100)
+	for _, addr := range addrs {
+		_, err := sf2.CreateState(addr, 100)
+		assert.Nil(t, err)
+	}
+	sender, recipient := addrs[0], addrs[1]
+	amount := big.NewInt(30)
+	sig := crypto.Sign(sender.PrivateKey, TransferMessage(1, recipient, amount, 0))
+
+	// a signature made for chain 1 is rejected on chain 2
+	root := sf2.RootHash()
+	assert.Equal(t, ErrInvalidSignature, sf2.AuthorizedTransfer(sender, recipient, amount, 0, sig))
+	assert.Equal(t, root, sf2.RootHash())
+
+	// tampering with the amount or the recipient breaks the signature
+	assert.Equal(t, ErrInvalidSignature, sf1.AuthorizedTransfer(sender, recipient, big.NewInt(31), 0, sig))
+	assert.Equal(t, ErrInvalidSignature, sf1.AuthorizedTransfer(sender, sender, amount, 0, sig))
+
+	assert.Nil(t, sf1.AuthorizedTransfer(sender, recipient, amount, 0, sig))
+	balance, err := sf1.Balance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(130)))
+	// the nonce has moved on, replaying on the same chain fails
+	assert.Equal(t, ErrNonceTooLow, sf1.AuthorizedTransfer(sender, recipient, amount, 0, sig))
+}
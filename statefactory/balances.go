@@ -0,0 +1,172 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+const (
+	// balanceIndexKVNameSpace is the namespace of the accounts ranked by balance in the KV store, each under its rank key
+	balanceIndexKVNameSpace = "BalanceIndex"
+
+	// balanceRankKVNameSpace is the namespace of the rank key of each account in the balance index
+	balanceRankKVNameSpace = "BalanceRank"
+)
+
+// ErrNoBalanceIndex is the error that no KV store has been set for the index of the accounts by balance
+var ErrNoBalanceIndex = errors.New("no balance index")
+
+// BalanceIndexOption sets the KV store the accounts are indexed by balance in, by default TopBalances fails
+// Each account has its own record keyed by its rank, so a commit only moves the accounts it changed. The store must be
+// able to walk a namespace in key order, see db.Walker. Only the changed accounts are indexed, so the option must be
+// set from genesis on, or after DeleteAll, for the index to hold every account. The store may be shared with the trie,
+// the index has its own namespaces.
+func BalanceIndexOption(kv db.KVStore) Option {
+	return func(sf *stateFactory) {
+		sf.balances = kv
+	}
+}
+
+// TopBalances returns the States of up to n accounts with the highest balances as of the last commit, richest first
+// Accounts with the same balance are ordered by ascending account key. Changes made since the last commit, including
+// those a failed mutation left out, are not reflected until they are committed.
+func (sf *stateFactory) TopBalances(n int) ([]*State, error) {
+	if sf.balances == nil {
+		return nil, ErrNoBalanceIndex
+	}
+	walker, ok := sf.balances.(db.Walker)
+	if !ok {
+		return nil, errors.New("the balance index cannot walk its records")
+	}
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return nil, ErrClosed
+	}
+	var states []*State
+	var err error
+	if n <= 0 {
+		return states, nil
+	}
+	if walkErr := walker.Walk(balanceIndexKVNameSpace, func(rank, value []byte) bool {
+		var key AccountKey
+		copy(key[:], value)
+		var state *State
+		if state, err = sf.loadStateByKey(key, true); err != nil {
+			return false
+		}
+		// a record left behind by an interrupted move no longer matches the account's balance
+		if bytes.Equal(rank, balanceRankKey(state.Balance, key)) {
+			states = append(states, state)
+		}
+		return len(states) < n
+	}); walkErr != nil {
+		return nil, walkErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return states, nil
+}
+
+// balanceRankKey returns the key of the account in the balance index, ordering the accounts by descending balance and
+// then by ascending account key
+// The balance is encoded as its length and big-endian bytes, and every byte inverted, so a larger balance sorts first.
+func balanceRankKey(balance *big.Int, key AccountKey) []byte {
+	b := balance.Bytes()
+	rank := make([]byte, 2, 2+len(b)+len(key))
+	binary.BigEndian.PutUint16(rank, uint16(len(b)))
+	rank = append(rank, b...)
+	for i := range rank {
+		rank[i] = ^rank[i]
+	}
+	return append(rank, key[:]...)
+}
+
+// indexBalances moves the accounts changed since the last commit to their rank, once the commit has been made
+// An account that cannot be moved is kept with the accounts still to be indexed, the next commit tries again.
+func (sf *stateFactory) indexBalances() error {
+	if sf.balances == nil {
+		return nil
+	}
+	for _, change := range sf.PendingChanges() {
+		if sf.unindexed == nil {
+			sf.unindexed = make(map[AccountKey]bool)
+		}
+		sf.unindexed[AccountKeyOf(change.Address)] = true
+	}
+	for key := range sf.unindexed {
+		if err := sf.indexBalance(key); err != nil {
+			return err
+		}
+		delete(sf.unindexed, key)
+	}
+	return nil
+}
+
+// indexBalance moves the account to the rank of its committed balance, or out of the index if it has been deleted
+// The new record is written before the old one is removed, so an interrupted move leaves a stale record TopBalances
+// skips rather than an account missing from the index.
+func (sf *stateFactory) indexBalance(key AccountKey) error {
+	old, err := sf.balances.Get(balanceRankKVNameSpace, key[:])
+	if cause := errors.Cause(err); cause == db.ErrNotExist || cause == bolt.ErrBucketNotFound {
+		old, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	var rank []byte
+	state, err := sf.loadStateByKey(key, true)
+	switch {
+	case errors.Cause(err) == ErrAccountNotExist:
+	case err != nil:
+		return err
+	default:
+		rank = balanceRankKey(state.Balance, key)
+	}
+	if bytes.Equal(old, rank) {
+		return nil
+	}
+	if rank != nil {
+		if err := sf.balances.Put(balanceIndexKVNameSpace, rank, key[:]); err != nil {
+			return err
+		}
+		if err := sf.balances.Put(balanceRankKVNameSpace, key[:], rank); err != nil {
+			return err
+		}
+	} else if err := sf.balances.Delete(balanceRankKVNameSpace, key[:]); err != nil {
+		return err
+	}
+	if old != nil {
+		return sf.balances.Delete(balanceIndexKVNameSpace, old)
+	}
+	return nil
+}
+
+// deleteBalanceIndex removes every account from the balance index
+func (sf *stateFactory) deleteBalanceIndex() error {
+	sf.unindexed = nil
+	if sf.balances == nil {
+		return nil
+	}
+	d, ok := sf.balances.(db.NamespaceDeleter)
+	if !ok {
+		return errors.New("the balance index does not support deleting all records")
+	}
+	if err := d.DeleteNamespace(balanceIndexKVNameSpace); err != nil {
+		return err
+	}
+	return d.DeleteNamespace(balanceRankKVNameSpace)
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestTopBalances(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	_, err = NewStateFactory(tr).TopBalances(1)
+	assert.Equal(t, ErrNoBalanceIndex, err)
+
+	sf := NewStateFactory(tr, BalanceIndexOption(kv))
+	addrs := createAccounts(t, sf, 4, 100)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	// top returns the raw addresses and balances of the n richest accounts
+	top := func(n int) ([]string, []int64) {
+		states, err := sf.TopBalances(n)
+		assert.Nil(t, err)
+		var raw []string
+		var balances []int64
+		for _, state := range states {
+			raw = append(raw, state.Address.RawAddress)
+			balances = append(balances, state.Balance.Int64())
+		}
+		return raw, balances
+	}
+	// ties are broken by account key
+	first, second := addrs[1], addrs[3]
+	if k1, k3 := AccountKeyOf(addrs[1]), AccountKeyOf(addrs[3]); bytes.Compare(k1[:], k3[:]) > 0 {
+		first, second = second, first
+	}
+	_, balances := top(10)
+	assert.Equal(t, []int64{100, 100, 100, 100}, balances)
+
+	// transfers are ranked once committed
+	assert.Nil(t, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(50), 0))
+	assert.Nil(t, sf.ApplyTransferTx(addrs[2], addrs[3], big.NewInt(30), 0))
+	assert.Nil(t, sf.ApplyTransferTx(addrs[1], addrs[3], big.NewInt(10), 0))
+	_, balances = top(10)
+	assert.Equal(t, []int64{100, 100, 100, 100}, balances)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	expected := []string{first.RawAddress, second.RawAddress, addrs[2].RawAddress, addrs[0].RawAddress}
+	raw, balances := top(10)
+	assert.Equal(t, expected, raw)
+	assert.Equal(t, []int64{140, 140, 70, 50}, balances)
+	raw, _ = top(2)
+	assert.Equal(t, expected[:2], raw)
+
+	// a batch reverted on failure and a transfer undone before the commit leave the ranking as is
+	err = sf.ApplyBatch([]StateChange{
+		{Address: addrs[0], Delta: big.NewInt(1000)},
+		{Address: addrs[2], Delta: big.NewInt(-1000)},
+	})
+	assert.NotNil(t, err)
+	assert.Nil(t, sf.ApplyTransferTx(addrs[0], addrs[2], big.NewInt(50), 1))
+	assert.Nil(t, sf.ApplyTransferTx(addrs[2], addrs[0], big.NewInt(50), 1))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	raw, balances = top(10)
+	assert.Equal(t, expected, raw)
+	assert.Equal(t, []int64{140, 140, 70, 50}, balances)
+
+	// a new account is ranked, DeleteAll clears the index
+	rich, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(rich, 500)
+	assert.Nil(t, err)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	raw, _ = top(1)
+	assert.Equal(t, []string{rich.RawAddress}, raw)
+	assert.Nil(t, sf.DeleteAll())
+	raw, _ = top(10)
+	assert.Nil(t, raw)
+}
+
+// unavailableIndex refuses writes while it is down, as a balance index on a failing disk does
+type unavailableIndex struct {
+	db.KVStore
+	down bool
+}
+
+func (s *unavailableIndex) Put(namespace string, key, value []byte) error {
+	if s.down {
+		return errors.New("index unavailable")
+	}
+	return s.KVStore.Put(namespace, key, value)
+}
+
+func (s *unavailableIndex) Walk(namespace string, fn func([]byte, []byte) bool) error {
+	return s.KVStore.(db.Walker).Walk(namespace, fn)
+}
+
+func TestTopBalancesRetry(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	index := &unavailableIndex{KVStore: db.NewMemKVStore(), down: true}
+	sf := NewStateFactory(tr, BalanceIndexOption(index))
+	addrs := createAccounts(t, sf, 2, 100)
+	assert.Nil(t, sf.AddBalance(addrs[1], big.NewInt(1)))
+
+	// the commit stands although the accounts cannot be indexed
+	_, err = sf.CommitWithHeight(1)
+	assert.Nil(t, err)
+	_, err = sf.CommitWithHeight(1)
+	assert.Equal(t, ErrNonMonotonicHeight, errors.Cause(err))
+	assert.Equal(t, 0, len(sf.(Monitor).PendingChanges()))
+	states, err := sf.TopBalances(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(states))
+
+	// the next commit indexes them
+	index.down = false
+	_, err = sf.CommitWithHeight(2)
+	assert.Nil(t, err)
+	states, err = sf.TopBalances(10)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(states))
+	assert.Equal(t, addrs[1].RawAddress, states[0].Address.RawAddress)
+	assert.Equal(t, addrs[0].RawAddress, states[1].Address.RawAddress)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// StateChange is a change to an account applied by ApplyBatch
+type StateChange struct {
+	Address *iotxaddress.Address
+	// Seq orders the changes to the same account, lower first
+	Seq uint64
+	// Delta is added to the balance, a negative delta is subtracted and fails if the balance is not enough
+	Delta *big.Int
+	// Nonce sets the nonce if not nil
+	Nonce *uint64
+}
+
+// ApplyBatch applies the changes to existing accounts, either all of them or none
+// The changes are processed in a canonical order regardless of the input order: sorted by account key in ascending byte
+// order, then by Seq. So the same set of changes always yields the same root, and changes to the same account that
+// depend on each other, e.g. a subtraction that only succeeds after a deposit, are applied in Seq order. Changes to the
+// same account with the same Seq must commute, their relative order is unspecified.
+func (sf *stateFactory) ApplyBatch(changes []StateChange) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Delta == nil {
+			continue
+		}
+		if err := sf.policy.AllowBalanceChange(change.Address, change.Delta); err != nil {
+			return err
+		}
+	}
+	sorted := make([]StateChange, len(changes))
+	copy(sorted, changes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ki := iotxaddress.HashPubKey(sorted[i].Address.PublicKey)
+		kj := iotxaddress.HashPubKey(sorted[j].Address.PublicKey)
+		if c := bytes.Compare(ki, kj); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Seq < sorted[j].Seq
+	})
+	keys := make([][]byte, len(sorted))
+	for i, change := range sorted {
+		keys[i] = iotxaddress.HashPubKey(change.Address.PublicKey)
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	for _, change := range sorted {
+		key := AccountKeyOf(change.Address)
+		state, ok := states[key]
+		if !ok {
+			var err error
+			if state, err = sf.getState(change.Address); err != nil {
+				return err
+			}
+			states[key], addrs[key] = state, change.Address
+		}
+		if change.Delta != nil {
+			if change.Delta.Sign() < 0 {
+				if err := state.SubBalance(new(big.Int).Neg(change.Delta)); err != nil {
+					return err
+				}
+			} else if err := state.AddBalance(change.Delta); err != nil {
+				return err
+			}
+		}
+		if change.Nonce != nil {
+			state.Nonce = *change.Nonce
+		}
+	}
+	return sf.writeStates(states, addrs, nil)
+}
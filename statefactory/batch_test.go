@@ -0,0 +1,91 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestApplyBatchOrder(t *testing.T) {
+	addrs := make([]*iotxaddress.Address, 5)
+	for i := range addrs {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		addrs[i] = addr
+	}
+	nonce := uint64(7)
+	var changes []StateChange
+	for i, addr := range addrs {
+		// the withdrawal only succeeds after the deposit
+		changes = append(changes,
+			StateChange{Address: addr, Seq: 1, Delta: big.NewInt(int64(10 * (i + 1)))},
+			StateChange{Address: addr, Seq: 2, Delta: big.NewInt(-5)},
+			StateChange{Address: addr, Seq: 3, Nonce: &nonce},
+		)
+	}
+
+	apply := func(changes []StateChange) StateFactory {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		sf := NewStateFactory(tr)
+		for _, addr := range addrs {
+			_, err := sf.CreateState(addr, 0)
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, sf.ApplyBatch(changes))
+		return sf
+	}
+	sf := apply(changes)
+	root := sf.RootHash()
+	for i, addr := range addrs {
+		n, balance, err := sf.NonceAndBalance(addr)
+		assert.Nil(t, err)
+		assert.Equal(t, nonce, n)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(int64(10*(i+1)-5))))
+	}
+
+	// shuffled input yields the same root
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		shuffled := make([]StateChange, len(changes))
+		for j, k := range r.Perm(len(changes)) {
+			shuffled[j] = changes[k]
+		}
+		assert.Equal(t, root, apply(shuffled).RootHash())
+	}
+}
+
+func TestApplyBatchAllOrNothing(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 10)
+	root := sf.RootHash()
+
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(sf.ApplyBatch([]StateChange{
+		{Address: addrs[0], Seq: 1, Delta: big.NewInt(5)},
+		{Address: addrs[1], Seq: 1, Delta: big.NewInt(-11)},
+	})))
+	assert.Equal(t, root, sf.RootHash())
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrAccountNotExist, sf.ApplyBatch([]StateChange{
+		{Address: addrs[0], Seq: 1, Delta: big.NewInt(5)},
+		{Address: addr, Seq: 1, Delta: big.NewInt(1)},
+	}))
+	assert.Equal(t, root, sf.RootHash())
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// Burn debits the amount from the account as destroyed funds, e.g. fees or slashing
+// If a burn address is configured with BurnAddressOption the amount is credited to it, creating its account if needed,
+// so the funds remain accounted for. Otherwise the amount leaves the supply.
+func (sf *stateFactory) Burn(addr *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	if err := sf.policy.AllowBalanceChange(addr, new(big.Int).Neg(amount)); err != nil {
+		return err
+	}
+	if err := sf.updateState(addr, func(state *State) error {
+		return state.SubBalance(amount)
+	}); err != nil {
+		return err
+	}
+	if sf.burnAddress == nil {
+		return nil
+	}
+	if _, err := sf.getState(sf.burnAddress); err == ErrAccountNotExist {
+		if _, err := sf.createState(sf.burnAddress, 0); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	return sf.updateState(sf.burnAddress, func(state *State) error {
+		return state.AddBalance(amount)
+	})
+}
+
+// BurnAddressOption sets the address burned funds are credited to, by default they are destroyed
+func BurnAddressOption(addr *iotxaddress.Address) Option {
+	return func(sf *stateFactory) {
+		sf.burnAddress = &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}
+	}
+}
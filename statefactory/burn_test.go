@@ -0,0 +1,55 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestBurn(t *testing.T) {
+	sink, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	for _, burnToAddress := range []bool{false, true} {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		var opts []Option
+		if burnToAddress {
+			opts = append(opts, BurnAddressOption(sink))
+		}
+		sf := NewStateFactory(tr, opts...)
+		addr := createAccounts(t, sf, 1, 100)[0]
+
+		assert.Equal(t, ErrInvalidAmount, sf.Burn(addr, big.NewInt(-1)))
+		assert.Equal(t, ErrNotEnoughBalance, errors.Cause(sf.Burn(addr, big.NewInt(101))))
+		assert.Nil(t, sf.Burn(addr, big.NewInt(30)))
+		assert.Nil(t, sf.Burn(addr, big.NewInt(10)))
+
+		balance, err := sf.Balance(addr)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(60)))
+		burned, err := sf.Balance(sink)
+		if burnToAddress {
+			// the supply is kept, the burned funds sit at the burn address
+			assert.Nil(t, err)
+			assert.Equal(t, 0, burned.Cmp(big.NewInt(40)))
+			assert.Equal(t, 0, new(big.Int).Add(balance, burned).Cmp(big.NewInt(100)))
+		} else {
+			// the supply shrinks by the burned amount
+			assert.Equal(t, ErrAccountNotExist, err)
+			count, err := sf.AccountCount()
+			assert.Nil(t, err)
+			assert.Equal(t, uint64(1), count)
+		}
+	}
+}
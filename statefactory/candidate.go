@@ -0,0 +1,240 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// CandidateInfo is the summary of a candidate used for delegate selection
+type CandidateInfo struct {
+	Address            *iotxaddress.Address
+	VotingWeight       *big.Int
+	RegistrationHeight uint64
+	// Power is the effective power the candidate is ranked by, see EffectivePower
+	Power *big.Int
+}
+
+var (
+	// candidateListKey is the trie key of the list of registered candidates
+	candidateListKey = func() []byte {
+		digest := blake2b.Sum256([]byte("candidates"))
+		return digest[7:27]
+	}()
+
+	// ErrNotEnoughLockedBalance is the error that the locked balance is not enough
+	ErrNotEnoughLockedBalance = errors.New("not enough locked balance")
+
+	// ErrInsufficientSelfStake is the error that the locked self-stake is below the minimum to be a candidate
+	ErrInsufficientSelfStake = errors.New("insufficient self-stake")
+)
+
+// Lock moves the amount from the balance into the locked self-stake
+func (sf *stateFactory) Lock(addr *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	return sf.updateState(addr, func(state *State) error {
+		if err := state.SubBalance(amount); err != nil {
+			return err
+		}
+		state.LockedBalance = new(big.Int).Add(state.lockedBalance(), amount)
+		return nil
+	})
+}
+
+// Unlock moves the amount from the locked self-stake back into the balance
+// A candidate whose locked self-stake drops below the minimum is unregistered as a candidate, its received votes are
+// kept on the account but no longer count until it registers again.
+func (sf *stateFactory) Unlock(addr *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	unregistered := false
+	if err := sf.updateState(addr, func(state *State) error {
+		locked := state.lockedBalance()
+		if amount.Cmp(locked) == 1 {
+			return ErrNotEnoughLockedBalance
+		}
+		state.LockedBalance = new(big.Int).Sub(locked, amount)
+		if err := state.AddBalance(amount); err != nil {
+			return err
+		}
+		if state.IsCandidate && state.LockedBalance.Cmp(sf.minSelfStake) < 0 {
+			state.IsCandidate = false
+			unregistered = true
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if unregistered {
+		return sf.removeFromAddressList(candidateListKey, addr)
+	}
+	return nil
+}
+
+// RegisterCandidate registers the account as a candidate at the given block height
+// The account must have locked at least the minimum self-stake. Registering an existing candidate again is a no-op and
+// keeps its original registration height.
+func (sf *stateFactory) RegisterCandidate(addr *iotxaddress.Address, height uint64) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.updateState(addr, func(state *State) error {
+		if state.IsCandidate {
+			return nil
+		}
+		if state.lockedBalance().Cmp(sf.minSelfStake) < 0 {
+			return ErrInsufficientSelfStake
+		}
+		state.IsCandidate = true
+		state.RegistrationHeight = height
+		if state.VotingWeight == nil {
+			state.VotingWeight = big.NewInt(0)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return sf.addToAddressList(candidateListKey, addr)
+}
+
+// RankedCandidates returns the candidates sorted by effective power in descending order
+// Ties are broken by the earliest registration height, then by the account key in ascending byte order, so every node
+// derives the same ranking from the same state. With QueryLimitsOption a ranking of the candidates read before a limit
+// was hit is returned with ErrResourceLimitExceeded.
+func (sf *stateFactory) RankedCandidates() ([]CandidateInfo, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	return sf.rankCandidates(sf.newQueryBudget())
+}
+
+// rankCandidates ranks the candidates as RankedCandidates does, reading them within the budget
+func (sf *stateFactory) rankCandidates(budget *queryBudget) ([]CandidateInfo, error) {
+	addrs, err := sf.candidates()
+	if err != nil {
+		return nil, err
+	}
+	ranked := make([]CandidateInfo, 0, len(addrs))
+	var limitErr error
+	for _, addr := range addrs {
+		state, err := sf.scanState(budget, addr)
+		if errors.Cause(err) == ErrResourceLimitExceeded {
+			limitErr = err
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !state.IsCandidate {
+			continue
+		}
+		weight := big.NewInt(0)
+		if state.VotingWeight != nil {
+			weight.Set(state.VotingWeight)
+		}
+		ranked = append(ranked, CandidateInfo{
+			Address:            addr,
+			VotingWeight:       weight,
+			RegistrationHeight: state.RegistrationHeight,
+			Power:              sf.effectivePower(state),
+		})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if c := ranked[i].Power.Cmp(ranked[j].Power); c != 0 {
+			return c > 0
+		}
+		if ranked[i].RegistrationHeight != ranked[j].RegistrationHeight {
+			return ranked[i].RegistrationHeight < ranked[j].RegistrationHeight
+		}
+		return bytes.Compare(iotxaddress.HashPubKey(ranked[i].Address.PublicKey),
+			iotxaddress.HashPubKey(ranked[j].Address.PublicKey)) < 0
+	})
+	return ranked, limitErr
+}
+
+// TallyVotes returns the voting weight of every candidate keyed by raw address and the state root it was taken at
+// No mutation runs while the tally is taken, so the weights are those of the returned root, and the returned weights
+// are copies, so later changes to the state do not alter a tally already handed out.
+func (sf *stateFactory) TallyVotes() (map[string]*big.Int, common.Hash32B, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.closed {
+		return nil, common.ZeroHash32B, ErrClosed
+	}
+	root := sf.trie.RootHash()
+	ranked, err := sf.rankCandidates(nil)
+	if err != nil {
+		return nil, common.ZeroHash32B, err
+	}
+	tally := make(map[string]*big.Int, len(ranked))
+	for _, c := range ranked {
+		tally[c.Address.RawAddress] = c.VotingWeight
+	}
+	return tally, root, nil
+}
+
+// RepairVotingWeights recomputes the voting weight of every candidate as the sum of its voters' votes
+// It is a recovery tool for operators, the candidates whose stored weight differs are written back and counted.
+func (sf *stateFactory) RepairVotingWeights() (int, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return 0, err
+	}
+	addrs, err := sf.candidates()
+	if err != nil {
+		return 0, err
+	}
+	repaired := 0
+	for _, addr := range addrs {
+		if err := sf.updateState(addr, func(state *State) error {
+			sum := big.NewInt(0)
+			for _, v := range state.Voters {
+				sum.Add(sum, v)
+			}
+			if state.VotingWeight != nil && state.VotingWeight.Cmp(sum) == 0 {
+				return nil
+			}
+			state.VotingWeight = sum
+			repaired++
+			return nil
+		}); err != nil {
+			return repaired, err
+		}
+	}
+	return repaired, nil
+}
+
+// candidates returns the addresses in the candidate list
+func (sf *stateFactory) candidates() ([]*iotxaddress.Address, error) {
+	return sf.getAddressList(candidateListKey)
+}
+
+// lockedBalance returns the locked balance, treating a missing value as zero
+func (st *State) lockedBalance() *big.Int {
+	if st.LockedBalance == nil {
+		return big.NewInt(0)
+	}
+	return st.LockedBalance
+}
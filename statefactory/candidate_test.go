@@ -0,0 +1,208 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestLockUnlock(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(addr, 100)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(sf.Lock(addr, big.NewInt(101))))
+	assert.Nil(t, sf.Lock(addr, big.NewInt(60)))
+	state, err := sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(40)))
+	assert.Equal(t, 0, state.LockedBalance.Cmp(big.NewInt(60)))
+
+	assert.Equal(t, ErrNotEnoughLockedBalance, sf.Unlock(addr, big.NewInt(61)))
+	assert.Nil(t, sf.Unlock(addr, big.NewInt(20)))
+	state, err = sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(60)))
+	assert.Equal(t, 0, state.LockedBalance.Cmp(big.NewInt(40)))
+}
+
+func TestMinSelfStake(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(50)))
+	sfi := sf.(*stateFactory)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(addr, 100)
+	assert.Nil(t, err)
+
+	// below the minimum
+	assert.Nil(t, sf.Lock(addr, big.NewInt(49)))
+	assert.Equal(t, ErrInsufficientSelfStake, sf.RegisterCandidate(addr, 1))
+	state, err := sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.False(t, state.IsCandidate)
+
+	// exactly the minimum
+	assert.Nil(t, sf.Lock(addr, big.NewInt(1)))
+	assert.Nil(t, sf.RegisterCandidate(addr, 1))
+	state, err = sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.True(t, state.IsCandidate)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(0)))
+
+	// unlocking down to the minimum is fine, below it unregisters the candidate
+	assert.Nil(t, sf.Lock(addr, big.NewInt(10)))
+	assert.Nil(t, sf.Unlock(addr, big.NewInt(5)))
+	assert.Nil(t, sf.Unlock(addr, big.NewInt(5)))
+	state, err = sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.True(t, state.IsCandidate)
+	assert.Nil(t, sf.Unlock(addr, big.NewInt(1)))
+	state, err = sfi.getState(addr)
+	assert.Nil(t, err)
+	assert.False(t, state.IsCandidate)
+	assert.Equal(t, 0, state.LockedBalance.Cmp(big.NewInt(49)))
+}
+
+func TestRankedCandidates(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(10)))
+	sfi := sf.(*stateFactory)
+
+	ranked, err := sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(ranked))
+
+	// a and b have the same weight and height, c has the same weight but registered earlier, d has the highest weight
+	var addrs []*iotxaddress.Address
+	heights := []uint64{5, 5, 3, 9}
+	weights := []int64{100, 100, 100, 200}
+	for i := range heights {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		_, err = sf.CreateState(addr, 100)
+		assert.Nil(t, err)
+		assert.Nil(t, sf.Lock(addr, big.NewInt(10)))
+		assert.Nil(t, sf.RegisterCandidate(addr, heights[i]))
+		weight := big.NewInt(weights[i])
+		assert.Nil(t, sfi.updateState(addr, func(state *State) error {
+			state.VotingWeight = weight
+			return nil
+		}))
+		addrs = append(addrs, addr)
+	}
+	// registering again keeps the original height
+	assert.Nil(t, sf.RegisterCandidate(addrs[2], 20))
+
+	a, b := addrs[0], addrs[1]
+	if bytes.Compare(iotxaddress.HashPubKey(a.PublicKey), iotxaddress.HashPubKey(b.PublicKey)) > 0 {
+		a, b = b, a
+	}
+	expected := []*iotxaddress.Address{addrs[3], addrs[2], a, b}
+	ranked, err = sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, len(expected), len(ranked))
+	for i, info := range ranked {
+		assert.Equal(t, expected[i].RawAddress, info.Address.RawAddress)
+	}
+	assert.Equal(t, 0, ranked[0].VotingWeight.Cmp(big.NewInt(200)))
+	assert.Equal(t, uint64(3), ranked[1].RegistrationHeight)
+
+	// an unregistered candidate drops out of the ranking
+	assert.Nil(t, sf.Unlock(addrs[3], big.NewInt(1)))
+	ranked, err = sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ranked))
+	assert.Equal(t, addrs[2].RawAddress, ranked[0].Address.RawAddress)
+}
+
+func TestRepairVotingWeights(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	voters := createAccounts(t, sf, 2, 100)
+	candidates := createAccounts(t, sf, 3, 0)
+	for _, candidate := range candidates {
+		assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+		assert.Nil(t, sf.Vote(voters[0], candidate, big.NewInt(10)))
+		assert.Nil(t, sf.Vote(voters[1], candidate, big.NewInt(20)))
+	}
+	repaired, err := sf.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, repaired)
+
+	// corrupt one candidate's weight
+	root := sf.RootHash()
+	assert.Nil(t, sfi.updateState(candidates[1], func(state *State) error {
+		state.VotingWeight = big.NewInt(7)
+		return nil
+	}))
+	corrupted := sf.RootHash()
+	assert.NotEqual(t, root, corrupted)
+
+	repaired, err = sf.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, repaired)
+	assert.NotEqual(t, corrupted, sf.RootHash())
+	state, err := sfi.getState(candidates[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(30)))
+}
+
+func TestTallyVotes(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 3, 100)
+	assert.Nil(t, sf.RegisterCandidate(addrs[0], 1))
+	assert.Nil(t, sf.RegisterCandidate(addrs[1], 1))
+	assert.Nil(t, sf.Vote(addrs[2], addrs[0], big.NewInt(30)))
+
+	tally, root, err := sf.TallyVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, sf.RootHash(), root)
+	assert.Equal(t, 2, len(tally))
+	assert.Equal(t, 0, tally[addrs[0].RawAddress].Cmp(big.NewInt(30)))
+	assert.Equal(t, 0, tally[addrs[1].RawAddress].Sign())
+
+	// later votes change the state but not the tally already taken
+	assert.Nil(t, sf.Vote(addrs[2], addrs[0], big.NewInt(20)))
+	assert.Nil(t, sf.Vote(addrs[2], addrs[1], big.NewInt(5)))
+	assert.NotEqual(t, root, sf.RootHash())
+	assert.Equal(t, 0, tally[addrs[0].RawAddress].Cmp(big.NewInt(30)))
+	assert.Equal(t, 0, tally[addrs[1].RawAddress].Sign())
+	next, _, err := sf.TallyVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, next[addrs[0].RawAddress].Cmp(big.NewInt(50)))
+	assert.Equal(t, 0, next[addrs[1].RawAddress].Cmp(big.NewInt(5)))
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+// CloseTrieOption makes Close also close the trie and its KV store, for a factory that is the only user of the trie
+// By default the trie belongs to the caller, which may share it between factories and closes it itself.
+func CloseTrieOption() Option {
+	return func(sf *stateFactory) {
+		sf.closeTrie = true
+	}
+}
+
+// Close commits the changes since the last commit and shuts the factory down
+// Afterwards every mutation, commit and read returning an error fails with ErrClosed, closing again is a no-op.
+// RootHash, Health and the other accessors that cannot fail keep reporting the state as it was closed. If the commit
+// fails the factory stays open, so the caller can retry. The trie is closed as well with CloseTrieOption.
+func (sf *stateFactory) Close() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.closed {
+		return nil
+	}
+	if _, err := sf.commit(); err != nil {
+		return err
+	}
+	sf.closed = true
+	sf.log.Info("state factory closed")
+	if sf.closeTrie {
+		return sf.trie.Close()
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/test/mock/mock_trie"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestClose(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	committed := NewStateFactory(tr, ReadCommittedOption())
+	addrs := createAccounts(t, sf, 2, 10)
+	_, err = committed.Balance(addrs[0])
+	assert.Equal(t, ErrAccountNotExist, err)
+
+	// the pending changes are committed
+	assert.Nil(t, sf.Close())
+	balance, err := committed.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+	assert.Empty(t, sf.(Monitor).PendingChanges())
+
+	// the closed factory refuses everything else
+	assert.Nil(t, sf.Close())
+	_, err = sf.CreateState(addrs[0], 0)
+	assert.Equal(t, ErrClosed, err)
+	assert.Equal(t, ErrClosed, sf.AddBalance(addrs[0], big.NewInt(1)))
+	assert.Equal(t, ErrClosed, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(1), 0))
+	_, err = sf.Balance(addrs[0])
+	assert.Equal(t, ErrClosed, err)
+	_, err = sf.GetStateByKey(AccountKeyOf(addrs[0]))
+	assert.Equal(t, ErrClosed, err)
+	_, err = sf.AccountCount()
+	assert.Equal(t, ErrClosed, err)
+	_, err = sf.RankedCandidates()
+	assert.Equal(t, ErrClosed, err)
+	_, _, err = sf.TallyVotes()
+	assert.Equal(t, ErrClosed, err)
+	_, err = sf.Code(addrs[0])
+	assert.Equal(t, ErrClosed, err)
+	_, err = sf.ResolveName("alice")
+	assert.Equal(t, ErrClosed, err)
+	// the root is still reported as it was closed
+	assert.Equal(t, committed.RootHash(), sf.RootHash())
+	_, err = sf.Commit()
+	assert.Equal(t, ErrClosed, err)
+
+	// the trie is not closed by default, other factories keep using it
+	_, err = committed.CreateState(addrs[0], 0)
+	assert.Nil(t, err)
+}
+
+func TestCloseTrie(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	mtrie.EXPECT().Get(gomock.Any()).AnyTimes().Return(nil, trie.ErrNotExist)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	mtrie.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(1).Return(trie.CommitStats{}, nil)
+	mtrie.EXPECT().Close().Times(1).Return(nil)
+	sf := NewStateFactory(mtrie, CloseTrieOption())
+	assert.Nil(t, sf.Close())
+	assert.Nil(t, sf.Close())
+}
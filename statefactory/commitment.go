@@ -0,0 +1,56 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/common/utils"
+)
+
+// StateCommitment is the summary of the state a block header commits to with the single hash of Hash
+// A light client that trusts the header can verify the root, the account count and the total supply without trusting
+// each of them separately.
+type StateCommitment struct {
+	RootHash     common.Hash32B
+	AccountCount uint64
+	TotalSupply  *big.Int
+	Height       uint64
+}
+
+// Commitment returns the StateCommitment of the current state at the height set by SetHeight
+// The factory does not track the total supply, the caller passes the supply it has accounted for.
+func (sf *stateFactory) Commitment(totalSupply *big.Int) (*StateCommitment, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	count, err := sf.AccountCount()
+	if err != nil {
+		return nil, err
+	}
+	return &StateCommitment{
+		RootHash:     sf.RootHash(),
+		AccountCount: count,
+		TotalSupply:  new(big.Int).Set(totalSupply),
+		Height:       sf.currentHeight(),
+	}, nil
+}
+
+// Hash returns the hash over all fields of the commitment
+func (c *StateCommitment) Hash() common.Hash32B {
+	var b bytes.Buffer
+	b.Write(c.RootHash[:])
+	b.Write(utils.Uint64ToBytes(c.AccountCount))
+	b.Write(utils.Uint64ToBytes(c.Height))
+	supply := c.TotalSupply.Bytes()
+	b.Write(utils.Uint64ToBytes(uint64(len(supply))))
+	b.Write(supply)
+	return blake2b.Sum256(b.Bytes())
+}
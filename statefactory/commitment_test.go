@@ -0,0 +1,53 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestStateCommitment(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	createAccounts(t, sf, 2, 10)
+	sf.SetHeight(7)
+
+	c, err := sf.Commitment(big.NewInt(20))
+	assert.Nil(t, err)
+	assert.Equal(t, sf.RootHash(), c.RootHash)
+	assert.Equal(t, uint64(2), c.AccountCount)
+	assert.Equal(t, 0, c.TotalSupply.Cmp(big.NewInt(20)))
+	assert.Equal(t, uint64(7), c.Height)
+	same, err := sf.Commitment(big.NewInt(20))
+	assert.Nil(t, err)
+	assert.Equal(t, c.Hash(), same.Hash())
+
+	// changing any field changes the hash
+	changes := []func(*StateCommitment){
+		func(c *StateCommitment) { c.RootHash[0] ^= 0x01 },
+		func(c *StateCommitment) { c.AccountCount++ },
+		func(c *StateCommitment) { c.TotalSupply = big.NewInt(21) },
+		func(c *StateCommitment) { c.Height++ },
+	}
+	for _, change := range changes {
+		changed := *c
+		change(&changed)
+		assert.NotEqual(t, c.Hash(), changed.Hash())
+	}
+
+	createAccounts(t, sf, 1, 10)
+	next, err := sf.Commitment(big.NewInt(30))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), next.AccountCount)
+	assert.NotEqual(t, c.Hash(), next.Hash())
+}
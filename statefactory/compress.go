@@ -0,0 +1,61 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// CompressLeavesOption makes the factory write state leaves compressed, leaves are read in either format
+// The trie hashes the stored bytes, so RootHash covers the compressed leaves and differs from the root of the same
+// accounts stored uncompressed. All the nodes agreeing on a root must use the same setting. The leaves are compressed
+// with snappy, whose version glide pins, as a change in its output would change the root.
+func CompressLeavesOption() Option {
+	return func(sf *stateFactory) {
+		sf.compress = true
+	}
+}
+
+// encodeState serializes the State as the factory stores it, compressed with CompressLeavesOption
+func (sf *stateFactory) encodeState(s *State) ([]byte, error) {
+	ss, err := stateToBytes(s)
+	if err != nil || !sf.compress {
+		return ss, err
+	}
+	return compressLeaf(ss)
+}
+
+// compressLeaf converts a leaf in the checksum format to the compressed format, with the checksum over the compressed
+// payload, which is a snappy block
+func compressLeaf(leaf []byte) ([]byte, error) {
+	payload := snappy.Encode(nil, leaf[stateHeaderLen:])
+	compressed := make([]byte, stateHeaderLen+len(payload))
+	copy(compressed[stateHeaderLen:], payload)
+	compressed[0] = stateFormatCompressed
+	binary.BigEndian.PutUint32(compressed[1:stateHeaderLen], crc32.ChecksumIEEE(compressed[stateHeaderLen:]))
+	return compressed, nil
+}
+
+// decompressPayload expands the payload of a compressed leaf, which may not exceed maxSize bytes once expanded either
+func decompressPayload(payload []byte, maxSize int) ([]byte, error) {
+	n, err := snappy.DecodedLen(payload)
+	if err != nil {
+		return nil, ErrLeafCorrupted
+	}
+	if n > maxSize {
+		return nil, errors.Wrapf(ErrLeafTooLarge, "expanded beyond the limit of %d bytes", maxSize)
+	}
+	ss, err := snappy.Decode(nil, payload)
+	if err != nil {
+		return nil, ErrLeafCorrupted
+	}
+	return ss, nil
+}
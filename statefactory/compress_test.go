@@ -0,0 +1,145 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestCompressLeaves(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	s := newState(addr, 0)
+	s.Nonce = 17
+	s.Balance, _ = new(big.Int).SetString("1234567890000000000000", 10)
+
+	plain, err := stateToBytes(s)
+	assert.Nil(t, err)
+	compressed, err := compressLeaf(plain)
+	assert.Nil(t, err)
+	assert.Equal(t, byte(stateFormatCompressed), compressed[0])
+	// the gob type descriptions repeated in every leaf compress well
+	t.Logf("leaf %d bytes, compressed %d bytes, ratio %.2f", len(plain), len(compressed),
+		float64(len(compressed))/float64(len(plain)))
+	assert.True(t, len(compressed)*10 < len(plain)*9)
+
+	decoded, err := bytesToState(compressed, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Nonce, decoded.Nonce)
+	assert.Equal(t, 0, s.Balance.Cmp(decoded.Balance))
+	assert.Equal(t, addr.RawAddress, decoded.Address.RawAddress)
+	reencoded, err := stateToBytes(decoded)
+	assert.Nil(t, err)
+	assert.Equal(t, plain, reencoded)
+
+	// the payload is a snappy block, the expanded length followed by literals and copies
+	pinned, err := compressLeaf(append(make([]byte, stateHeaderLen), []byte("abcabcabcabc")...))
+	assert.Nil(t, err)
+	assert.Equal(t, append([]byte{0x0c, 0x2c}, "abcabcabcabc"...), pinned[stateHeaderLen:])
+	expanded, err := decompressPayload([]byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x03}, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("abcabcabcabc"), expanded)
+
+	// a damaged payload fails the checksum, an expanded payload over the limit is refused
+	damaged := append([]byte{}, compressed...)
+	damaged[len(damaged)-1] ^= 0xff
+	_, err = bytesToState(damaged, defaultMaxLeafSize)
+	assert.Equal(t, ErrLeafCorrupted, err)
+	bomb, err := compressLeaf(append(make([]byte, stateHeaderLen), bytes.Repeat([]byte{0}, 1<<16)...))
+	assert.Nil(t, err)
+	_, err = bytesToState(bomb, 1<<12)
+	assert.Equal(t, ErrLeafTooLarge, errors.Cause(err))
+	// a copy reaching before the start of the output is refused
+	_, err = decompressPayload([]byte{0x05, 0x00, 'a', 0x01, 0x02}, defaultMaxLeafSize)
+	assert.Equal(t, ErrLeafCorrupted, err)
+}
+
+func TestCompressLeavesGolden(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, CompressLeavesOption())
+	keyed := func(b byte) *iotxaddress.Address {
+		return &iotxaddress.Address{PublicKey: bytes.Repeat([]byte{b}, 32), RawAddress: "io1" + string('a'+b)}
+	}
+	addr, voter, spender := keyed(1), keyed(2), keyed(3)
+	s := newState(addr, 0)
+	s.Nonce = 17
+	s.Balance, _ = new(big.Int).SetString("1234567890000000000000", 10)
+	s.IsCandidate = true
+	s.VotingWeight = big.NewInt(70)
+	s.Voters = map[common.Hash32B]*big.Int{voterKey(voter): big.NewInt(40), voterKey(addr): big.NewInt(30)}
+	s.Allowances = map[common.Hash32B]*big.Int{spenderKey(spender): big.NewInt(25)}
+	leaf, err := sf.(*stateFactory).encodeState(s)
+	assert.Nil(t, err)
+	assert.Nil(t, tr.Upsert(AccountKeyOf(addr).Bytes(), leaf))
+
+	// a change to the codec or the leaf encoding changes these bytes and with them the root every node agrees on
+	golden := "" +
+		"819f4318baf706e8ffe47f0301010b73746f726564537461746501ff8000010d01054e6f6e6365010600010742616c61" +
+		"6e636501ff8200010d4c6f636b656442616c61011f64ff820001074164647265737301ff8400010b497343616e646964" +
+		"014a3c0200010c566f74696e67576569676874052f6406566f7465727301ff8a000112526567697374726174696f6e48" +
+		"09240c0600010b012300641d344408436f64654861736801ff8800010f5665730155785363686564756c6501ff8e0001" +
+		"0641737365747301ff9200010a416c6c6f7701b405674400000aff81050102ff9400000041ff83030136b4005c03010a" +
+		"507269766174654b6579010a0001095075626c69630d0e0c0a52617711e3340c00000029ff890201011a5b5d73212f48" +
+		"666163746f72792e6b65796564416d6f756e7405db18ff8600002eff85215d321b0014860001020103015b01cb000611" +
+		"32288200000017ff870101010701e808333242092028014000002aff8d0201011b3a71002d0508506f690172348e0001" +
+		"ff8c000031ff8b0301010c361c00008c01730006395f327500082aff914a5d00254d004231f810920001ff90015d008f" +
+		"015d361c000090015d0005051421bb2e2d026c0000fe0116ff800111010a0242ed123aef19872000010102010220017e" +
+		"0100f08b04696f31620001010102024601020120ffd9ff81ff80ff87ffde7244ffabffc1ffb5fffcfff2ff8e55ffe42c" +
+		"7ffff9ffc678ffc06051ff81fff37affc5ffd7414a7bff9501020228000120fff40cffeafff86e5776ff923332ffb8ff" +
+		"d8fffd3bffefff84ff9cffadffb1ff9c69ff96ffbc272afff1fff648ffd9566a4c0102021e0003200000000000000000" +
+		"5e0500e803010120fffc14ffac6767ff80ffc40e5cfffeffb5ffb8701b14761aff89ffb551ff9effaf663b29ffe7fff8" +
+		"ffabffbdffc721ff95010202190000"
+	assert.Equal(t, golden, hex.EncodeToString(leaf))
+	root := sf.RootHash()
+	assert.Equal(t, "85a1d409273a835a52bbd8b83b4ad592a77fb9472b648df0d7227c1d583ed294", hex.EncodeToString(root[:]))
+	decoded, err := bytesToState(leaf, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, s.Balance.Cmp(decoded.Balance))
+	assert.Equal(t, 0, big.NewInt(40).Cmp(decoded.Voters[voterKey(voter)]))
+	assert.Equal(t, 0, big.NewInt(25).Cmp(decoded.Allowances[spenderKey(spender)]))
+}
+
+func TestCompressLeavesMixed(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	compressing := NewStateFactory(tr, CompressLeavesOption())
+	addrs := createAccounts(t, sf, 2, 10)
+	uncompressedRoot := sf.RootHash()
+
+	// leaves written in either format are read by both factories
+	assert.Nil(t, compressing.AddBalance(addrs[0], big.NewInt(5)))
+	leaf, err := tr.Get(iotxaddress.HashPubKey(addrs[0].PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, byte(stateFormatCompressed), leaf[0])
+	for _, f := range []StateFactory{sf, compressing} {
+		balance, err := f.Balance(addrs[0])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(15)))
+		balance, err = f.Balance(addrs[1])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+	}
+
+	// the root covers the stored bytes, so it depends on the format
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(-5)))
+	assert.Equal(t, uncompressedRoot, sf.RootHash())
+	assert.Nil(t, compressing.AddBalance(addrs[0], big.NewInt(0)))
+	assert.NotEqual(t, uncompressedRoot, sf.RootHash())
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInconsistentState is the error that a decoded state has a negative amount or a voting weight that is not the sum
+// of its voters' votes
+var ErrInconsistentState = errors.New("inconsistent state")
+
+// StrictDecodeOption makes GetState, GetStateByKey and the other reads check the amounts of every decoded state
+// A leaf with a negative amount, or a VotingWeight other than the sum of its Voters, is then refused with
+// ErrInconsistentState instead of being trusted. RepairVotingWeights still reads such leaves to fix them.
+func StrictDecodeOption() Option {
+	return func(sf *stateFactory) {
+		sf.strictDecode = true
+	}
+}
+
+// checkConsistency returns ErrInconsistentState if an amount of the state is negative or its VotingWeight is not the
+// sum of its Voters, a nil amount counts as zero
+func checkConsistency(state *State) error {
+	amounts := []struct {
+		name   string
+		amount *big.Int
+	}{
+		{"balance", state.Balance},
+		{"locked balance", state.LockedBalance},
+		{"voting weight", state.VotingWeight},
+		{"voted weight", state.VotedWeight},
+	}
+	for _, a := range amounts {
+		if a.amount != nil && a.amount.Sign() < 0 {
+			return errors.Wrapf(ErrInconsistentState, "%s %s", a.name, a.amount)
+		}
+	}
+	sum := big.NewInt(0)
+	for voter, v := range state.Voters {
+		if v == nil || v.Sign() < 0 {
+			return errors.Wrapf(ErrInconsistentState, "vote %v of voter %x", v, voter)
+		}
+		sum.Add(sum, v)
+	}
+	for spender, allowance := range state.Allowances {
+		if allowance == nil || allowance.Sign() < 0 {
+			return errors.Wrapf(ErrInconsistentState, "allowance %v of spender %x", allowance, spender)
+		}
+	}
+	weight := state.VotingWeight
+	if weight == nil {
+		weight = big.NewInt(0)
+	}
+	if weight.Cmp(sum) != 0 {
+		return errors.Wrapf(ErrInconsistentState, "voting weight %s, voters sum %s", weight, sum)
+	}
+	return nil
+}
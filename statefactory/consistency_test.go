@@ -0,0 +1,89 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestStrictDecode(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	strict := NewStateFactory(tr, StrictDecodeOption())
+	addrs := createAccounts(t, sf, 2, 100)
+	assert.Nil(t, sf.Lock(addrs[0], big.NewInt(10)))
+	assert.Nil(t, sf.RegisterCandidate(addrs[0], 0))
+	assert.Nil(t, sf.Lock(addrs[1], big.NewInt(30)))
+	assert.Nil(t, sf.Vote(addrs[1], addrs[0], big.NewInt(30)))
+	_, err = strict.GetState(addrs[0])
+	assert.Nil(t, err)
+	assert.Nil(t, strict.SelfCheck())
+
+	// file a leaf whose voting weight exceeds the sum of its voters
+	state, err := sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	state.VotingWeight = new(big.Int).Lsh(big.NewInt(1), 200)
+	ss, err := stateToBytes(state)
+	assert.Nil(t, err)
+	assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addrs[0].PublicKey), ss))
+
+	// reads only check the amounts with StrictDecodeOption
+	_, err = sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	_, err = strict.GetState(addrs[0])
+	assert.Equal(t, ErrInconsistentState, errors.Cause(err))
+	_, err = strict.Balance(addrs[0])
+	assert.Equal(t, ErrInconsistentState, errors.Cause(err))
+	_, err = strict.GetStateByKey(AccountKeyOf(addrs[0]))
+	assert.Equal(t, ErrInconsistentState, errors.Cause(err))
+	assert.Equal(t, ErrInconsistentState, errors.Cause(strict.SelfCheck()))
+	assert.Nil(t, sf.SelfCheck())
+
+	// the leaf can still be repaired
+	repaired, err := strict.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, repaired)
+	state, err = strict.GetState(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, big.NewInt(30), state.VotingWeight)
+}
+
+func TestCheckConsistency(t *testing.T) {
+	voter := common.Hash32B{1}
+	cases := []struct {
+		state *State
+		ok    bool
+	}{
+		{&State{}, true},
+		{&State{Balance: big.NewInt(1), VotingWeight: big.NewInt(0)}, true},
+		{&State{VotingWeight: big.NewInt(5), Voters: map[common.Hash32B]*big.Int{voter: big.NewInt(5)}}, true},
+		{&State{Balance: big.NewInt(-1)}, false},
+		{&State{LockedBalance: big.NewInt(-1)}, false},
+		{&State{VotedWeight: big.NewInt(-1)}, false},
+		{&State{VotingWeight: big.NewInt(1)}, false},
+		{&State{VotingWeight: big.NewInt(-5), Voters: map[common.Hash32B]*big.Int{voter: big.NewInt(-5)}}, false},
+		{&State{VotingWeight: big.NewInt(0), Voters: map[common.Hash32B]*big.Int{voter: nil}}, false},
+	}
+	for i, c := range cases {
+		err := checkConsistency(c.state)
+		if c.ok {
+			assert.Nil(t, err, "case %d", i)
+		} else {
+			assert.Equal(t, ErrInconsistentState, errors.Cause(err), "case %d", i)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+var (
+	codePrefix    = []byte("code.")
+	storagePrefix = []byte("storage.")
+
+	// ErrEmptyCode is the error that the contract code is empty
+	ErrEmptyCode = errors.New("empty code")
+
+	// ErrCodeNotExist is the error that the account has no code
+	ErrCodeNotExist = errors.New("the code does not exist")
+)
+
+// SetCode deploys the code to the account, turning it into a contract account
+// The code is stored in the trie keyed by its hash, and the account keeps the hash as its CodeHash.
+func (sf *stateFactory) SetCode(addr *iotxaddress.Address, code []byte) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if len(code) == 0 {
+		return ErrEmptyCode
+	}
+	hash := blake2b.Sum256(code)
+	if err := sf.updateState(addr, func(state *State) error {
+		state.CodeHash = hash
+		return nil
+	}); err != nil {
+		return err
+	}
+	return sf.trie.Upsert(codeKey(hash), code)
+}
+
+// Code returns the code deployed to the account
+func (sf *stateFactory) Code(addr *iotxaddress.Address) ([]byte, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	state, err := sf.getState(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !state.IsContract() {
+		return nil, ErrCodeNotExist
+	}
+	code, err := sf.trie.Get(codeKey(state.CodeHash))
+	if errors.Cause(err) == trie.ErrNotExist {
+		return nil, ErrCodeNotExist
+	}
+	return code, err
+}
+
+// IsContract returns true if the account is a contract account, false if it is an externally owned account
+func (sf *stateFactory) IsContract(addr *iotxaddress.Address) (bool, error) {
+	if err := sf.checkOpen(); err != nil {
+		return false, err
+	}
+	state, err := sf.getState(addr)
+	if err != nil {
+		return false, err
+	}
+	return state.IsContract(), nil
+}
+
+// IsContract returns true if code has been deployed to the account
+func (st *State) IsContract() bool {
+	return st.CodeHash != common.ZeroHash32B
+}
+
+// codeKey returns the trie key of the code with the hash
+func codeKey(hash common.Hash32B) []byte {
+	digest := blake2b.Sum256(append(codePrefix, hash[:]...))
+	return digest[7:27]
+}
+
+// StorageKey returns the trie key of the contract storage slot of the account
+// The key is bytes 7 to 27 of the blake2b-256 digest of "storage." followed by the 20-byte hash of the account's public
+// key and then the slot. The hash has a fixed length, so no (address, slot) pair encodes like another. This derivation
+// is part of the consensus: every node must map a slot to the same key, so it must never change.
+func StorageKey(addr *iotxaddress.Address, slot []byte) []byte {
+	preimage := append([]byte{}, storagePrefix...)
+	preimage = append(preimage, iotxaddress.HashPubKey(addr.PublicKey)...)
+	digest := blake2b.Sum256(append(preimage, slot...))
+	return digest[7:27]
+}
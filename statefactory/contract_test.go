@@ -0,0 +1,86 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestIsContract(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 2, 10)
+	eoa, contract := addrs[0], addrs[1]
+	code := []byte{0x60, 0x80, 0x60, 0x40}
+	assert.Equal(t, ErrEmptyCode, sf.SetCode(contract, nil))
+	assert.Nil(t, sf.SetCode(contract, code))
+
+	// externally owned account
+	isContract, err := sf.IsContract(eoa)
+	assert.Nil(t, err)
+	assert.False(t, isContract)
+	_, err = sf.Code(eoa)
+	assert.Equal(t, ErrCodeNotExist, err)
+
+	// deployed contract
+	isContract, err = sf.IsContract(contract)
+	assert.Nil(t, err)
+	assert.True(t, isContract)
+	b, err := sf.Code(contract)
+	assert.Nil(t, err)
+	assert.Equal(t, code, b)
+	state, err := sf.GetState(contract)
+	assert.Nil(t, err)
+	assert.True(t, state.IsContract())
+
+	// nonexistent account
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.IsContract(addr)
+	assert.Equal(t, ErrAccountNotExist, err)
+
+	// the pending state used by the actpool sees the same account type
+	vs := NewVirtualStateFactory(tr)
+	isContract, err = vs.IsContract(contract)
+	assert.Nil(t, err)
+	assert.True(t, isContract)
+}
+
+func TestStorageKey(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	other, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// the same (address, slot) always maps to the same key
+	key := StorageKey(addr, []byte("balance"))
+	assert.Equal(t, 20, len(key))
+	assert.Equal(t, key, StorageKey(addr, []byte("balance")))
+	same := &iotxaddress.Address{PublicKey: addr.PublicKey}
+	assert.Equal(t, key, StorageKey(same, []byte("balance")))
+
+	// different slots or accounts do not collide
+	keys := map[string]bool{string(key): true}
+	for _, k := range [][]byte{
+		StorageKey(addr, []byte("balances")),
+		StorageKey(addr, nil),
+		StorageKey(addr, []byte{0}),
+		StorageKey(other, []byte("balance")),
+	} {
+		assert.False(t, keys[string(k)])
+		keys[string(k)] = true
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// Rounding is how a DecimalFormat drops the digits beyond the fractional digits it shows
+type Rounding int
+
+const (
+	// RoundTruncate drops the extra digits, rounding toward zero
+	RoundTruncate Rounding = iota
+	// RoundHalfUp rounds to the nearest shown digit, halves away from zero
+	RoundHalfUp
+)
+
+// DecimalFormat renders raw amounts as fixed-point decimal strings
+// A whole unit is 10^Decimals raw units, and Digits fractional digits are always shown, trailing zeros included. The
+// zero value renders raw units as integers.
+type DecimalFormat struct {
+	Decimals uint
+	Digits   uint
+	Rounding Rounding
+}
+
+// DecimalFormatOption sets the format BalanceDecimal renders balances in, default is raw units as integers
+func DecimalFormatOption(format DecimalFormat) Option {
+	return func(sf *stateFactory) {
+		sf.format = format
+	}
+}
+
+// BalanceDecimal returns the balance as a fixed-point decimal string in the configured format
+func (sf *stateFactory) BalanceDecimal(addr *iotxaddress.Address) (string, error) {
+	balance, err := sf.Balance(addr)
+	if err != nil {
+		return "", err
+	}
+	return sf.format.Format(balance), nil
+}
+
+// Format renders the raw amount with the format's fractional digits, rounding the dropped digits if any
+func (f DecimalFormat) Format(amount *big.Int) string {
+	abs := new(big.Int).Abs(amount)
+	if f.Digits < f.Decimals {
+		scale := pow10(f.Decimals - f.Digits)
+		var rem big.Int
+		abs.DivMod(abs, scale, &rem)
+		if f.Rounding == RoundHalfUp && rem.Lsh(&rem, 1).Cmp(scale) >= 0 {
+			abs.Add(abs, big.NewInt(1))
+		}
+	} else {
+		abs.Mul(abs, pow10(f.Digits-f.Decimals))
+	}
+	s := abs.String()
+	if f.Digits > 0 {
+		if pad := int(f.Digits) + 1 - len(s); pad > 0 {
+			s = strings.Repeat("0", pad) + s
+		}
+		s = s[:len(s)-int(f.Digits)] + "." + s[len(s)-int(f.Digits):]
+	}
+	if amount.Sign() < 0 && abs.Sign() != 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// Parse converts a decimal string back to raw units, it must not have more fractional digits than Decimals
+func (f DecimalFormat) Parse(s string) (*big.Int, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+		if len(frac) == 0 {
+			return nil, errors.Wrapf(ErrInvalidAmount, "amount %q", s)
+		}
+	}
+	if strings.TrimLeft(whole, "+-")+frac == "" || uint(len(frac)) > f.Decimals || strings.ContainsAny(frac, "+-") {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount %q", s)
+	}
+	digits := whole + frac + strings.Repeat("0", int(f.Decimals)-len(frac))
+	amount, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount %q", s)
+	}
+	return amount, nil
+}
+
+// pow10 returns 10^n
+func pow10(n uint) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
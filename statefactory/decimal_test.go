@@ -0,0 +1,93 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestDecimalFormat(t *testing.T) {
+	// a supply cap of 10 billion whole units of 18 decimals
+	supplyCap, ok := new(big.Int).SetString("10000000000000000000000000000", 10)
+	assert.True(t, ok)
+	nearCap := new(big.Int).Sub(supplyCap, big.NewInt(1))
+	truncate := DecimalFormat{Decimals: 18, Digits: 4, Rounding: RoundTruncate}
+	halfUp := DecimalFormat{Decimals: 18, Digits: 4, Rounding: RoundHalfUp}
+
+	for _, c := range []struct {
+		format DecimalFormat
+		amount *big.Int
+		expect string
+	}{
+		{DecimalFormat{}, big.NewInt(1234), "1234"},
+		// trailing zeros are kept to the configured digits
+		{truncate, new(big.Int).Mul(big.NewInt(15), pow10(17)), "1.5000"},
+		{DecimalFormat{Decimals: 2, Digits: 4}, big.NewInt(100), "1.0000"},
+		{truncate, big.NewInt(0), "0.0000"},
+		// sub-unit amounts
+		{truncate, big.NewInt(1), "0.0000"},
+		{halfUp, big.NewInt(1), "0.0000"},
+		{truncate, big.NewInt(123456789), "0.0000"},
+		{truncate, new(big.Int).Mul(big.NewInt(123), pow10(12)), "0.0001"},
+		{halfUp, new(big.Int).Mul(big.NewInt(149), pow10(12)), "0.0001"},
+		{halfUp, new(big.Int).Mul(big.NewInt(150), pow10(12)), "0.0002"},
+		{truncate, new(big.Int).Mul(big.NewInt(-150), pow10(12)), "-0.0001"},
+		{halfUp, new(big.Int).Mul(big.NewInt(-150), pow10(12)), "-0.0002"},
+		{halfUp, new(big.Int).Mul(big.NewInt(-49), pow10(12)), "0.0000"},
+		// very large balances near the supply cap
+		{truncate, supplyCap, "10000000000.0000"},
+		{truncate, nearCap, "9999999999.9999"},
+		{halfUp, nearCap, "10000000000.0000"},
+	} {
+		assert.Equal(t, c.expect, c.format.Format(c.amount))
+	}
+}
+
+func TestDecimalParse(t *testing.T) {
+	f := DecimalFormat{Decimals: 18, Digits: 4}
+	for s, expect := range map[string]string{
+		"1.5":                           "1500000000000000000",
+		"0.000000000000000001":          "1",
+		".25":                           "250000000000000000",
+		"-2":                            "-2000000000000000000",
+		"9999999999.999999999999999999": "9999999999999999999999999999",
+	} {
+		amount, err := f.Parse(s)
+		assert.Nil(t, err)
+		assert.Equal(t, expect, amount.String())
+	}
+	for _, s := range []string{"", "1.", "1.0000000000000000001", "1.-5", "one", "1.5.0"} {
+		_, err := f.Parse(s)
+		assert.Equal(t, ErrInvalidAmount, errors.Cause(err))
+	}
+	// parsing a formatted amount without dropped digits gives it back
+	amount := new(big.Int).Mul(big.NewInt(12345), pow10(14))
+	parsed, err := f.Parse(f.Format(amount))
+	assert.Nil(t, err)
+	assert.Equal(t, amount, parsed)
+}
+
+func TestBalanceDecimal(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, DecimalFormatOption(DecimalFormat{Decimals: 2, Digits: 1, Rounding: RoundHalfUp}))
+	addrs := createAccounts(t, sf, 1, 1255)
+
+	b, err := sf.BalanceDecimal(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "12.6", b)
+	raw, err := NewStateFactory(tr).BalanceDecimal(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, "1255", raw)
+}
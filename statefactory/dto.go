@@ -0,0 +1,203 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"encoding/hex"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+type (
+	// StateDTO is the representation of a State for RPC responses
+	// Amounts are decimal strings and byte values are hex strings, the voters and allowances are lists sorted by key so
+	// the encoding is deterministic.
+	StateDTO struct {
+		Address            string         `json:"address"`
+		PublicKey          string         `json:"publicKey"`
+		Nonce              uint64         `json:"nonce"`
+		Balance            string         `json:"balance"`
+		LockedBalance      string         `json:"lockedBalance"`
+		IsCandidate        bool           `json:"isCandidate"`
+		VotingWeight       string         `json:"votingWeight"`
+		Voters             []VoterDTO     `json:"voters,omitempty"`
+		RegistrationHeight uint64         `json:"registrationHeight"`
+		VotedWeight        string         `json:"votedWeight"`
+		CodeHash           string         `json:"codeHash,omitempty"`
+		VestingSchedule    []VestingDTO   `json:"vestingSchedule,omitempty"`
+		Assets             []AssetDTO     `json:"assets,omitempty"`
+		Allowances         []AllowanceDTO `json:"allowances,omitempty"`
+	}
+
+	// VoterDTO is the weight one voter has cast to a candidate
+	VoterDTO struct {
+		Voter  string `json:"voter"`
+		Weight string `json:"weight"`
+	}
+
+	// VestingDTO is a VestingPoint of a StateDTO
+	VestingDTO struct {
+		Height uint64 `json:"height"`
+		Amount string `json:"amount"`
+	}
+
+	// AssetDTO is an AssetBalance of a StateDTO
+	AssetDTO struct {
+		Asset   AssetID `json:"asset"`
+		Balance string  `json:"balance"`
+	}
+
+	// AllowanceDTO is the amount one spender may move from the balance of a StateDTO
+	AllowanceDTO struct {
+		Spender string `json:"spender"`
+		Amount  string `json:"amount"`
+	}
+)
+
+// ToDTO converts the State to a StateDTO, a missing amount is zero
+func ToDTO(s *State) *StateDTO {
+	dto := &StateDTO{
+		Nonce:              s.Nonce,
+		Balance:            amountString(s.Balance),
+		LockedBalance:      amountString(s.LockedBalance),
+		IsCandidate:        s.IsCandidate,
+		VotingWeight:       amountString(s.VotingWeight),
+		RegistrationHeight: s.RegistrationHeight,
+		VotedWeight:        amountString(s.VotedWeight),
+	}
+	if s.Address != nil {
+		dto.Address = s.Address.RawAddress
+		dto.PublicKey = hex.EncodeToString(s.Address.PublicKey)
+	}
+	for key, weight := range s.Voters {
+		dto.Voters = append(dto.Voters, VoterDTO{Voter: hex.EncodeToString(key[:]), Weight: amountString(weight)})
+	}
+	sort.Slice(dto.Voters, func(i, j int) bool { return dto.Voters[i].Voter < dto.Voters[j].Voter })
+	if s.CodeHash != common.ZeroHash32B {
+		dto.CodeHash = hex.EncodeToString(s.CodeHash[:])
+	}
+	for _, point := range s.VestingSchedule {
+		dto.VestingSchedule = append(dto.VestingSchedule, VestingDTO{Height: point.Height, Amount: amountString(point.Amount)})
+	}
+	for _, asset := range s.Assets {
+		dto.Assets = append(dto.Assets, AssetDTO{Asset: asset.Asset, Balance: amountString(asset.Balance)})
+	}
+	for key, allowance := range s.Allowances {
+		dto.Allowances = append(dto.Allowances, AllowanceDTO{
+			Spender: hex.EncodeToString(key[:]),
+			Amount:  amountString(allowance),
+		})
+	}
+	sort.Slice(dto.Allowances, func(i, j int) bool { return dto.Allowances[i].Spender < dto.Allowances[j].Spender })
+	return dto
+}
+
+// FromDTO converts the StateDTO back to a State
+func FromDTO(dto *StateDTO) (*State, error) {
+	s := &State{
+		Nonce:              dto.Nonce,
+		IsCandidate:        dto.IsCandidate,
+		RegistrationHeight: dto.RegistrationHeight,
+	}
+	var err error
+	if s.Balance, err = parseAmount(dto.Balance); err != nil {
+		return nil, err
+	}
+	if s.LockedBalance, err = parseAmount(dto.LockedBalance); err != nil {
+		return nil, err
+	}
+	if s.VotingWeight, err = parseAmount(dto.VotingWeight); err != nil {
+		return nil, err
+	}
+	if s.VotedWeight, err = parseAmount(dto.VotedWeight); err != nil {
+		return nil, err
+	}
+	if dto.Address != "" || dto.PublicKey != "" {
+		pubKey, err := hex.DecodeString(dto.PublicKey)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid public key %s", dto.PublicKey)
+		}
+		s.Address = &iotxaddress.Address{PublicKey: pubKey, RawAddress: dto.Address}
+	}
+	if len(dto.Voters) > 0 {
+		s.Voters = make(map[common.Hash32B]*big.Int, len(dto.Voters))
+	}
+	for _, voter := range dto.Voters {
+		var key common.Hash32B
+		if err := decodeHash(voter.Voter, &key); err != nil {
+			return nil, err
+		}
+		if s.Voters[key], err = parseAmount(voter.Weight); err != nil {
+			return nil, err
+		}
+	}
+	if dto.CodeHash != "" {
+		if err := decodeHash(dto.CodeHash, &s.CodeHash); err != nil {
+			return nil, err
+		}
+	}
+	for _, point := range dto.VestingSchedule {
+		amount, err := parseAmount(point.Amount)
+		if err != nil {
+			return nil, err
+		}
+		s.VestingSchedule = append(s.VestingSchedule, VestingPoint{Height: point.Height, Amount: amount})
+	}
+	for _, asset := range dto.Assets {
+		balance, err := parseAmount(asset.Balance)
+		if err != nil {
+			return nil, err
+		}
+		s.setAssetBalance(asset.Asset, balance)
+	}
+	for _, allowance := range dto.Allowances {
+		var key common.Hash32B
+		if err := decodeHash(allowance.Spender, &key); err != nil {
+			return nil, err
+		}
+		amount, err := parseAmount(allowance.Amount)
+		if err != nil {
+			return nil, err
+		}
+		s.setAllowance(key, amount)
+	}
+	return s, nil
+}
+
+// amountString returns the amount as a decimal string, treating nil as zero
+func amountString(amount *big.Int) string {
+	if amount == nil {
+		return "0"
+	}
+	return amount.String()
+}
+
+// parseAmount parses a decimal amount
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidAmount, "amount %q", s)
+	}
+	return amount, nil
+}
+
+// decodeHash decodes a hex string of a 32-byte hash
+func decodeHash(s string, hash *common.Hash32B) error {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid hash %s", s)
+	}
+	if len(b) != len(hash) {
+		return errors.Errorf("invalid hash length %d", len(b))
+	}
+	copy(hash[:], b)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+func TestStateDTO(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	voter, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	s := &State{
+		Nonce:              3,
+		Balance:            new(big.Int).Lsh(big.NewInt(1), 100),
+		LockedBalance:      big.NewInt(20),
+		Address:            addr,
+		IsCandidate:        true,
+		VotingWeight:       big.NewInt(12),
+		Voters:             map[common.Hash32B]*big.Int{voterKey(addr): big.NewInt(5), voterKey(voter): big.NewInt(7)},
+		RegistrationHeight: 9,
+		VotedWeight:        big.NewInt(5),
+		CodeHash:           blake2b.Sum256([]byte("code")),
+		VestingSchedule:    []VestingPoint{{Height: 10, Amount: big.NewInt(1)}, {Height: 20, Amount: big.NewInt(2)}},
+		Assets:             []AssetBalance{{Asset: 1, Balance: big.NewInt(4)}, {Asset: 7, Balance: big.NewInt(9)}},
+		Allowances:         map[common.Hash32B]*big.Int{spenderKey(voter): big.NewInt(6), spenderKey(addr): big.NewInt(2)},
+	}
+
+	encoded, err := json.Marshal(ToDTO(s))
+	assert.Nil(t, err)
+	// the encoding does not depend on map ordering
+	for i := 0; i < 10; i++ {
+		again, err := json.Marshal(ToDTO(s))
+		assert.Nil(t, err)
+		assert.Equal(t, encoded, again)
+	}
+	var dto StateDTO
+	assert.Nil(t, json.Unmarshal(encoded, &dto))
+	assert.Equal(t, addr.RawAddress, dto.Address)
+	assert.Equal(t, "1267650600228229401496703205376", dto.Balance)
+
+	decoded, err := FromDTO(&dto)
+	assert.Nil(t, err)
+	assert.Equal(t, addr.PublicKey, decoded.Address.PublicKey)
+	assert.Equal(t, addr.RawAddress, decoded.Address.RawAddress)
+	assert.Equal(t, s.Nonce, decoded.Nonce)
+	assert.Equal(t, 0, s.Balance.Cmp(decoded.Balance))
+	assert.Equal(t, 0, s.LockedBalance.Cmp(decoded.LockedBalance))
+	assert.Equal(t, s.IsCandidate, decoded.IsCandidate)
+	assert.Equal(t, 0, s.VotingWeight.Cmp(decoded.VotingWeight))
+	assert.Equal(t, s.RegistrationHeight, decoded.RegistrationHeight)
+	assert.Equal(t, 0, s.VotedWeight.Cmp(decoded.VotedWeight))
+	assert.Equal(t, len(s.Voters), len(decoded.Voters))
+	for key, weight := range s.Voters {
+		assert.Equal(t, 0, weight.Cmp(decoded.Voters[key]))
+	}
+	assert.Equal(t, s.CodeHash, decoded.CodeHash)
+	assert.Equal(t, s.VestingSchedule, decoded.VestingSchedule)
+	assert.Equal(t, s.Assets, decoded.Assets)
+	assert.Equal(t, len(s.Allowances), len(decoded.Allowances))
+	for key, allowance := range s.Allowances {
+		assert.Equal(t, 0, allowance.Cmp(decoded.Allowances[key]))
+	}
+
+	dto.Balance = "1.5"
+	_, err = FromDTO(&dto)
+	assert.Equal(t, ErrInvalidAmount, errors.Cause(err))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// SweepDustVotes removes the votes below the minimum vote weight from every candidate and returns how many it removed
+// It is a one-time tool for operators after raising the minimum with MinVoteWeightOption, Vote refuses new dust votes
+// but those cast before stay until swept. Each candidate's VotingWeight drops by the votes removed, and the voters get
+// the weight back to vote again as with Slash, a vote whose voter is not in the voter list is removed all the same.
+// All the accounts change together or not at all.
+func (sf *stateFactory) SweepDustVotes() (int, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return 0, err
+	}
+	if sf.minVote.Sign() <= 0 {
+		return 0, nil
+	}
+	candidates, err := sf.candidates()
+	if err != nil {
+		return 0, err
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return 0, err
+	}
+	var keys [][]byte
+	for _, list := range [][]*iotxaddress.Address{candidates, voters} {
+		for _, addr := range list {
+			keys = append(keys, iotxaddress.HashPubKey(addr.PublicKey))
+		}
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	load := func(addr *iotxaddress.Address) (*State, error) {
+		key := AccountKeyOf(addr)
+		if state, ok := states[key]; ok {
+			return state, nil
+		}
+		state, err := sf.getState(addr)
+		if err != nil {
+			return nil, err
+		}
+		states[key], addrs[key] = state, addr
+		return state, nil
+	}
+	voterAddrs := make(map[common.Hash32B]*iotxaddress.Address)
+	for _, voter := range voters {
+		voterAddrs[voterKey(voter)] = voter
+	}
+	// only the accounts a dust vote was removed from or returned to are written
+	changed := make(map[AccountKey]bool)
+	swept := 0
+	for _, addr := range candidates {
+		candidate, err := load(addr)
+		if err != nil {
+			return 0, err
+		}
+		dust := big.NewInt(0)
+		for key, vote := range candidate.Voters {
+			if vote.Cmp(sf.minVote) >= 0 {
+				continue
+			}
+			delete(candidate.Voters, key)
+			dust.Add(dust, vote)
+			swept++
+			voter, ok := voterAddrs[key]
+			if !ok {
+				continue
+			}
+			state, err := load(voter)
+			if err != nil {
+				return 0, err
+			}
+			state.VotedWeight = new(big.Int).Sub(state.votedWeight(), vote)
+			changed[AccountKeyOf(voter)] = true
+		}
+		if dust.Sign() > 0 {
+			candidate.VotingWeight = new(big.Int).Sub(candidate.VotingWeight, dust)
+			changed[AccountKeyOf(addr)] = true
+		}
+	}
+	if swept == 0 {
+		return 0, nil
+	}
+	for key := range states {
+		if !changed[key] {
+			delete(states, key)
+		}
+	}
+	if err := sf.writeStates(states, addrs, nil); err != nil {
+		return 0, err
+	}
+	return swept, nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestSweepDustVotes(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	// votes cast before the minimum was set
+	sf := NewStateFactory(tr)
+	candidates := createAccounts(t, sf, 2, 0)
+	for _, c := range candidates {
+		assert.Nil(t, sf.RegisterCandidate(c, 1))
+	}
+	voters := createAccounts(t, sf, 3, 100)
+	assert.Nil(t, sf.Vote(voters[0], candidates[0], big.NewInt(5)))
+	assert.Nil(t, sf.Vote(voters[0], candidates[1], big.NewInt(20)))
+	assert.Nil(t, sf.Vote(voters[1], candidates[0], big.NewInt(3)))
+	assert.Nil(t, sf.Vote(voters[2], candidates[0], big.NewInt(50)))
+
+	// a factory with a minimum refuses smaller votes, but existing dust stays until swept
+	sf = NewStateFactory(tr, MinVoteWeightOption(big.NewInt(10)))
+	sfi := sf.(*stateFactory)
+	err = sf.Vote(voters[1], candidates[1], big.NewInt(9))
+	assert.Equal(t, ErrVoteTooSmall, errors.Cause(err))
+	err = sf.ApplyVoteChanges([]VoteChange{{Voter: voters[1], NewCandidate: candidates[1], Weight: big.NewInt(9)}})
+	assert.Equal(t, ErrVoteTooSmall, errors.Cause(err))
+	assert.Nil(t, sf.Vote(voters[1], candidates[1], big.NewInt(10)))
+	state, err := sfi.getState(candidates[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(state.Voters))
+
+	swept, err := sf.SweepDustVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, swept)
+	state, err = sfi.getState(candidates[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(50)))
+	assert.Equal(t, 1, len(state.Voters))
+	assert.Equal(t, 0, state.Voters[voterKey(voters[2])].Cmp(big.NewInt(50)))
+	state, err = sfi.getState(candidates[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(30)))
+	assert.Equal(t, 2, len(state.Voters))
+
+	// the voters get the swept weight back
+	for i, voted := range []int64{20, 10, 50} {
+		state, err = sfi.getState(voters[i])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, state.votedWeight().Cmp(big.NewInt(voted)))
+	}
+	inconsistencies, err := sf.ReconcileStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(inconsistencies))
+
+	// nothing left to sweep
+	swept, err = sf.SweepDustVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, swept)
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// EstimateAccess returns the number of trie nodes on the path to the account, a proxy of the cost to read or write it
+// An executor can price state access with it in a gas model. For an account that does not exist yet they are the nodes
+// its creation would rewrite. The uncommitted changes are taken into account and nothing is changed. The count says
+// nothing about which nodes are cached, telling cold from warm access is up to the caller.
+func (sf *stateFactory) EstimateAccess(addr *iotxaddress.Address) (int, error) {
+	if err := sf.checkOpen(); err != nil {
+		return 0, err
+	}
+	p, ok := sf.trie.(interface {
+		PathNodes([]byte) (int, error)
+	})
+	if !ok {
+		return 0, errors.New("the trie does not support counting the nodes on a path")
+	}
+	return p.PathNodes(iotxaddress.HashPubKey(addr.PublicKey))
+}
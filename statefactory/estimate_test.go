@@ -0,0 +1,49 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestEstimateAccess(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 10)
+	shallow, deep := addrs[0], addrs[1]
+	root := sf.RootHash()
+	nodes, err := sf.EstimateAccess(shallow)
+	assert.Nil(t, err)
+	assert.Equal(t, root, sf.RootHash())
+
+	// entries sharing all but the last bytes of its key push the deep account further down
+	key := iotxaddress.HashPubKey(deep.PublicKey)
+	if key[0] == iotxaddress.HashPubKey(shallow.PublicKey)[0] {
+		t.Skip("the accounts share the first byte of their keys")
+	}
+	before, err := sf.EstimateAccess(deep)
+	assert.Nil(t, err)
+	for _, i := range []int{len(key) - 1, len(key) - 2} {
+		neighbor := append([]byte(nil), key...)
+		neighbor[i] ^= 0xff
+		assert.Nil(t, tr.Upsert(neighbor, []byte{1}))
+	}
+	deepNodes, err := sf.EstimateAccess(deep)
+	assert.Nil(t, err)
+	assert.True(t, deepNodes > before)
+	assert.True(t, deepNodes > nodes)
+	shallowNodes, err := sf.EstimateAccess(shallow)
+	assert.Nil(t, err)
+	assert.Equal(t, nodes, shallowNodes)
+}
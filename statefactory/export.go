@@ -0,0 +1,59 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"encoding/gob"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// StateEntry is a raw entry of the state trie, see ExportState
+type StateEntry struct {
+	Key   []byte
+	Value []byte
+}
+
+// ExportState writes every entry of the state as of the last commit to w and returns the root it was taken at
+// The root is pinned under a brief lock, then the entries are read while mutations and commits go on: the trie keeps
+// the nodes of the pinned root until the export is done, so the export is a consistent point in time however far the
+// state moves meanwhile. The output is a gob stream of the root hash followed by one StateEntry per entry in key
+// order, accounts and the factory's own records alike. Upserting the entries into an empty trie gives the root back.
+func (sf *stateFactory) ExportState(w io.Writer) (common.Hash32B, error) {
+	if err := sf.checkOpen(); err != nil {
+		return common.ZeroHash32B, err
+	}
+	s, ok := sf.trie.(interface {
+		Snapshot() (*trie.Snapshot, error)
+	})
+	if !ok {
+		return common.ZeroHash32B, errors.New("the trie does not support snapshots")
+	}
+	// Commit holds mu for writing, so the snapshot never sees half a commit
+	sf.mu.RLock()
+	snapshot, err := s.Snapshot()
+	sf.mu.RUnlock()
+	if err != nil {
+		return common.ZeroHash32B, err
+	}
+	defer snapshot.Release()
+	root := snapshot.RootHash()
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(root); err != nil {
+		return common.ZeroHash32B, errors.Wrap(err, "failed to write the root")
+	}
+	if err := snapshot.Walk(func(key, value []byte) error {
+		return enc.Encode(StateEntry{Key: key, Value: value})
+	}); err != nil {
+		return common.ZeroHash32B, err
+	}
+	return root, snapshot.Release()
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"encoding/gob"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// pausingWriter blocks the first write until resumed, so a test can act while an export is under way
+type pausingWriter struct {
+	bytes.Buffer
+	paused  chan struct{}
+	resume  chan struct{}
+	started bool
+}
+
+func (w *pausingWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		close(w.paused)
+		<-w.resume
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestExportState(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 5, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	// mutations and commits go on while the export is paused
+	w := &pausingWriter{paused: make(chan struct{}), resume: make(chan struct{})}
+	type result struct {
+		root common.Hash32B
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		root, err := sf.(Exporter).ExportState(w)
+		done <- result{root, err}
+	}()
+	<-w.paused
+	for i, addr := range addrs {
+		assert.Nil(t, sf.AddBalance(addr, big.NewInt(int64(i+1))))
+		_, err = sf.Commit()
+		assert.Nil(t, err)
+	}
+	createAccounts(t, sf, 2, 5)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.NotEqual(t, root, sf.RootHash())
+	close(w.resume)
+	res := <-done
+	assert.Nil(t, res.err)
+	assert.Equal(t, root, res.root)
+
+	// the export is the state before the mutations exactly
+	dec := gob.NewDecoder(&w.Buffer)
+	var exported common.Hash32B
+	assert.Nil(t, dec.Decode(&exported))
+	assert.Equal(t, root, exported)
+	rebuilt, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sfRebuilt := NewStateFactory(rebuilt)
+	for {
+		var entry StateEntry
+		if err := dec.Decode(&entry); err == io.EOF {
+			break
+		} else {
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, rebuilt.Upsert(entry.Key, entry.Value))
+	}
+	assert.Equal(t, root, rebuilt.RootHash())
+	for _, addr := range addrs {
+		balance, err := sfRebuilt.Balance(addr)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+	}
+
+	// a later export sees the current state
+	var buf bytes.Buffer
+	current, err := sf.(Exporter).ExportState(&buf)
+	assert.Nil(t, err)
+	assert.Equal(t, sf.RootHash(), current)
+}
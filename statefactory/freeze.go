@@ -0,0 +1,74 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrAccountFrozen is the error that a FreezeList vetoes a mutation of a frozen account
+var ErrAccountFrozen = errors.New("account is frozen")
+
+// FreezeList is a MutationPolicy vetoing the balance mutations of frozen accounts, set with PolicyOption
+// A frozen account cannot send or spend. Crediting it is allowed unless the list was created to reject credits, so by
+// default funds can still be sent to it, e.g. to recover them once it is unfrozen. The list is checked after the
+// factory's own state, so while the factory is paused any credit fails with ErrFactoryPaused, frozen or not.
+type FreezeList struct {
+	mu            sync.RWMutex
+	frozen        map[AccountKey]bool
+	rejectCredits bool
+}
+
+// NewFreezeList creates an empty freeze list, crediting a frozen account fails with ErrAccountFrozen if rejectCredits
+func NewFreezeList(rejectCredits bool) *FreezeList {
+	return &FreezeList{frozen: make(map[AccountKey]bool), rejectCredits: rejectCredits}
+}
+
+// Freeze freezes the account
+func (l *FreezeList) Freeze(addr *iotxaddress.Address) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.frozen[AccountKeyOf(addr)] = true
+}
+
+// Unfreeze lifts a previous Freeze of the account
+func (l *FreezeList) Unfreeze(addr *iotxaddress.Address) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.frozen, AccountKeyOf(addr))
+}
+
+// IsFrozen returns whether the account is frozen
+func (l *FreezeList) IsFrozen(addr *iotxaddress.Address) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.frozen[AccountKeyOf(addr)]
+}
+
+// AllowTransfer vetoes a transfer from a frozen sender, and to a frozen recipient if credits are rejected
+func (l *FreezeList) AllowTransfer(sender, recipient *iotxaddress.Address, amount *big.Int) error {
+	if l.IsFrozen(sender) {
+		return errors.Wrapf(ErrAccountFrozen, "sender %s", sender.RawAddress)
+	}
+	if l.rejectCredits && l.IsFrozen(recipient) {
+		return errors.Wrapf(ErrAccountFrozen, "recipient %s", recipient.RawAddress)
+	}
+	return nil
+}
+
+// AllowBalanceChange vetoes debiting a frozen account, and crediting it if credits are rejected
+func (l *FreezeList) AllowBalanceChange(addr *iotxaddress.Address, delta *big.Int) error {
+	if !l.IsFrozen(addr) || (delta.Sign() >= 0 && !l.rejectCredits) {
+		return nil
+	}
+	return errors.Wrapf(ErrAccountFrozen, "account %s, delta %s", addr.RawAddress, delta)
+}
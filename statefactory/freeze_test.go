@@ -0,0 +1,59 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestFreezeList(t *testing.T) {
+	for _, rejectCredits := range []bool{false, true} {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		frozen := NewFreezeList(rejectCredits)
+		sf := NewStateFactory(tr, PolicyOption(frozen))
+		addrs := createAccounts(t, sf, 2, 100)
+		frozen.Freeze(addrs[0])
+		assert.True(t, frozen.IsFrozen(addrs[0]))
+		assert.False(t, frozen.IsFrozen(addrs[1]))
+
+		// a frozen account never sends or spends
+		assert.Equal(t, ErrAccountFrozen, errors.Cause(sf.AddBalance(addrs[0], big.NewInt(-1))))
+		assert.Equal(t, ErrAccountFrozen, errors.Cause(sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(1), 0)))
+
+		// it is credited unless credits are rejected
+		credit := sf.AddBalance(addrs[0], big.NewInt(10))
+		transfer := sf.ApplyTransferTx(addrs[1], addrs[0], big.NewInt(5), 0)
+		balance, err := sf.Balance(addrs[0])
+		assert.Nil(t, err)
+		if rejectCredits {
+			assert.Equal(t, ErrAccountFrozen, errors.Cause(credit))
+			assert.Equal(t, ErrAccountFrozen, errors.Cause(transfer))
+			assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+		} else {
+			assert.Nil(t, credit)
+			assert.Nil(t, transfer)
+			assert.Equal(t, 0, balance.Cmp(big.NewInt(115)))
+		}
+
+		// while the factory is paused no credit goes through, frozen or not
+		sf.(Pauser).Pause()
+		assert.Equal(t, ErrFactoryPaused, sf.AddBalance(addrs[0], big.NewInt(1)))
+		assert.Equal(t, ErrFactoryPaused, sf.AddBalance(addrs[1], big.NewInt(1)))
+		sf.(Pauser).Resume()
+
+		frozen.Unfreeze(addrs[0])
+		assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(-1)))
+	}
+}
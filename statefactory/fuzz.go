@@ -0,0 +1,48 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+// +build gofuzz
+
+package statefactory
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// fuzzMaxLeafSize is the leaf size limit of the fuzzed decoding, small so oversized inputs are cheap to reach
+const fuzzMaxLeafSize = 1 << 12
+
+// Fuzz is the go-fuzz entry point of the state leaf codec, seeded by testdata/fuzz/corpus
+//   go-fuzz-build github.com/iotexproject/iotex-core/statefactory
+//   go-fuzz -bin=statefactory-fuzz.zip -workdir=statefactory/testdata/fuzz
+// Decoding arbitrary bytes must return an error rather than panic, and a State it returns must survive encoding and
+// decoding again, in both the checksum and the compressed format, unchanged. The States are compared through their
+// DTOs since gob may decode a zero amount as nil.
+func Fuzz(data []byte) int {
+	state, err := bytesToState(data, fuzzMaxLeafSize)
+	if err != nil {
+		return 0
+	}
+	ss, err := stateToBytes(state)
+	if err != nil {
+		// e.g. a decoded State breaking the invariants, which cannot be written back
+		return 0
+	}
+	compressed, err := compressLeaf(ss)
+	if err != nil {
+		panic(fmt.Sprintf("failed to compress an encoded state: %v", err))
+	}
+	for _, leaf := range [][]byte{ss, compressed} {
+		again, err := bytesToState(leaf, len(ss)+len(compressed))
+		if err != nil {
+			panic(fmt.Sprintf("failed to decode a re-encoded state: %v", err))
+		}
+		if !reflect.DeepEqual(ToDTO(state), ToDTO(again)) {
+			panic(fmt.Sprintf("state changed by a round trip: %+v, then %+v", ToDTO(state), ToDTO(again)))
+		}
+	}
+	return 1
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+// +build gofuzz
+
+package statefactory
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFuzzCorpus runs the fuzz target over the seed corpus, with go test -tags gofuzz
+func TestFuzzCorpus(t *testing.T) {
+	files, err := ioutil.ReadDir(filepath.Join("testdata", "fuzz", "corpus"))
+	assert.Nil(t, err)
+	assert.NotEmpty(t, files)
+	decoded := 0
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", "fuzz", "corpus", file.Name()))
+		assert.Nil(t, err)
+		assert.NotPanics(t, func() {
+			decoded += Fuzz(data)
+		}, file.Name())
+	}
+	// the well-formed seeds decode
+	assert.True(t, decoded >= 3)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInvalidGenesis is the error that the genesis accounts are malformed or the state is not empty
+var ErrInvalidGenesis = errors.New("invalid genesis")
+
+// GenesisAccount is an account allocated at genesis with the fields of Init, see InitGenesis
+type GenesisAccount struct {
+	Address *iotxaddress.Address
+	Init    State
+}
+
+// InitGenesis creates the genesis accounts in an empty state, commits them and returns the genesis root
+// No transaction has been applied at genesis, so every account must have nonce 0, and no election has taken place, so
+// none may be a candidate, cast votes or hold votes. Each account must otherwise be valid for AddStateWithInit and
+// appear once. Every account is checked before anything is written: a malformed genesis fails with ErrInvalidGenesis
+// and leaves the state empty, no root is computed from it.
+func (sf *stateFactory) InitGenesis(accounts []GenesisAccount) (common.Hash32B, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if err := sf.writable(); err != nil {
+		return common.ZeroHash32B, err
+	}
+	if sf.RootHash() != EmptyRootHash {
+		return common.ZeroHash32B, errors.Wrap(ErrInvalidGenesis, "the state is not empty")
+	}
+	seen := make(map[AccountKey]bool, len(accounts))
+	for i, account := range accounts {
+		if account.Address == nil {
+			return common.ZeroHash32B, errors.Wrapf(ErrInvalidGenesis, "account %d has no address", i)
+		}
+		key := AccountKeyOf(account.Address)
+		if seen[key] {
+			return common.ZeroHash32B, errors.Wrapf(ErrInvalidGenesis, "account %s allocated twice",
+				account.Address.RawAddress)
+		}
+		seen[key] = true
+		if err := sf.checkGenesisAccount(account); err != nil {
+			return common.ZeroHash32B, errors.Wrapf(err, "account %s", account.Address.RawAddress)
+		}
+	}
+	for _, account := range accounts {
+		if _, err := sf.addStateWithInit(account.Address, account.Init); err != nil {
+			return common.ZeroHash32B, errors.Wrapf(err, "account %s", account.Address.RawAddress)
+		}
+	}
+	if _, err := sf.commit(); err != nil {
+		return common.ZeroHash32B, err
+	}
+	return sf.RootHash(), nil
+}
+
+// checkGenesisAccount returns an error if the account cannot be allocated at genesis
+func (sf *stateFactory) checkGenesisAccount(account GenesisAccount) error {
+	init := account.Init
+	switch {
+	case init.Nonce != 0:
+		return errors.Wrapf(ErrInvalidGenesis, "nonce %d", init.Nonce)
+	case init.IsCandidate:
+		return errors.Wrap(ErrInvalidGenesis, "candidate")
+	case init.votedWeight().Sign() != 0:
+		return errors.Wrapf(ErrInvalidGenesis, "voted weight %s", init.VotedWeight)
+	case len(init.Voters) > 0 || init.VotingWeight != nil && init.VotingWeight.Sign() != 0:
+		return errors.Wrap(ErrInvalidGenesis, "received votes")
+	}
+	if err := sf.checkPublicKeys(account.Address); err != nil {
+		return err
+	}
+	_, err := sf.initState(account.Address, &init)
+	return err
+}
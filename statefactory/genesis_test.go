@@ -0,0 +1,76 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestInitGenesis(t *testing.T) {
+	newFactory := func() StateFactory {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		return NewStateFactory(tr)
+	}
+	var accounts []GenesisAccount
+	for i := 0; i < 3; i++ {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		accounts = append(accounts, GenesisAccount{Address: addr, Init: State{Balance: big.NewInt(int64(100 * (i + 1)))}})
+	}
+
+	// a nonzero nonce is rejected before anything is written
+	sf := newFactory()
+	malformed := append([]GenesisAccount(nil), accounts...)
+	malformed[2].Init.Nonce = 1
+	root, err := sf.InitGenesis(malformed)
+	assert.Equal(t, ErrInvalidGenesis, errors.Cause(err))
+	assert.Equal(t, common.ZeroHash32B, root)
+	assert.Equal(t, EmptyRootHash, sf.RootHash())
+	_, err = sf.Balance(accounts[0].Address)
+	assert.Equal(t, ErrAccountNotExist, errors.Cause(err))
+
+	// so are candidates, votes and duplicates
+	for _, malform := range []func(*GenesisAccount){
+		func(a *GenesisAccount) { a.Init.IsCandidate = true },
+		func(a *GenesisAccount) { a.Init.VotedWeight = big.NewInt(1) },
+		func(a *GenesisAccount) { a.Init.VotingWeight = big.NewInt(1) },
+		func(a *GenesisAccount) { a.Address = accounts[0].Address },
+	} {
+		malformed = append([]GenesisAccount(nil), accounts...)
+		malform(&malformed[1])
+		_, err = sf.InitGenesis(malformed)
+		assert.Equal(t, ErrInvalidGenesis, errors.Cause(err))
+		assert.Equal(t, EmptyRootHash, sf.RootHash())
+	}
+
+	// a valid genesis is committed, the same accounts give the same root
+	root, err = sf.InitGenesis(accounts)
+	assert.Nil(t, err)
+	assert.Equal(t, sf.RootHash(), root)
+	for i, account := range accounts {
+		balance, err := sf.Balance(account.Address)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(int64(100*(i+1)))))
+	}
+	other, err := newFactory().InitGenesis(accounts)
+	assert.Nil(t, err)
+	assert.Equal(t, root, other)
+
+	// genesis is applied once
+	_, err = sf.InitGenesis(accounts)
+	assert.Equal(t, ErrInvalidGenesis, errors.Cause(err))
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/iotexproject/iotex-core/common"
+)
+
+// HealthStatus summarizes whether the state factory is serving correctly, see Health
+type HealthStatus struct {
+	CommitErr error          // error of the last commit, nil if it succeeded or nothing was committed yet
+	Root      common.Hash32B // current root hash, including the changes since the last commit
+	Height    uint64         // height of the last CommitWithHeight, 0 if none
+	Paused    bool           // whether mutations are rejected, see Pause
+}
+
+// Ready returns whether the factory can apply and commit blocks, i.e. the last commit succeeded and it is not paused
+func (h HealthStatus) Ready() bool {
+	return h.CommitErr == nil && !h.Paused
+}
+
+// Health returns the status of the factory for liveness and readiness probes
+// It reads only fields kept in memory and the root hash, so it is cheap enough to be polled, but it waits for a commit
+// in progress.
+func (sf *stateFactory) Health() HealthStatus {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	status := HealthStatus{CommitErr: sf.commitErr, Root: sf.trie.RootHash(), Paused: sf.paused}
+	if sf.heightKnown {
+		status.Height = sf.lastHeight
+	}
+	return status
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/test/mock/mock_trie"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestHealth(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	status := sf.(Monitor).Health()
+	assert.True(t, status.Ready())
+	assert.Equal(t, EmptyRootHash, status.Root)
+	assert.Equal(t, uint64(0), status.Height)
+
+	createAccounts(t, sf, 2, 10)
+	_, err = sf.CommitWithHeight(7)
+	assert.Nil(t, err)
+	status = sf.(Monitor).Health()
+	assert.True(t, status.Ready())
+	assert.Equal(t, sf.RootHash(), status.Root)
+	assert.Equal(t, uint64(7), status.Height)
+
+	sf.(Pauser).Pause()
+	status = sf.(Monitor).Health()
+	assert.True(t, status.Paused)
+	assert.False(t, status.Ready())
+	assert.Equal(t, uint64(7), status.Height)
+	sf.(Pauser).Resume()
+	assert.True(t, sf.(Monitor).Health().Ready())
+
+	assert.Nil(t, sf.DeleteAll())
+	assert.Equal(t, uint64(0), sf.(Monitor).Health().Height)
+}
+
+func TestHealthCommitError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	sf := NewStateFactory(mtrie)
+	errWrite := errors.New("disk full")
+	mtrie.EXPECT().Get(gomock.Any()).AnyTimes().Return(nil, trie.ErrNotExist)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).AnyTimes().Return(nil)
+	mtrie.EXPECT().RootHash().AnyTimes().Return(EmptyRootHash)
+
+	// a failed commit makes the factory not ready until a commit succeeds again
+	mtrie.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(1).Return(trie.CommitStats{}, errWrite)
+	_, err := sf.Commit()
+	assert.Equal(t, errWrite, err)
+	status := sf.(Monitor).Health()
+	assert.Equal(t, errWrite, status.CommitErr)
+	assert.False(t, status.Ready())
+
+	mtrie.EXPECT().Commit(gomock.Any(), gomock.Any()).Times(1).Return(trie.CommitStats{}, nil)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.True(t, sf.(Monitor).Health().Ready())
+}
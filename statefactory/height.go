@@ -0,0 +1,53 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ErrNonMonotonicHeight is the error that a commit's height is not above the last committed height
+var ErrNonMonotonicHeight = errors.New("non-monotonic height")
+
+// CommitWithHeight commits the state changes of the block at the height, which must be above the last committed height
+// A lower or equal height means a block is applied twice or out of order, it is refused with ErrNonMonotonicHeight and
+// nothing is committed. A factory created with RootIndexOption picks the last height up from the index, so a restarted
+// node keeps refusing the blocks it has applied. Only DeleteAll resets the last height, the trie cannot be rewound to an earlier root. On
+// success the height is also set as by SetHeight, and the root is indexed for RecentRoots if RootIndexOption is set. A
+// failure to index it is returned although the commit stands.
+func (sf *stateFactory) CommitWithHeight(height uint64) (CommitStats, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if sf.heightKnown && height <= sf.lastHeight {
+		return CommitStats{}, errors.Wrapf(ErrNonMonotonicHeight, "height %d, last committed %d", height, sf.lastHeight)
+	}
+	stats, err := sf.commit()
+	if err != nil {
+		return CommitStats{}, err
+	}
+	sf.lastHeight, sf.heightKnown = height, true
+	sf.SetHeight(height)
+	return stats, sf.indexRoot(height, sf.trie.RootHash())
+}
+
+// restoreHeight takes the last committed height from the root index, if any
+// A failure to read the index is logged, the height is then unknown as for a factory without the index.
+func (sf *stateFactory) restoreHeight() {
+	if sf.roots == nil {
+		return
+	}
+	recent, err := sf.loadRecentRoots()
+	if err != nil {
+		sf.log.Error("failed to read the last committed height", "error", err)
+		return
+	}
+	if len(recent) == 0 {
+		return
+	}
+	sf.lastHeight, sf.heightKnown = recent[0].Height, true
+	sf.SetHeight(recent[0].Height)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestCommitWithHeight(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 1, 10)
+
+	for height := uint64(1); height <= 3; height++ {
+		assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+		_, err = sf.CommitWithHeight(height)
+		assert.Nil(t, err)
+	}
+	c, err := sf.Commitment(big.NewInt(0))
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), c.Height)
+
+	// committing height 2 again is refused and commits nothing
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+	_, err = sf.CommitWithHeight(2)
+	assert.Equal(t, ErrNonMonotonicHeight, errors.Cause(err))
+	_, err = sf.CommitWithHeight(3)
+	assert.Equal(t, ErrNonMonotonicHeight, errors.Cause(err))
+	assert.Equal(t, 1, len(sf.(Monitor).PendingChanges()))
+	_, err = sf.CommitWithHeight(4)
+	assert.Nil(t, err)
+	assert.Empty(t, sf.(Monitor).PendingChanges())
+
+	// wiping the state allows replaying from the start
+	assert.Nil(t, sf.DeleteAll())
+	_, err = sf.CommitWithHeight(1)
+	assert.Nil(t, err)
+}
+
+func TestCommitWithHeightRestart(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, RootIndexOption(kv, 0))
+	createAccounts(t, sf, 1, 10)
+	_, err = sf.CommitWithHeight(5)
+	assert.Nil(t, err)
+
+	// a factory created on the same store picks up the last height
+	sf = NewStateFactory(tr, RootIndexOption(kv, 0))
+	assert.Equal(t, uint64(5), sf.(Monitor).Health().Height)
+	_, err = sf.CommitWithHeight(5)
+	assert.Equal(t, ErrNonMonotonicHeight, errors.Cause(err))
+	_, err = sf.CommitWithHeight(6)
+	assert.Nil(t, err)
+
+	// without the index the height is unknown
+	sf = NewStateFactory(tr)
+	_, err = sf.CommitWithHeight(1)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// ErrRootNotFound is the error that the state root has been pruned from the trie, or was never committed
+var ErrRootNotFound = errors.New("state root not found")
+
+// RetainRootsOption keeps the trie nodes of the last n roots committed before the current one, so BalanceAtRoot can
+// read them
+// Every root kept costs the nodes its commit changed, which stay in DB until n more commits have been made. Without
+// the option a root is pruned as soon as the next one is committed. It has no effect if the trie cannot retain roots.
+func RetainRootsOption(n int) Option {
+	return func(sf *stateFactory) {
+		if r, ok := sf.trie.(interface {
+			RetainCommits(int)
+		}); ok {
+			r.RetainCommits(n)
+		}
+	}
+}
+
+// BalanceAtRoot returns the balance of the account as of the committed state root, which needs no height index
+// The root must be the last committed one or one retained with RetainRootsOption, a pruned root returns
+// ErrRootNotFound. An account that did not exist at the root returns ErrAccountNotExist.
+func (sf *stateFactory) BalanceAtRoot(root common.Hash32B, addr iotxaddress.Address) (*big.Int, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	h, ok := sf.trie.(interface {
+		GetAtRoot(common.Hash32B, []byte) ([]byte, error)
+	})
+	if !ok {
+		return nil, errors.New("the trie does not support reading past roots")
+	}
+	key := AccountKeyOf(&addr)
+	leaf, err := h.GetAtRoot(root, key.Bytes())
+	if errors.Cause(err) == trie.ErrRootNotFound {
+		return nil, errors.Wrapf(ErrRootNotFound, "root %x", root)
+	}
+	state, err := decodeLeaf(leaf, err, sf.maxLeafSize)
+	if err == nil {
+		err = sf.verifyState(state, key)
+	}
+	if err != nil {
+		return nil, sf.checkLeaf(key.Bytes(), err)
+	}
+	return state.Balance, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestBalanceAtRoot(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, RetainRootsOption(2))
+	addrs := createAccounts(t, sf, 3, 100)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	// each commit adds 10 to every balance
+	roots := []common.Hash32B{sf.RootHash()}
+	for i := 0; i < 3; i++ {
+		for _, addr := range addrs {
+			assert.Nil(t, sf.AddBalance(addr, big.NewInt(10)))
+		}
+		_, err = sf.Commit()
+		assert.Nil(t, err)
+		roots = append(roots, sf.RootHash())
+	}
+
+	// the current root and the two before it are read, uncommitted changes are not
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+	for i := 1; i < len(roots); i++ {
+		for _, addr := range addrs {
+			balance, err := sf.BalanceAtRoot(roots[i], *addr)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, balance.Cmp(big.NewInt(int64(100+10*i))))
+		}
+	}
+
+	// the oldest root has been pruned
+	_, err = sf.BalanceAtRoot(roots[0], *addrs[0])
+	assert.Equal(t, ErrRootNotFound, errors.Cause(err))
+	_, err = sf.BalanceAtRoot(common.Hash32B{1}, *addrs[0])
+	assert.Equal(t, ErrRootNotFound, errors.Cause(err))
+
+	// an account created later did not exist at the root
+	later := createAccounts(t, sf, 1, 5)[0]
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	_, err = sf.BalanceAtRoot(roots[3], *later)
+	assert.Equal(t, ErrAccountNotExist, errors.Cause(err))
+	balance, err := sf.BalanceAtRoot(sf.RootHash(), *later)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(5)))
+	_, err = sf.BalanceAtRoot(roots[1], *addrs[0])
+	assert.Equal(t, ErrRootNotFound, errors.Cause(err))
+	unknown, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.BalanceAtRoot(sf.RootHash(), *unknown)
+	assert.Equal(t, ErrAccountNotExist, errors.Cause(err))
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrAccountExist is the error that the account to create already exists
+var ErrAccountExist = errors.New("the account already exists")
+
+// AddStateWithInit creates the account with the fields of init, e.g. a genesis candidate with its nonce and self-stake
+// already set
+// The Address of init is replaced by addr and missing amounts count as zero. The state must pass the checks of
+// StrictDecodeOption, a candidate must have locked the minimum self-stake, the votes the account cast must not exceed
+// its stake, and vesting points and assets must be positive and sorted. Votes are taken as given, keeping the voters'
+// and the candidates' records in line is up to the caller, see ReconcileStakes. Unlike CreateState an existing account
+// is refused with ErrAccountExist rather than returned.
+func (sf *stateFactory) AddStateWithInit(addr *iotxaddress.Address, init State) (*State, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return nil, err
+	}
+	return sf.addStateWithInit(addr, init)
+}
+
+// addStateWithInit adds the State as AddStateWithInit does, the caller must hold sf.mu
+func (sf *stateFactory) addStateWithInit(addr *iotxaddress.Address, init State) (*State, error) {
+	if err := sf.checkPublicKeys(addr); err != nil {
+		return nil, err
+	}
+	s, err := sf.initState(addr, &init)
+	if err != nil {
+		return nil, err
+	}
+	key := iotxaddress.HashPubKey(addr.PublicKey)
+	unlock := sf.lockAccounts(key)
+	defer unlock()
+	if _, err := sf.getState(addr); err == nil {
+		return nil, ErrAccountExist
+	} else if err != ErrAccountNotExist {
+		return nil, err
+	}
+	mstate, err := sf.encodeState(s)
+	if err != nil {
+		return nil, err
+	}
+	if err := sf.trie.Upsert(key, mstate); err != nil {
+		return nil, err
+	}
+	sf.touch(addr, ChangeCreated)
+	if err := sf.addAccountCount(1); err != nil {
+		return nil, err
+	}
+	if s.IsCandidate {
+		if err := sf.addToAddressList(candidateListKey, addr); err != nil {
+			return nil, err
+		}
+	}
+	if s.votedWeight().Sign() > 0 {
+		if err := sf.addToAddressList(voterListKey, addr); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// initState returns a copy of init for the account with missing amounts set to zero, checked as AddStateWithInit
+// requires
+func (sf *stateFactory) initState(addr *iotxaddress.Address, init *State) (*State, error) {
+	s := *init
+	s.Address = addr
+	for _, amount := range []**big.Int{&s.Balance, &s.LockedBalance, &s.VotingWeight} {
+		if *amount == nil {
+			*amount = big.NewInt(0)
+		}
+	}
+	if err := checkConsistency(&s); err != nil {
+		return nil, err
+	}
+	if s.IsCandidate && s.LockedBalance.Cmp(sf.minSelfStake) < 0 {
+		return nil, ErrInsufficientSelfStake
+	}
+	if s.votedWeight().Cmp(s.stake()) > 0 {
+		return nil, ErrInsufficientStake
+	}
+	for i, point := range s.VestingSchedule {
+		if point.Amount == nil || point.Amount.Sign() <= 0 {
+			return nil, ErrInvalidAmount
+		}
+		if i > 0 && point.Height < s.VestingSchedule[i-1].Height {
+			return nil, errors.Wrapf(ErrInconsistentState, "vesting point at height %d out of order", point.Height)
+		}
+	}
+	for i, asset := range s.Assets {
+		if asset.Asset == NativeAsset || asset.Balance == nil || asset.Balance.Sign() <= 0 ||
+			i > 0 && asset.Asset <= s.Assets[i-1].Asset {
+			return nil, errors.Wrapf(ErrInconsistentState, "asset %d", asset.Asset)
+		}
+	}
+	// a round trip through the leaf format copies every amount, so init stays the caller's
+	ss, err := stateToBytes(&s)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := bytesToState(ss, len(ss))
+	if err != nil {
+		return nil, err
+	}
+	copied.Address = addr
+	return copied, nil
+}
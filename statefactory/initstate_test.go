@@ -0,0 +1,108 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAddStateWithInit(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(50)))
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// a pre-staked candidate
+	init := State{
+		Nonce:              5,
+		Balance:            big.NewInt(100),
+		LockedBalance:      big.NewInt(50),
+		IsCandidate:        true,
+		RegistrationHeight: 3,
+	}
+	state, err := sf.AddStateWithInit(addr, init)
+	assert.Nil(t, err)
+	assert.Equal(t, addr, state.Address)
+	stored, err := sf.GetState(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), stored.Nonce)
+	assert.Equal(t, 0, stored.Balance.Cmp(big.NewInt(100)))
+	assert.Equal(t, 0, stored.LockedBalance.Cmp(big.NewInt(50)))
+	assert.Equal(t, 0, stored.VotingWeight.Sign())
+	assert.True(t, stored.IsCandidate)
+	assert.Equal(t, uint64(3), stored.RegistrationHeight)
+	ranked, err := sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(ranked))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), count)
+	_, err = sf.AddStateWithInit(addr, init)
+	assert.Equal(t, ErrAccountExist, err)
+
+	// the root is the one of the same account built step by step
+	tr2, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf2 := NewStateFactory(tr2, MinSelfStakeOption(big.NewInt(50)))
+	_, err = sf2.CreateState(addr, 150)
+	assert.Nil(t, err)
+	assert.Nil(t, sf2.SetNonce(addr, 5))
+	assert.Nil(t, sf2.Lock(addr, big.NewInt(50)))
+	assert.Nil(t, sf2.RegisterCandidate(addr, 3))
+	assert.Equal(t, sf2.RootHash(), sf.RootHash())
+}
+
+func TestAddStateWithInitInvalid(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(50)))
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	for _, c := range []struct {
+		init State
+		err  error
+	}{
+		{State{Balance: big.NewInt(-1)}, ErrInconsistentState},
+		{State{VotingWeight: big.NewInt(10)}, ErrInconsistentState},
+		{State{IsCandidate: true, LockedBalance: big.NewInt(49)}, ErrInsufficientSelfStake},
+		{State{Balance: big.NewInt(10), VotedWeight: big.NewInt(11)}, ErrInsufficientStake},
+		{State{VestingSchedule: []VestingPoint{{Height: 1, Amount: big.NewInt(0)}}}, ErrInvalidAmount},
+		{State{Assets: []AssetBalance{{Asset: 2, Balance: big.NewInt(1)}, {Asset: 1, Balance: big.NewInt(1)}}},
+			ErrInconsistentState},
+	} {
+		_, err := sf.AddStateWithInit(addr, c.init)
+		assert.Equal(t, c.err, errors.Cause(err))
+	}
+	assert.Equal(t, root, sf.RootHash())
+
+	// votes received are taken as given, as long as the voting weight is their sum
+	voter, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	init := State{
+		LockedBalance: big.NewInt(50),
+		IsCandidate:   true,
+		VotingWeight:  big.NewInt(30),
+		Voters:        map[common.Hash32B]*big.Int{voterKey(voter): big.NewInt(30)},
+	}
+	state, err := sf.AddStateWithInit(addr, init)
+	assert.Nil(t, err)
+	// the returned state does not share amounts with init
+	init.Voters[voterKey(voter)].SetInt64(40)
+	assert.Equal(t, 0, state.Voters[voterKey(voter)].Cmp(big.NewInt(30)))
+}
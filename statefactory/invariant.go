@@ -0,0 +1,35 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/logger"
+)
+
+// ErrNegativeBalance is the error that a state about to be stored has a negative balance
+var ErrNegativeBalance = errors.New("negative balance")
+
+// checkInvariants catches a state that must never be stored at the write site, before it is persisted
+// A violation is always logged. Builds with the strictinvariants tag panic so the bug surfaces in development, otherwise
+// the write is refused with the error.
+func checkInvariants(s *State) error {
+	var err error
+	switch {
+	case s.Balance != nil && s.Balance.Sign() < 0:
+		err = errors.Wrapf(ErrNegativeBalance, "balance %s", s.Balance)
+	case s.LockedBalance != nil && s.LockedBalance.Sign() < 0:
+		err = errors.Wrapf(ErrNegativeBalance, "locked balance %s", s.LockedBalance)
+	default:
+		return nil
+	}
+	logger.Error().Err(err).Msg("state invariant violated")
+	if strictInvariants {
+		panic(err)
+	}
+	return err
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+func TestNegativeBalanceCaught(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	state := newState(addr, 10)
+	_, err = stateToBytes(state)
+	assert.Nil(t, err)
+
+	// bypass the SubBalance guard
+	state.Balance.Sub(state.Balance, big.NewInt(11))
+	if strictInvariants {
+		assert.Panics(t, func() { stateToBytes(state) })
+		return
+	}
+	_, err = stateToBytes(state)
+	assert.Equal(t, ErrNegativeBalance, errors.Cause(err))
+
+	state = newState(addr, 10)
+	state.LockedBalance.SetInt64(-1)
+	_, err = stateToBytes(state)
+	assert.Equal(t, ErrNegativeBalance, errors.Cause(err))
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// AccountKeyLength is the length of an account key
+const AccountKeyLength = 20
+
+// ErrInvalidAccountKey is the error that the account key has the wrong length
+var ErrInvalidAccountKey = errors.New("invalid account key")
+
+// AccountKey is the trie key of an account, the 20-byte hash of its public key
+type AccountKey [AccountKeyLength]byte
+
+// NewAccountKey creates an AccountKey from the bytes, which must be exactly AccountKeyLength long
+func NewAccountKey(b []byte) (AccountKey, error) {
+	var key AccountKey
+	if len(b) != AccountKeyLength {
+		return key, errors.Wrapf(ErrInvalidAccountKey, "length %d, expect %d", len(b), AccountKeyLength)
+	}
+	copy(key[:], b)
+	return key, nil
+}
+
+// AccountKeyOf returns the AccountKey of the address
+func AccountKeyOf(addr *iotxaddress.Address) AccountKey {
+	var key AccountKey
+	copy(key[:], iotxaddress.HashPubKey(addr.PublicKey))
+	return key
+}
+
+// Bytes returns the key as a byte slice
+func (k AccountKey) Bytes() []byte {
+	return k[:]
+}
+
+// GetStateByKey returns the State of the account with the key
+func (sf *stateFactory) GetStateByKey(key AccountKey) (*State, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	state, err := getStateByKey(sf.trie, key, sf.maxLeafSize)
+	if err == nil {
+		err = sf.verifyState(state, key)
+	}
+	if err != nil {
+		return nil, sf.checkLeaf(key.Bytes(), err)
+	}
+	return state, nil
+}
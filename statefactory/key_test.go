@@ -0,0 +1,39 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAccountKey(t *testing.T) {
+	for _, n := range []int{0, 19, 21, 32} {
+		_, err := NewAccountKey(make([]byte, n))
+		assert.Equal(t, ErrInvalidAccountKey, errors.Cause(err))
+	}
+
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addr := createAccounts(t, sf, 1, 10)[0]
+	key, err := NewAccountKey(iotxaddress.HashPubKey(addr.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, AccountKeyOf(addr), key)
+	state, err := sf.GetStateByKey(key)
+	assert.Nil(t, err)
+	assert.Equal(t, addr.RawAddress, state.Address.RawAddress)
+
+	_, err = sf.GetStateByKey(AccountKey{})
+	assert.Equal(t, ErrAccountNotExist, err)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrResourceLimitExceeded is the error that a query scanning accounts hit a limit set by QueryLimitsOption
+var ErrResourceLimitExceeded = errors.New("resource limit exceeded")
+
+// QueryLimits bound the cost of a single query scanning accounts, a zero field means no limit
+type QueryLimits struct {
+	MaxAccounts int           // accounts read
+	MaxDuration time.Duration // time spent reading accounts
+	MaxBytes    int           // bytes of the state leaves read, the leaf reaching the limit is still read in full
+}
+
+// QueryLimitsOption bounds the queries RankedCandidates, AuditVotingStakes and ReconcileStakes, e.g. on a public node
+// A query hitting a limit stops reading accounts and returns what it gathered so far along with
+// ErrResourceLimitExceeded, so a caller can tell a partial result by the error. The ranking TallyVotes takes is never
+// limited, every node must reach the same tally.
+func QueryLimitsOption(limits QueryLimits) Option {
+	return func(sf *stateFactory) {
+		sf.queryLimits = limits
+	}
+}
+
+// queryBudget tracks the cost of one query against the QueryLimits, a nil budget is unlimited
+type queryBudget struct {
+	limits   QueryLimits
+	start    time.Time
+	accounts int
+	bytes    int
+}
+
+// newQueryBudget starts tracking a query against the factory's limits
+func (sf *stateFactory) newQueryBudget() *queryBudget {
+	return &queryBudget{limits: sf.queryLimits, start: time.Now()}
+}
+
+// check returns ErrResourceLimitExceeded if the query may not read another account
+func (b *queryBudget) check() error {
+	switch {
+	case b == nil:
+		return nil
+	case b.limits.MaxAccounts > 0 && b.accounts >= b.limits.MaxAccounts:
+		return errors.Wrapf(ErrResourceLimitExceeded, "read %d accounts", b.accounts)
+	case b.limits.MaxBytes > 0 && b.bytes >= b.limits.MaxBytes:
+		return errors.Wrapf(ErrResourceLimitExceeded, "read %d bytes", b.bytes)
+	case b.limits.MaxDuration > 0 && time.Since(b.start) >= b.limits.MaxDuration:
+		return errors.Wrapf(ErrResourceLimitExceeded, "ran for %s", time.Since(b.start))
+	}
+	return nil
+}
+
+// scanState pulls an existing State as getState does, charging the read to the query's budget
+func (sf *stateFactory) scanState(b *queryBudget, addr *iotxaddress.Address) (*State, error) {
+	if b == nil {
+		return sf.getState(addr)
+	}
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	key := AccountKeyOf(addr)
+	leaf, err := sf.trie.Get(key.Bytes())
+	b.accounts++
+	b.bytes += len(leaf)
+	state, err := decodeLeaf(leaf, err, sf.maxLeafSize)
+	if err == nil {
+		err = sf.verifyState(state, key)
+	}
+	if err != nil {
+		return nil, sf.checkLeaf(key.Bytes(), err)
+	}
+	state.height = sf.currentHeight()
+	return state, nil
+}
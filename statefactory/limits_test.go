@@ -0,0 +1,79 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestQueryLimits(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, QueryLimitsOption(QueryLimits{MaxAccounts: 2}))
+	addrs := createAccounts(t, sf, 4, 100)
+	// the candidate and voter lists are walked in account key order, register in that order too
+	sort.Slice(addrs, func(i, j int) bool {
+		return bytes.Compare(iotxaddress.HashPubKey(addrs[i].PublicKey), iotxaddress.HashPubKey(addrs[j].PublicKey)) < 0
+	})
+	for i, addr := range addrs {
+		assert.Nil(t, sf.RegisterCandidate(addr, uint64(i)))
+	}
+
+	// the walk stops at the third candidate and ranks the two read so far
+	ranked, err := sf.RankedCandidates()
+	assert.Equal(t, ErrResourceLimitExceeded, errors.Cause(err))
+	assert.Equal(t, 2, len(ranked))
+	assert.Equal(t, addrs[0].RawAddress, ranked[0].Address.RawAddress)
+	assert.Equal(t, addrs[1].RawAddress, ranked[1].Address.RawAddress)
+
+	// the tally is never limited
+	tally, _, err := sf.TallyVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(tally))
+
+	// every voter is over-staked, only those read before the limit are reported
+	for _, addr := range addrs[1:] {
+		assert.Nil(t, sf.Vote(addr, addrs[0], big.NewInt(100)))
+		assert.Nil(t, sf.SubBalanceOf(addr, NativeAsset, big.NewInt(1)))
+	}
+	overStaked, err := sf.AuditVotingStakes()
+	assert.Equal(t, ErrResourceLimitExceeded, errors.Cause(err))
+	assert.Equal(t, []string{addrs[1].RawAddress, addrs[2].RawAddress}, overStaked)
+	found, err := sf.ReconcileStakes()
+	assert.Equal(t, ErrResourceLimitExceeded, errors.Cause(err))
+	assert.Equal(t, 0, len(found))
+}
+
+func TestQueryLimitsBytes(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, QueryLimitsOption(QueryLimits{MaxBytes: 1}))
+	addrs := createAccounts(t, sf, 3, 100)
+	for i, addr := range addrs {
+		assert.Nil(t, sf.RegisterCandidate(addr, uint64(i)))
+	}
+
+	// the first leaf is read in full, then the limit is reached
+	ranked, err := sf.RankedCandidates()
+	assert.Equal(t, ErrResourceLimitExceeded, errors.Cause(err))
+	assert.Equal(t, 1, len(ranked))
+
+	// without limits every candidate is read
+	ranked, err = NewStateFactory(tr).RankedCandidates()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(ranked))
+}
@@ -0,0 +1,55 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"hash/fnv"
+	"sort"
+)
+
+// accountLockStripes is the number of account locks, accounts whose keys hash to the same stripe share a lock
+const accountLockStripes = 256
+
+// lockAccounts locks the accounts of the keys and returns the function that unlocks them
+// Account locks make the read-modify-write of an account atomic while accounts on other stripes are updated
+// concurrently. To avoid deadlocks locks are always taken in this order: the factory lock, for reading by mutations
+// and for writing by Commit, then the account stripes in ascending index order, each stripe once, then countMu or
+// listMu, then pendingMu. A transfer thus locks its two accounts in the same order whichever way the funds flow. No
+// account lock may be taken while another account lock is held except through a single lockAccounts call. A mutation
+// holds the factory lock from its writable check to its last write, so no commit lands in the middle of it, and never
+// takes it a second time.
+func (sf *stateFactory) lockAccounts(keys ...[]byte) func() {
+	stripes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		stripe := stripeOf(key)
+		found := false
+		for _, s := range stripes {
+			if s == stripe {
+				found = true
+				break
+			}
+		}
+		if !found {
+			stripes = append(stripes, stripe)
+		}
+	}
+	sort.Ints(stripes)
+	for _, s := range stripes {
+		sf.accountLocks[s].Lock()
+	}
+	return func() {
+		for i := len(stripes) - 1; i >= 0; i-- {
+			sf.accountLocks[stripes[i]].Unlock()
+		}
+	}
+}
+
+// stripeOf returns the index of the account lock of the key
+func stripeOf(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % accountLockStripes)
+}
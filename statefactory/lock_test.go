@@ -0,0 +1,228 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestConcurrentTransfers(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 8, 1000)
+
+	// each sender transfers to its neighbour, so every account is both sending and receiving concurrently, and the
+	// first two also transfer back and forth to each other in opposite directions
+	const rounds = 20
+	var wg sync.WaitGroup
+	for i := range addrs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for nonce := uint64(0); nonce < rounds; nonce++ {
+				assert.Nil(t, sf.ApplyTransferTx(addrs[i], addrs[(i+1)%len(addrs)], big.NewInt(3), nonce))
+			}
+		}(i)
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			assert.Nil(t, sf.AddBalance(addrs[1], big.NewInt(1)))
+			_ = sf.(Monitor).PendingChanges()
+		}
+	}()
+	wg.Wait()
+
+	total := big.NewInt(0)
+	for i, addr := range addrs {
+		state, err := sf.GetState(addr)
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(rounds), state.Nonce)
+		expected := int64(1000)
+		if i < 2 {
+			expected += rounds
+		}
+		assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(expected)))
+		total.Add(total, state.Balance)
+	}
+	assert.Equal(t, 0, total.Cmp(big.NewInt(8*1000+2*rounds)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+}
+
+func TestLockAccountsOrder(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr).(*stateFactory)
+	a, b := []byte("a"), []byte("b")
+
+	// locking the same pair in opposite orders concurrently does not deadlock
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sf.lockAccounts(a, b)()
+		}()
+		go func() {
+			defer wg.Done()
+			sf.lockAccounts(b, a)()
+		}()
+	}
+	wg.Wait()
+	// the same key twice is locked once
+	sf.lockAccounts(a, a)()
+}
+
+func TestConcurrentCreateState(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// creating the same account concurrently with crediting it never resets the funds credited
+	const creators = 50
+	var wg sync.WaitGroup
+	wg.Add(creators + 1)
+	for i := 0; i < creators; i++ {
+		go func() {
+			defer wg.Done()
+			_, err := sf.CreateState(addr, 0)
+			assert.Nil(t, err)
+		}()
+	}
+	credited := int64(0)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < creators; i++ {
+			if sf.AddBalance(addr, big.NewInt(1)) == nil {
+				credited++
+			}
+		}
+	}()
+	wg.Wait()
+
+	balance, err := sf.Balance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(credited)))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), count)
+	changes := sf.(Monitor).PendingChanges()
+	assert.Equal(t, 1, len(changes))
+	assert.Equal(t, ChangeCreated, changes[0].Kind)
+}
+
+func TestConcurrentDeleteState(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// the account is deleted and recreated over and over while it is read, a read sees it whole or not at all
+	const rounds = 200
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			_, err := sf.CreateState(addr, 100)
+			assert.Nil(t, err)
+			assert.Nil(t, sf.DeleteState(addr))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			state, err := sf.GetState(addr)
+			if err == ErrAccountNotExist {
+				continue
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(100)))
+			assert.Equal(t, uint64(0), state.Nonce)
+			assert.Nil(t, checkConsistency(state))
+		}
+	}()
+	wg.Wait()
+
+	_, err = sf.GetState(addr)
+	assert.Equal(t, ErrAccountNotExist, err)
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), count)
+}
+
+func TestConcurrentCandidates(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	const accounts = 64
+	addrs := createAccounts(t, sf, accounts, 100)
+
+	// candidates register and voters vote concurrently while commits land, no list entry or vote is lost
+	var wg sync.WaitGroup
+	wg.Add(2*accounts + 1)
+	for i, addr := range addrs {
+		go func(i int, addr *iotxaddress.Address) {
+			defer wg.Done()
+			assert.Nil(t, sf.RegisterCandidate(addr, uint64(i)))
+		}(i, addr)
+	}
+	for _, addr := range addrs {
+		go func(addr *iotxaddress.Address) {
+			defer wg.Done()
+			for {
+				err := sf.Vote(addr, addrs[0], big.NewInt(1))
+				if err != ErrNotCandidate {
+					assert.Nil(t, err)
+					return
+				}
+			}
+		}(addr)
+	}
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 10; i++ {
+			_, err := sf.Commit()
+			assert.Nil(t, err)
+		}
+	}()
+	wg.Wait()
+
+	sfi := sf.(*stateFactory)
+	candidates, err := sfi.candidates()
+	assert.Nil(t, err)
+	assert.Equal(t, accounts, len(candidates))
+	voters, err := sfi.getAddressList(voterListKey)
+	assert.Nil(t, err)
+	assert.Equal(t, accounts, len(voters))
+	tally, _, err := sf.TallyVotes()
+	assert.Nil(t, err)
+	assert.Equal(t, accounts, len(tally))
+	assert.Equal(t, 0, tally[addrs[0].RawAddress].Cmp(big.NewInt(accounts)))
+	repaired, err := sf.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, repaired)
+}
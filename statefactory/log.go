@@ -0,0 +1,49 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+)
+
+type (
+	// Logger receives the structured log lines of the factory, fields alternate keys and values
+	Logger interface {
+		Debug(msg string, fields ...interface{})
+		Info(msg string, fields ...interface{})
+		Warn(msg string, fields ...interface{})
+		Error(msg string, fields ...interface{})
+	}
+
+	// nopLogger discards all log lines
+	nopLogger struct{}
+)
+
+func (nopLogger) Debug(string, ...interface{}) {}
+
+func (nopLogger) Info(string, ...interface{}) {}
+
+func (nopLogger) Warn(string, ...interface{}) {}
+
+func (nopLogger) Error(string, ...interface{}) {}
+
+// LoggerOption sets the logger the factory reports corrupted leaves, pauses and failed commits to, default discards
+func LoggerOption(log Logger) Option {
+	return func(sf *stateFactory) {
+		sf.log = log
+	}
+}
+
+// checkLeaf warns about a leaf under the key that cannot be trusted, err is returned as is
+func (sf *stateFactory) checkLeaf(key []byte, err error) error {
+	switch errors.Cause(err) {
+	case ErrLeafCorrupted, ErrLeafTooLarge, ErrFailedToUnmarshalState, ErrAddressKeyMismatch,
+		ErrInconsistentState:
+		sf.log.Warn("corrupted state leaf", "key", key, "error", err)
+	}
+	return err
+}
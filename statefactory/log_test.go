@@ -0,0 +1,76 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestLogger(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	log := &capturingLogger{}
+	sf := NewStateFactory(tr, LoggerOption(log))
+	addrs := createAccounts(t, sf, 2, 10)
+	_, err = sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	assert.Empty(t, log.lines)
+
+	sf.(Pauser).Pause()
+	sf.(Pauser).Resume()
+	assert.Equal(t, []string{"INFO state factory paused", "INFO state factory resumed"}, log.lines)
+
+	// a corrupted leaf met while walking the accounts is warned about with its key
+	log.lines, log.fields = nil, nil
+	key := iotxaddress.HashPubKey(addrs[1].PublicKey)
+	assert.Nil(t, tr.Upsert(key, []byte{stateFormatChecksum, 0, 0, 0, 0, 1}))
+	assert.Equal(t, ErrLeafCorrupted, sf.SelfCheck())
+	assert.Equal(t, []string{"WARN corrupted state leaf"}, log.lines)
+	assert.Equal(t, "key", log.fields[0][0])
+	assert.Equal(t, key, log.fields[0][1])
+	_, err = sf.Balance(addrs[1])
+	assert.Equal(t, ErrLeafCorrupted, err)
+	assert.Equal(t, 2, len(log.lines))
+
+	// the default logger discards everything
+	sf = NewStateFactory(tr)
+	_, err = sf.GetState(addrs[1])
+	assert.Equal(t, ErrLeafCorrupted, err)
+}
+
+// capturingLogger records the log lines as level and message, with their fields
+type capturingLogger struct {
+	lines  []string
+	fields [][]interface{}
+}
+
+func (l *capturingLogger) Debug(msg string, fields ...interface{}) {
+	l.log("DEBUG", msg, fields)
+}
+
+func (l *capturingLogger) Info(msg string, fields ...interface{}) {
+	l.log("INFO", msg, fields)
+}
+
+func (l *capturingLogger) Warn(msg string, fields ...interface{}) {
+	l.log("WARN", msg, fields)
+}
+
+func (l *capturingLogger) Error(msg string, fields ...interface{}) {
+	l.log("ERROR", msg, fields)
+}
+
+func (l *capturingLogger) log(level, msg string, fields []interface{}) {
+	l.lines = append(l.lines, level+" "+msg)
+	l.fields = append(l.fields, fields)
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// metaKVNameSpace is the namespace of account metadata in the KV store
+const metaKVNameSpace = "AccountMeta"
+
+var (
+	// ErrNoMetaStore is the error that no KV store has been set for account metadata
+	ErrNoMetaStore = errors.New("no metadata store")
+
+	// ErrMetaNotExist is the error that the account has no metadata under the key
+	ErrMetaNotExist = errors.New("the metadata does not exist")
+)
+
+// MetaStoreOption sets the KV store account metadata is kept in, by default SetMeta and GetMeta fail
+// The store may be shared with the trie, metadata has its own namespace.
+func MetaStoreOption(kv db.KVStore) Option {
+	return func(sf *stateFactory) {
+		sf.meta = kv
+	}
+}
+
+// SetMeta attaches an opaque value to the account under the key, e.g. a label or a KYC reference
+// Metadata is local to this node and not part of consensus: it is kept outside the trie, so it never changes RootHash,
+// is not committed or replicated and may differ between nodes. It is written at once, regardless of Commit or Pause.
+func (sf *stateFactory) SetMeta(addr *iotxaddress.Address, key, value []byte) error {
+	if sf.meta == nil {
+		return ErrNoMetaStore
+	}
+	return sf.meta.Put(metaKVNameSpace, metaKey(addr, key), value)
+}
+
+// GetMeta returns the value attached to the account under the key, see SetMeta
+func (sf *stateFactory) GetMeta(addr *iotxaddress.Address, key []byte) ([]byte, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	if sf.meta == nil {
+		return nil, ErrNoMetaStore
+	}
+	value, err := sf.meta.Get(metaKVNameSpace, metaKey(addr, key))
+	if cause := errors.Cause(err); cause == db.ErrNotExist || cause == bolt.ErrBucketNotFound {
+		return nil, ErrMetaNotExist
+	}
+	return value, err
+}
+
+// metaKey returns the KV store key of the account's metadata, the account key has a fixed length so keys never clash
+func metaKey(addr *iotxaddress.Address, key []byte) []byte {
+	return append(AccountKeyOf(addr).Bytes(), key...)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestMeta(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	addrs := createAccounts(t, NewStateFactory(tr), 2, 10)
+	assert.Equal(t, ErrNoMetaStore, NewStateFactory(tr).SetMeta(addrs[0], []byte("label"), []byte("cold wallet")))
+
+	sf := NewStateFactory(tr, MetaStoreOption(kv))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	root := sf.RootHash()
+	_, err = sf.GetMeta(addrs[0], []byte("label"))
+	assert.Equal(t, ErrMetaNotExist, err)
+
+	// metadata never changes the root or shows up as a pending change
+	assert.Nil(t, sf.SetMeta(addrs[0], []byte("label"), []byte("cold wallet")))
+	assert.Nil(t, sf.SetMeta(addrs[0], []byte("kyc"), []byte("ref-42")))
+	assert.Equal(t, root, sf.RootHash())
+	assert.Empty(t, sf.(Monitor).PendingChanges())
+	stats, err := sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stats.Leaves)
+	assert.Equal(t, root, sf.RootHash())
+
+	value, err := sf.GetMeta(addrs[0], []byte("label"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("cold wallet"), value)
+	value, err = sf.GetMeta(addrs[0], []byte("kyc"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ref-42"), value)
+	// keys are per account
+	_, err = sf.GetMeta(addrs[1], []byte("label"))
+	assert.Equal(t, ErrMetaNotExist, err)
+}
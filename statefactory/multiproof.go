@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+type (
+	// LeafBinding binds an account of a multiproof to its State as stored in the trie
+	LeafBinding struct {
+		// Address is the public part of the account's address
+		Address *iotxaddress.Address
+		// Leaf is the account's State as stored in the trie
+		Leaf []byte
+	}
+
+	// MultiProof is what a third party needs to check the states of several accounts as of a committed block
+	// The accounts' paths share the trie nodes near the root, which are only carried once.
+	MultiProof struct {
+		// Leaves are the proven accounts, in the order they were asked for
+		Leaves []LeafBinding
+		// Nodes are the trie nodes on the paths of all the accounts, each once, see trie.VerifyMultiProof
+		Nodes [][]byte
+		// Root is the state root the paths lead from
+		Root common.Hash32B
+		// Height is the height of the block the root was committed at
+		Height uint64
+	}
+)
+
+// MultiProof returns the proof of the accounts' states as of the last CommitWithHeight, as ProofBundle does for one
+func (sf *stateFactory) MultiProof(addrs []iotxaddress.Address) (MultiProof, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return MultiProof{}, ErrClosed
+	}
+	if !sf.heightKnown {
+		return MultiProof{}, ErrNoCommittedHeight
+	}
+	p, ok := sf.trie.(interface {
+		MultiProof([][]byte) ([][]byte, error)
+		CommittedRootHash() common.Hash32B
+	})
+	if !ok {
+		return MultiProof{}, errors.New("the trie does not support proofs")
+	}
+	keys := make([][]byte, len(addrs))
+	for i := range addrs {
+		keys[i] = AccountKeyOf(&addrs[i]).Bytes()
+	}
+	nodes, err := p.MultiProof(keys)
+	if errors.Cause(err) == trie.ErrNotExist {
+		return MultiProof{}, ErrAccountNotExist
+	}
+	if err != nil {
+		return MultiProof{}, err
+	}
+	root := p.CommittedRootHash()
+	leaves, err := trie.VerifyMultiProof(root, keys, nodes)
+	if err != nil {
+		return MultiProof{}, err
+	}
+	bindings := make([]LeafBinding, len(addrs))
+	for i, leaf := range leaves {
+		// the address is the one stored with the account, which the verifier checks the leaf against
+		addr := &addrs[i]
+		state, err := bytesToState(leaf, sf.maxLeafSize)
+		if err != nil {
+			return MultiProof{}, sf.checkLeaf(keys[i], err)
+		}
+		if state.Address != nil {
+			addr = state.Address
+		}
+		bindings[i] = LeafBinding{
+			Address: &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress},
+			Leaf:    leaf,
+		}
+	}
+	return MultiProof{Leaves: bindings, Nodes: nodes, Root: root, Height: sf.lastHeight}, nil
+}
+
+// VerifyMultiProof checks the multiproof against the state root trusted for the height and returns the States it
+// proves, in the order of its leaves
+// Every leaf is checked as VerifyBundle checks a bundle's, against the paths picked out of the shared nodes.
+func VerifyMultiProof(proof MultiProof, root common.Hash32B, height uint64) ([]*State, error) {
+	if proof.Root != root {
+		return nil, errors.Wrapf(ErrInvalidBundle, "root %x, trusted %x", proof.Root, root)
+	}
+	if proof.Height != height {
+		return nil, errors.Wrapf(ErrInvalidBundle, "height %d, trusted %d", proof.Height, height)
+	}
+	keys := make([][]byte, len(proof.Leaves))
+	for i, binding := range proof.Leaves {
+		if binding.Address == nil {
+			return nil, errors.Wrapf(ErrInvalidBundle, "leaf %d has no address", i)
+		}
+		keys[i] = AccountKeyOf(binding.Address).Bytes()
+	}
+	leaves, err := trie.VerifyMultiProof(root, keys, proof.Nodes)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidBundle, "%v", err)
+	}
+	states := make([]*State, len(leaves))
+	for i, leaf := range leaves {
+		binding := proof.Leaves[i]
+		if states[i], err = provenState(binding.Address, binding.Leaf, leaf, height); err != nil {
+			return nil, errors.Wrapf(err, "leaf %d", i)
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestMultiProof(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	created := createAccounts(t, sf, 16, 100)
+	addrs := make([]iotxaddress.Address, 8)
+	for i := range addrs {
+		addrs[i] = *created[i]
+	}
+	_, err = sf.(Prover).MultiProof(addrs)
+	assert.Equal(t, ErrNoCommittedHeight, err)
+	_, err = sf.CommitWithHeight(7)
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	// the accounts share the nodes near the root, which separate bundles carry each
+	assert.Nil(t, sf.AddBalance(created[0], big.NewInt(5)))
+	proof, err := sf.(Prover).MultiProof(addrs)
+	assert.Nil(t, err)
+	assert.Equal(t, root, proof.Root)
+	assert.Equal(t, uint64(7), proof.Height)
+	separate := 0
+	for _, addr := range created[:8] {
+		bundle, err := sf.(Prover).ProofBundle(addr)
+		assert.Nil(t, err)
+		separate += len(bundle.Proof)
+	}
+	assert.True(t, len(proof.Nodes) < separate)
+	states, err := VerifyMultiProof(proof, root, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, len(addrs), len(states))
+	for i, state := range states {
+		assert.Equal(t, addrs[i].RawAddress, state.Address.RawAddress)
+		assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(100)))
+	}
+
+	missing, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.(Prover).MultiProof(append(addrs, *missing))
+	assert.Equal(t, ErrAccountNotExist, err)
+
+	// altering the proof fails the verification
+	alter := map[string]func(p *MultiProof){
+		"swapped leaves": func(p *MultiProof) {
+			p.Leaves = append([]LeafBinding{}, p.Leaves...)
+			p.Leaves[0].Leaf, p.Leaves[1].Leaf = p.Leaves[1].Leaf, p.Leaves[0].Leaf
+		},
+		"unproven account": func(p *MultiProof) {
+			p.Leaves = append(p.Leaves, LeafBinding{Address: created[8], Leaf: p.Leaves[0].Leaf})
+		},
+		"dropped node": func(p *MultiProof) { p.Nodes = p.Nodes[1:] },
+		"root":         func(p *MultiProof) { p.Root[0] ^= 1 },
+		"height":       func(p *MultiProof) { p.Height++ },
+	}
+	for name, f := range alter {
+		altered := proof
+		f(&altered)
+		_, err := VerifyMultiProof(altered, root, 7)
+		assert.Equal(t, ErrInvalidBundle, errors.Cause(err), name)
+	}
+	_, err = VerifyMultiProof(proof, sf.RootHash(), 7)
+	assert.Equal(t, ErrInvalidBundle, errors.Cause(err))
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+const (
+	// maxNameLen is the maximum length in bytes of a registered name
+	maxNameLen = 64
+)
+
+var (
+	namePrefix = []byte("name.")
+
+	// ErrInvalidName is the error that the name is empty or too long
+	ErrInvalidName = errors.New("invalid name")
+
+	// ErrNameTaken is the error that the name is already registered to another address
+	ErrNameTaken = errors.New("name already registered to another address")
+
+	// ErrNameNotExist is the error that the name is not registered
+	ErrNameNotExist = errors.New("the name does not exist")
+)
+
+// RegisterName maps a human-readable name to the given address
+// Names are stored in the trie alongside the accounts, so they are part of the state root. A name maps to exactly one
+// address: registering a name that is taken by another address fails with ErrNameTaken, while registering it again to
+// the same address is a no-op. An address may own more than one name.
+func (sf *stateFactory) RegisterName(name string, addr *iotxaddress.Address) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if len(name) == 0 || len(name) > maxNameLen {
+		return ErrInvalidName
+	}
+	// the name is locked like an account, so two registrations of the same name cannot both find it free
+	unlock := sf.lockAccounts(nameKey(name), iotxaddress.HashPubKey(addr.PublicKey))
+	defer unlock()
+	if _, err := sf.getState(addr); err != nil {
+		return err
+	}
+	owner, err := resolveName(sf.trie, name)
+	switch {
+	case err == nil:
+		if bytes.Equal(owner.PublicKey, addr.PublicKey) {
+			return nil
+		}
+		return ErrNameTaken
+	case err != ErrNameNotExist:
+		return err
+	}
+	value, err := nameToBytes(addr)
+	if err != nil {
+		return err
+	}
+	return sf.trie.Upsert(nameKey(name), value)
+}
+
+// ResolveName returns the address the name is registered to
+func (sf *stateFactory) ResolveName(name string) (*iotxaddress.Address, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	return resolveName(sf.trie, name)
+}
+
+// resolveName looks up the name in the trie
+func resolveName(tr trie.Trie, name string) (*iotxaddress.Address, error) {
+	value, err := tr.Get(nameKey(name))
+	if errors.Cause(err) == trie.ErrNotExist {
+		return nil, ErrNameNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytesToName(value)
+}
+
+// nameKey returns the trie key of the name, derived like an account key but domain separated by the name prefix
+func nameKey(name string) []byte {
+	digest := blake2b.Sum256(append(namePrefix, []byte(name)...))
+	return digest[7:27]
+}
+
+// nameToBytes serializes the public part of the address a name is registered to
+func nameToBytes(addr *iotxaddress.Address) ([]byte, error) {
+	var ss bytes.Buffer
+	e := gob.NewEncoder(&ss)
+	if err := e.Encode(&iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}); err != nil {
+		return nil, errors.Wrap(err, "failed to marshal name")
+	}
+	return ss.Bytes(), nil
+}
+
+// bytesToName de-serializes the address a name is registered to
+func bytesToName(ss []byte) (*iotxaddress.Address, error) {
+	var addr iotxaddress.Address
+	e := gob.NewDecoder(bytes.NewBuffer(ss))
+	if err := e.Decode(&addr); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal name")
+	}
+	return &addr, nil
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestRegisterName(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	a, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	b, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// the address must have an account
+	assert.Equal(t, ErrAccountNotExist, sf.RegisterName("alfa", a))
+	_, err = sf.CreateState(a, 10)
+	assert.Nil(t, err)
+	_, err = sf.CreateState(b, 20)
+	assert.Nil(t, err)
+
+	assert.Equal(t, ErrInvalidName, sf.RegisterName("", a))
+	assert.Equal(t, ErrInvalidName, sf.RegisterName(strings.Repeat("a", maxNameLen+1), a))
+	_, err = sf.ResolveName("alfa")
+	assert.Equal(t, ErrNameNotExist, err)
+
+	// registering a name changes the state root
+	root := sf.RootHash()
+	assert.Nil(t, sf.RegisterName("alfa", a))
+	assert.NotEqual(t, root, sf.RootHash())
+
+	// re-registering to the same address is a no-op
+	root = sf.RootHash()
+	assert.Nil(t, sf.RegisterName("alfa", a))
+	assert.Equal(t, root, sf.RootHash())
+
+	// duplicate name for another address is rejected
+	assert.Equal(t, ErrNameTaken, sf.RegisterName("alfa", b))
+	assert.Nil(t, sf.RegisterName("bravo", b))
+	assert.Nil(t, sf.RegisterName("charlie", b))
+
+	// names resolve through a new factory on the same trie
+	sf = NewStateFactory(tr)
+	addr, err := sf.ResolveName("alfa")
+	assert.Nil(t, err)
+	assert.Equal(t, a.PublicKey, addr.PublicKey)
+	assert.Equal(t, a.RawAddress, addr.RawAddress)
+	assert.Nil(t, addr.PrivateKey)
+	addr, err = sf.ResolveName("charlie")
+	assert.Nil(t, err)
+	assert.Equal(t, b.RawAddress, addr.RawAddress)
+}
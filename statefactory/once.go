@@ -0,0 +1,87 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+)
+
+// appliedTxKVNameSpace is the namespace of the applied transaction hashes in the KV store
+const appliedTxKVNameSpace = "AppliedTx"
+
+// ErrNoAppliedTxStore is the error that no KV store has been set for the applied transaction hashes
+var ErrNoAppliedTxStore = errors.New("no applied transaction store")
+
+// AppliedTxStoreOption sets the KV store ApplyOnce records applied transaction hashes in, by default ApplyOnce fails
+// The store may be shared with the trie, the hashes have their own namespace.
+func AppliedTxStoreOption(kv db.KVStore) Option {
+	return func(sf *stateFactory) {
+		sf.appliedTxs = kv
+	}
+}
+
+// ApplyOnce runs fn only if the transaction with the hash has not been applied yet, recording the hash if fn succeeds
+// Applying a recorded hash again is a no-op returning nil, a failed fn is not recorded so it can be retried. Calls are
+// serialized, so the same hash never runs twice concurrently. The record is local to this node and persisted by the
+// next commit along with the changes fn made, so changes lost before being committed are applied again on replay.
+func (sf *stateFactory) ApplyOnce(txHash common.Hash32B, fn func() error) error {
+	if sf.appliedTxs == nil {
+		return ErrNoAppliedTxStore
+	}
+	sf.onceMu.Lock()
+	defer sf.onceMu.Unlock()
+	sf.pendingMu.Lock()
+	_, ok := sf.applied[txHash]
+	sf.pendingMu.Unlock()
+	if ok {
+		return nil
+	}
+	_, err := sf.appliedTxs.Get(appliedTxKVNameSpace, txHash[:])
+	switch cause := errors.Cause(err); {
+	case err == nil:
+		return nil
+	case cause != db.ErrNotExist && cause != bolt.ErrBucketNotFound:
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+	sf.pendingMu.Lock()
+	defer sf.pendingMu.Unlock()
+	if sf.applied == nil {
+		sf.applied = make(map[common.Hash32B]struct{})
+	}
+	sf.applied[txHash] = struct{}{}
+	return nil
+}
+
+// persistApplied writes the hashes recorded by ApplyOnce since the last commit to the store, the caller must hold sf.mu
+// for writing
+// The hashes stay buffered if they cannot be written, so the next commit tries again.
+func (sf *stateFactory) persistApplied() error {
+	sf.pendingMu.Lock()
+	defer sf.pendingMu.Unlock()
+	if len(sf.applied) == 0 {
+		return nil
+	}
+	keys := make([][]byte, 0, len(sf.applied))
+	values := make([][]byte, 0, len(sf.applied))
+	for txHash := range sf.applied {
+		key := txHash
+		keys = append(keys, key[:])
+		values = append(values, []byte{1})
+	}
+	if err := sf.appliedTxs.BatchPut(appliedTxKVNameSpace, keys, values); err != nil {
+		return err
+	}
+	sf.applied = nil
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestApplyOnce(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, AppliedTxStoreOption(kv))
+	addrs := createAccounts(t, sf, 1, 10)
+	credit := func() error {
+		return sf.AddBalance(addrs[0], big.NewInt(5))
+	}
+	assert.Equal(t, ErrNoAppliedTxStore, NewStateFactory(tr).ApplyOnce(common.Hash32B{1}, credit))
+
+	// the second call with the same hash is a no-op
+	assert.Nil(t, sf.ApplyOnce(common.Hash32B{1}, credit))
+	assert.Nil(t, sf.ApplyOnce(common.Hash32B{1}, credit))
+	balance, err := sf.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(15)))
+
+	// a failed application is not recorded and can be retried
+	errFailed := errors.New("failed")
+	assert.Equal(t, errFailed, sf.ApplyOnce(common.Hash32B{2}, func() error { return errFailed }))
+	assert.Nil(t, sf.ApplyOnce(common.Hash32B{2}, credit))
+	balance, err = sf.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(20)))
+}
+
+func TestApplyOnceCommit(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, AppliedTxStoreOption(kv))
+	addrs := createAccounts(t, sf, 1, 10)
+	credit := func() error {
+		return sf.AddBalance(addrs[0], big.NewInt(5))
+	}
+
+	// the hash is buffered until the commit persisting the credit, yet not applied twice meanwhile
+	hash := common.Hash32B{1}
+	assert.Nil(t, sf.ApplyOnce(hash, credit))
+	_, err = kv.Get(appliedTxKVNameSpace, hash[:])
+	assert.NotNil(t, err)
+	assert.Nil(t, sf.ApplyOnce(hash, credit))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	_, err = kv.Get(appliedTxKVNameSpace, hash[:])
+	assert.Nil(t, err)
+	balance, err := sf.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(15)))
+
+	// a credit lost before the commit is applied again on replay
+	assert.Nil(t, sf.ApplyOnce(common.Hash32B{2}, credit))
+	replayed := NewStateFactory(tr, AppliedTxStoreOption(kv))
+	applied := false
+	assert.Nil(t, replayed.ApplyOnce(hash, func() error { applied = true; return nil }))
+	assert.False(t, applied)
+	assert.Nil(t, replayed.ApplyOnce(common.Hash32B{2}, func() error { applied = true; return nil }))
+	assert.True(t, applied)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common/utils"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+var (
+	// paramsKey is the trie key of the hash of the governance parameters
+	paramsKey = func() []byte {
+		digest := blake2b.Sum256([]byte("params"))
+		return digest[7:27]
+	}()
+
+	// ErrParamsMismatch is the error that the committed governance parameters differ from the configured ones
+	ErrParamsMismatch = errors.New("governance parameters mismatch")
+)
+
+// CheckParams verifies the governance parameters committed in the state match the configured ones
+// It should be called when opening an existing state, a state that has never been committed passes.
+func (sf *stateFactory) CheckParams() error {
+	if err := sf.checkOpen(); err != nil {
+		return err
+	}
+	stored, err := sf.trie.Get(paramsKey)
+	if errors.Cause(err) == trie.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(stored, sf.paramsHash()) {
+		return errors.Wrapf(ErrParamsMismatch, "committed %x, configured %x", stored, sf.paramsHash())
+	}
+	return nil
+}
+
+// putParams stores the hash of the configured governance parameters, so the root commits to them
+func (sf *stateFactory) putParams() error {
+	hash := sf.paramsHash()
+	stored, err := sf.trie.Get(paramsKey)
+	if err == nil && bytes.Equal(stored, hash) {
+		return nil
+	}
+	if err != nil && errors.Cause(err) != trie.ErrNotExist {
+		return err
+	}
+	return sf.trie.Upsert(paramsKey, hash)
+}
+
+// paramsHash returns the hash of the governance parameters: the minimum self-stake, the maximum voters and, once they
+// are set, the minimum vote weight and the self-stake weight, which are left out at 0 so states committed before they
+// existed keep their hash
+// Once the self-stake weight is set the minimum vote weight is written even at 0, so neither is mistaken for the other.
+func (sf *stateFactory) paramsHash() []byte {
+	var b bytes.Buffer
+	writeInt := func(i *big.Int) {
+		raw := i.Bytes()
+		b.Write(utils.Uint64ToBytes(uint64(len(raw))))
+		b.Write(raw)
+	}
+	writeInt(sf.minSelfStake)
+	b.Write(utils.Uint64ToBytes(uint64(sf.maxVoters)))
+	weighted := sf.stakeWeight != nil && sf.stakeWeight.Sign() > 0
+	if sf.minVote.Sign() > 0 || weighted {
+		writeInt(sf.minVote)
+	}
+	if weighted {
+		writeInt(sf.stakeWeight.Num())
+		writeInt(sf.stakeWeight.Denom())
+	}
+	digest := blake2b.Sum256(b.Bytes())
+	return digest[:]
+}
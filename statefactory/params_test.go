@@ -0,0 +1,67 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestParamsInRoot(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	commit := func(opts ...Option) (trie.Trie, StateFactory) {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		sf := NewStateFactory(tr, opts...)
+		_, err = sf.CreateState(addr, 10)
+		assert.Nil(t, err)
+		_, err = sf.Commit()
+		assert.Nil(t, err)
+		return tr, sf
+	}
+
+	// same accounts, different min self-stake, different committed root
+	_, sf := commit()
+	root := sf.RootHash()
+	tr, sf := commit(MinSelfStakeOption(big.NewInt(100)))
+	assert.NotEqual(t, root, sf.RootHash())
+	_, sf1 := commit(MaxVotersOption(10))
+	assert.NotEqual(t, root, sf1.RootHash())
+	assert.NotEqual(t, sf.RootHash(), sf1.RootHash())
+	_, sf1 = commit(MinVoteWeightOption(big.NewInt(5)))
+	assert.NotEqual(t, root, sf1.RootHash())
+	_, sf1 = commit(MinVoteWeightOption(big.NewInt(0)))
+	assert.Equal(t, root, sf1.RootHash())
+	_, sf1 = commit(SelfStakeWeightOption(big.NewRat(3, 2)))
+	assert.NotEqual(t, root, sf1.RootHash())
+	_, sf2 := commit(SelfStakeWeightOption(big.NewRat(1, 2)))
+	assert.NotEqual(t, sf1.RootHash(), sf2.RootHash())
+	_, sf2 = commit(SelfStakeWeightOption(big.NewRat(0, 1)))
+	assert.Equal(t, root, sf2.RootHash())
+	_, sf2 = commit(SelfStakeWeightOption(big.NewRat(-1, 2)))
+	assert.Equal(t, root, sf2.RootHash())
+
+	// reopening with the same parameters passes, with different ones is rejected
+	assert.Nil(t, NewStateFactory(tr, MinSelfStakeOption(big.NewInt(100))).CheckParams())
+	err = NewStateFactory(tr, MinSelfStakeOption(big.NewInt(99))).CheckParams()
+	assert.Equal(t, ErrParamsMismatch, errors.Cause(err))
+	err = NewStateFactory(tr).CheckParams()
+	assert.Equal(t, ErrParamsMismatch, errors.Cause(err))
+
+	// a state never committed passes
+	tr, err = trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	assert.Nil(t, NewStateFactory(tr).CheckParams())
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ChangeKind is the kind of change made to an account
+type ChangeKind int
+
+const (
+	// ChangeCreated means the account has been created
+	ChangeCreated ChangeKind = iota
+	// ChangeUpdated means the existing account has been updated
+	ChangeUpdated
+	// ChangeDeleted means the existing account has been deleted
+	ChangeDeleted
+)
+
+// AddressChange is an account changed since the last commit
+type AddressChange struct {
+	Address *iotxaddress.Address
+	Kind    ChangeKind
+}
+
+// PendingChanges returns the accounts changed since the last commit, sorted by account key
+// Each account is listed once with its net change, an account created and deleted again is not listed.
+func (sf *stateFactory) PendingChanges() []AddressChange {
+	sf.pendingMu.Lock()
+	defer sf.pendingMu.Unlock()
+	changes := make([]AddressChange, 0, len(sf.pending))
+	for _, change := range sf.pending {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return bytes.Compare(iotxaddress.HashPubKey(changes[i].Address.PublicKey),
+			iotxaddress.HashPubKey(changes[j].Address.PublicKey)) < 0
+	})
+	return changes
+}
+
+// touch records a change to the account, merging it with the earlier pending change
+func (sf *stateFactory) touch(addr *iotxaddress.Address, kind ChangeKind) {
+	sf.pendingMu.Lock()
+	defer sf.pendingMu.Unlock()
+	key := string(iotxaddress.HashPubKey(addr.PublicKey))
+	prev, ok := sf.pending[key]
+	switch {
+	case !ok:
+	case prev.Kind == ChangeCreated && kind == ChangeDeleted:
+		delete(sf.pending, key)
+		return
+	case prev.Kind == ChangeCreated:
+		kind = ChangeCreated
+	case prev.Kind == ChangeDeleted && kind == ChangeCreated:
+		kind = ChangeUpdated
+	}
+	sf.pending[key] = AddressChange{Address: addr, Kind: kind}
+}
+
+// resetPending forgets the pending changes, once they are committed
+func (sf *stateFactory) resetPending() {
+	sf.pendingMu.Lock()
+	defer sf.pendingMu.Unlock()
+	sf.pending = make(map[string]AddressChange)
+}
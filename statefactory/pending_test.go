@@ -0,0 +1,65 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestPendingChanges(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 5, 10)
+	assert.Equal(t, 5, len(sf.(Monitor).PendingChanges()))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sf.(Monitor).PendingChanges()))
+
+	assert.Nil(t, sf.AddBalance(addrs[1], big.NewInt(5)))
+	assert.Nil(t, sf.SetNonce(addrs[3], 2))
+	assert.Nil(t, sf.SetNonce(addrs[3], 3))
+	assert.Nil(t, sf.DeleteState(addrs[4]))
+	// no-op update is not a change
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(0)))
+	created, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(created, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, sf.AddBalance(created, big.NewInt(1)))
+	// created and deleted again is no change
+	transient, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(transient, 1)
+	assert.Nil(t, err)
+	assert.Nil(t, sf.DeleteState(transient))
+
+	expected := map[string]ChangeKind{
+		addrs[1].RawAddress: ChangeUpdated,
+		addrs[3].RawAddress: ChangeUpdated,
+		addrs[4].RawAddress: ChangeDeleted,
+		created.RawAddress:  ChangeCreated,
+	}
+	changes := sf.(Monitor).PendingChanges()
+	assert.Equal(t, len(expected), len(changes))
+	for _, change := range changes {
+		kind, ok := expected[change.Address.RawAddress]
+		assert.True(t, ok)
+		assert.Equal(t, kind, change.Kind)
+	}
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(sf.(Monitor).PendingChanges()))
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+type (
+	// MutationPolicy is consulted before a balance mutation is applied, a non-nil error vetoes the mutation and is
+	// returned to the caller as is
+	// It lets operators layer freeze lists, velocity limits or audit logging on top of the state factory.
+	MutationPolicy interface {
+		// AllowTransfer is consulted before the amount moves from the sender to the recipient
+		AllowTransfer(sender, recipient *iotxaddress.Address, amount *big.Int) error
+		// AllowBalanceChange is consulted before the delta is added to the balance of the account
+		AllowBalanceChange(addr *iotxaddress.Address, delta *big.Int) error
+	}
+
+	// permissivePolicy allows every mutation
+	permissivePolicy struct{}
+)
+
+func (permissivePolicy) AllowTransfer(*iotxaddress.Address, *iotxaddress.Address, *big.Int) error {
+	return nil
+}
+
+func (permissivePolicy) AllowBalanceChange(*iotxaddress.Address, *big.Int) error {
+	return nil
+}
+
+// PolicyOption sets the policy consulted before balance mutations, the default allows everything
+func PolicyOption(policy MutationPolicy) Option {
+	return func(sf *stateFactory) {
+		sf.policy = policy
+	}
+}
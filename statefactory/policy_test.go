@@ -0,0 +1,66 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+var errOverLimit = errors.New("over limit")
+
+// limitPolicy vetoes transfers and balance changes over the limit
+type limitPolicy struct {
+	limit *big.Int
+}
+
+func (p limitPolicy) AllowTransfer(_, _ *iotxaddress.Address, amount *big.Int) error {
+	if amount.Cmp(p.limit) > 0 {
+		return errOverLimit
+	}
+	return nil
+}
+
+func (p limitPolicy) AllowBalanceChange(_ *iotxaddress.Address, delta *big.Int) error {
+	if new(big.Int).Abs(delta).Cmp(p.limit) > 0 {
+		return errOverLimit
+	}
+	return nil
+}
+
+func TestMutationPolicy(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, PolicyOption(limitPolicy{limit: big.NewInt(50)}))
+
+	addrs := createAccounts(t, sf, 2, 100)
+	root := sf.RootHash()
+	assert.Equal(t, errOverLimit, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(51), 0))
+	assert.Equal(t, errOverLimit, sf.AddBalance(addrs[0], big.NewInt(51)))
+	assert.Equal(t, errOverLimit, sf.ApplyBatch([]StateChange{{Address: addrs[0], Delta: big.NewInt(-51)}}))
+	assert.Equal(t, root, sf.RootHash())
+
+	// at the threshold is allowed
+	assert.Nil(t, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(50), 0))
+	balance, err := sf.Balance(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(150)))
+
+	// the default policy allows everything
+	tr, err = trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf = NewStateFactory(tr)
+	addrs = createAccounts(t, sf, 2, 100)
+	assert.Nil(t, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(100), 0))
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// SelfStakeWeightOption sets how much a candidate's locked self-stake counts toward its effective power, default is 0
+// The effective power is the voting weight received plus the self-stake times the weight, rounded down, e.g. a weight
+// of 3/2 counts a self-stake of 100 as 150 votes. A negative weight is taken as 0, the self-stake never lowers the
+// power.
+func SelfStakeWeightOption(weight *big.Rat) Option {
+	return func(sf *stateFactory) {
+		sf.stakeWeight = new(big.Rat)
+		if weight.Sign() > 0 {
+			sf.stakeWeight.Set(weight)
+		}
+	}
+}
+
+// EffectivePower returns the power the candidate is ranked by, combining the votes it received with its self-stake
+func (sf *stateFactory) EffectivePower(addr *iotxaddress.Address) (*big.Int, error) {
+	state, err := sf.readState(addr)
+	if err != nil {
+		return nil, err
+	}
+	if !state.IsCandidate {
+		return nil, ErrNotCandidate
+	}
+	return sf.effectivePower(state), nil
+}
+
+// effectivePower returns the voting weight of the state plus its weighted self-stake, a fresh value
+func (sf *stateFactory) effectivePower(state *State) *big.Int {
+	power := big.NewInt(0)
+	if state.VotingWeight != nil {
+		power.Set(state.VotingWeight)
+	}
+	if sf.stakeWeight == nil || sf.stakeWeight.Sign() == 0 {
+		return power
+	}
+	stake := new(big.Int).Mul(state.lockedBalance(), sf.stakeWeight.Num())
+	return power.Add(power, stake.Div(stake, sf.stakeWeight.Denom()))
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestEffectivePower(t *testing.T) {
+	for _, weighted := range []bool{false, true} {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		var opts []Option
+		if weighted {
+			opts = append(opts, SelfStakeWeightOption(big.NewRat(3, 2)))
+		}
+		sf := NewStateFactory(tr, opts...)
+		addrs := createAccounts(t, sf, 3, 1000)
+		staked, voted, voter := addrs[0], addrs[1], addrs[2]
+		assert.Nil(t, sf.Lock(staked, big.NewInt(101)))
+		assert.Nil(t, sf.RegisterCandidate(staked, 0))
+		assert.Nil(t, sf.RegisterCandidate(voted, 0))
+		assert.Nil(t, sf.Vote(voter, staked, big.NewInt(50)))
+		assert.Nil(t, sf.Vote(voter, voted, big.NewInt(180)))
+
+		_, err = sf.EffectivePower(voter)
+		assert.Equal(t, ErrNotCandidate, err)
+		power, err := sf.EffectivePower(voted)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, power.Cmp(big.NewInt(180)))
+		power, err = sf.EffectivePower(staked)
+		assert.Nil(t, err)
+		ranked, err := sf.RankedCandidates()
+		assert.Nil(t, err)
+		assert.Len(t, ranked, 2)
+		if !weighted {
+			// the power is the votes received, the self-stake does not count
+			assert.Equal(t, 0, power.Cmp(big.NewInt(50)))
+			assert.Equal(t, voted.RawAddress, ranked[0].Address.RawAddress)
+			continue
+		}
+		// 50 votes plus 101 * 3/2 rounded down, which outranks 180 votes
+		assert.Equal(t, 0, power.Cmp(big.NewInt(201)))
+		assert.Equal(t, staked.RawAddress, ranked[0].Address.RawAddress)
+		assert.Equal(t, 0, ranked[0].Power.Cmp(big.NewInt(201)))
+		assert.Equal(t, 0, ranked[0].VotingWeight.Cmp(big.NewInt(50)))
+	}
+}
+
+func TestNegativeStakeWeight(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, SelfStakeWeightOption(big.NewRat(-3, 2)))
+	addrs := createAccounts(t, sf, 1, 1000)
+	assert.Nil(t, sf.Lock(addrs[0], big.NewInt(101)))
+	assert.Nil(t, sf.RegisterCandidate(addrs[0], 0))
+
+	// a negative weight counts as 0, the self-stake does not lower the power below the votes received
+	power, err := sf.EffectivePower(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, power.Sign())
+}
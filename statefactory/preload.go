@@ -0,0 +1,51 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// Preload reads the accounts a block is about to touch in one pass, so the reads while applying it hit the trie cache
+// The accounts are read once each in key order, accounts that do not exist yet are skipped. The trie cache is bounded,
+// so preloading more accounts than it holds evicts the earliest ones again.
+func (sf *stateFactory) Preload(addrs []*iotxaddress.Address) error {
+	keys := make([][]byte, 0, len(addrs))
+	for _, addr := range addrs {
+		keys = append(keys, iotxaddress.HashPubKey(addr.PublicKey))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	for i, key := range keys {
+		if i > 0 && bytes.Equal(key, keys[i-1]) {
+			continue
+		}
+		if _, err := sf.trie.Get(key); err != nil && errors.Cause(err) != trie.ErrNotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+// InvalidateCache drops the trie nodes on the path of the account from the trie cache, so they are read from DB again
+// Nodes are cached by hash and never go stale on their own, this is for operators suspecting a cached node differs from
+// DB, e.g. after repairing DB behind a running factory. It is a no-op if the trie has no cache.
+func (sf *stateFactory) InvalidateCache(addr *iotxaddress.Address) error {
+	if c, ok := sf.trie.(interface {
+		Evict([]byte) error
+	}); ok {
+		return c.Evict(iotxaddress.HashPubKey(addr.PublicKey))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestPreload(t *testing.T) {
+	dao := &getCountingKVStore{KVStore: db.NewMemKVStore()}
+	tr, err := trie.NewTrieSharedDB(dao)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	// more accounts than the trie cache holds nodes for, so the first ones are evicted
+	addrs := createAccounts(t, sf, 3000, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	missing, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	block := append([]*iotxaddress.Address{missing, addrs[1]}, addrs[:20]...)
+	assert.Nil(t, sf.Preload(block))
+
+	// the reads while applying the block are served from the cache
+	dao.gets = 0
+	for _, addr := range addrs[:20] {
+		_, _, err := sf.NonceAndBalance(addr)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 0, dao.gets)
+}
+
+func TestInvalidateCache(t *testing.T) {
+	dao := &corruptingKVStore{KVStore: db.NewMemKVStore()}
+	tr, err := trie.NewTrieSharedDB(dao)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// the account's nodes are read from DB again after invalidating them, while DB returns garbage the garbage is
+	// cached
+	assert.Nil(t, sf.InvalidateCache(addrs[0]))
+	dao.corrupt = true
+	_, err = sf.Balance(addrs[0])
+	assert.NotNil(t, err)
+
+	// repairing DB alone does not help, the cache still holds the garbage
+	dao.corrupt = false
+	_, err = sf.Balance(addrs[0])
+	assert.NotNil(t, err)
+	assert.Nil(t, sf.InvalidateCache(addrs[0]))
+	balance, err := sf.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), balance.Uint64())
+
+	// an account that does not exist has nothing to invalidate
+	missing, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	assert.Nil(t, sf.InvalidateCache(missing))
+}
+
+func BenchmarkBlockReadsCold(b *testing.B) {
+	benchmarkBlockReads(b, false)
+}
+
+func BenchmarkBlockReadsPreloaded(b *testing.B) {
+	benchmarkBlockReads(b, true)
+}
+
+// benchmarkBlockReads reads the nonce and balance of a block's senders and the balance of its recipients, each block
+// touching accounts the trie cache no longer holds
+func benchmarkBlockReads(b *testing.B, preload bool) {
+	os.Remove(testTriePath)
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(b, sf, 5000, 10)
+	if _, err := sf.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a block of 100 transfers between distinct accounts
+		start := (i * 200) % (len(addrs) - 200)
+		block := addrs[start : start+200]
+		if preload {
+			if err := sf.Preload(block); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for j := 0; j < len(block); j += 2 {
+			if _, _, err := sf.NonceAndBalance(block[j]); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := sf.Balance(block[j+1]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// getCountingKVStore counts the reads reaching the KV store
+type getCountingKVStore struct {
+	db.KVStore
+	gets int
+}
+
+func (c *getCountingKVStore) Get(namespace string, key []byte) ([]byte, error) {
+	c.gets++
+	return c.KVStore.Get(namespace, key)
+}
+
+// corruptingKVStore returns garbage for every read while corrupt is set
+type corruptingKVStore struct {
+	db.KVStore
+	corrupt bool
+}
+
+func (c *corruptingKVStore) Get(namespace string, key []byte) ([]byte, error) {
+	if c.corrupt {
+		return []byte{0xff}, nil
+	}
+	return c.KVStore.Get(namespace, key)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+var (
+	// ErrInvalidBundle is the error that a proof bundle does not prove its account state under the trusted root
+	ErrInvalidBundle = errors.New("invalid proof bundle")
+	// ErrNoCommittedHeight is the error that no height has been committed with CommitWithHeight to bundle a proof at
+	ErrNoCommittedHeight = errors.New("no committed height")
+)
+
+// ProofBundle is what a third party needs to check an account's state as of a committed block
+type ProofBundle struct {
+	// Address is the public part of the account's address
+	Address *iotxaddress.Address
+	// Leaf is the account's State as stored in the trie
+	Leaf []byte
+	// Proof is the trie nodes from the root down to the leaf, see trie.VerifyProof
+	Proof [][]byte
+	// Root is the state root the proof leads from
+	Root common.Hash32B
+	// Height is the height of the block the root was committed at
+	Height uint64
+}
+
+// ProofBundle returns the proof of the account's state as of the last CommitWithHeight
+// Changes made since are not included. A factory that never committed a height returns ErrNoCommittedHeight, since a
+// bundle without one cannot be matched to a block.
+func (sf *stateFactory) ProofBundle(addr *iotxaddress.Address) (ProofBundle, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return ProofBundle{}, ErrClosed
+	}
+	if !sf.heightKnown {
+		return ProofBundle{}, ErrNoCommittedHeight
+	}
+	p, ok := sf.trie.(interface {
+		Proof([]byte) ([][]byte, error)
+		CommittedRootHash() common.Hash32B
+	})
+	if !ok {
+		return ProofBundle{}, errors.New("the trie does not support proofs")
+	}
+	key := AccountKeyOf(addr)
+	proof, err := p.Proof(key.Bytes())
+	if errors.Cause(err) == trie.ErrNotExist {
+		return ProofBundle{}, ErrAccountNotExist
+	}
+	if err != nil {
+		return ProofBundle{}, err
+	}
+	root := p.CommittedRootHash()
+	leaf, err := trie.VerifyProof(root, key.Bytes(), proof)
+	if err != nil {
+		return ProofBundle{}, err
+	}
+	// the address is the one stored with the account, which the verifier checks the leaf against
+	state, err := bytesToState(leaf, sf.maxLeafSize)
+	if err != nil {
+		return ProofBundle{}, sf.checkLeaf(key.Bytes(), err)
+	}
+	if state.Address != nil {
+		addr = state.Address
+	}
+	return ProofBundle{
+		Address: &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress},
+		Leaf:    leaf,
+		Proof:   proof,
+		Root:    root,
+		Height:  sf.lastHeight,
+	}, nil
+}
+
+// VerifyBundle checks the bundle against the state root trusted for the height, e.g. from a block header, and returns
+// the State it proves
+// The bundle must carry that root and height, its proof must lead from the root to its leaf under the account key of
+// its address, and the leaf must decode to a State of that same address.
+func VerifyBundle(bundle ProofBundle, root common.Hash32B, height uint64) (*State, error) {
+	if bundle.Root != root {
+		return nil, errors.Wrapf(ErrInvalidBundle, "root %x, trusted %x", bundle.Root, root)
+	}
+	if bundle.Height != height {
+		return nil, errors.Wrapf(ErrInvalidBundle, "height %d, trusted %d", bundle.Height, height)
+	}
+	if bundle.Address == nil {
+		return nil, errors.Wrap(ErrInvalidBundle, "no address")
+	}
+	leaf, err := trie.VerifyProof(root, AccountKeyOf(bundle.Address).Bytes(), bundle.Proof)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidBundle, "%v", err)
+	}
+	return provenState(bundle.Address, bundle.Leaf, leaf, height)
+}
+
+// provenState decodes the leaf proven for the address, which must be the leaf claimed and hold a State of the address
+func provenState(addr *iotxaddress.Address, claimed, leaf []byte, height uint64) (*State, error) {
+	if !bytes.Equal(leaf, claimed) {
+		return nil, errors.Wrap(ErrInvalidBundle, "leaf is not the proven one")
+	}
+	state, err := bytesToState(leaf, defaultMaxLeafSize)
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidBundle, "%v", err)
+	}
+	if state.Address == nil || !bytes.Equal(state.Address.PublicKey, addr.PublicKey) ||
+		state.Address.RawAddress != addr.RawAddress {
+		return nil, errors.Wrap(ErrInvalidBundle, "state of another address")
+	}
+	state.height = height
+	return state, nil
+}
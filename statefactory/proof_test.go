@@ -0,0 +1,82 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestProofBundle(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 4, 100)
+	_, err = sf.(Prover).ProofBundle(addrs[0])
+	assert.Equal(t, ErrNoCommittedHeight, err)
+	_, err = sf.CommitWithHeight(7)
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	// changes since the commit are not in the bundle
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(5)))
+	bundle, err := sf.(Prover).ProofBundle(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, root, bundle.Root)
+	assert.Equal(t, uint64(7), bundle.Height)
+	assert.Nil(t, bundle.Address.PrivateKey)
+	state, err := VerifyBundle(bundle, root, 7)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(100)))
+	assert.Equal(t, addrs[0].RawAddress, state.Address.RawAddress)
+
+	missing, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.(Prover).ProofBundle(missing)
+	assert.Equal(t, ErrAccountNotExist, err)
+
+	// altering any field of the bundle fails the verification
+	other, err := sf.(Prover).ProofBundle(addrs[1])
+	assert.Nil(t, err)
+	alter := map[string]func(b *ProofBundle){
+		"address": func(b *ProofBundle) { b.Address = other.Address },
+		"raw address": func(b *ProofBundle) {
+			b.Address = &iotxaddress.Address{PublicKey: b.Address.PublicKey, RawAddress: other.Address.RawAddress}
+		},
+		"leaf":   func(b *ProofBundle) { b.Leaf = other.Leaf },
+		"proof":  func(b *ProofBundle) { b.Proof = other.Proof },
+		"root":   func(b *ProofBundle) { b.Root[0] ^= 1 },
+		"height": func(b *ProofBundle) { b.Height++ },
+	}
+	for i := range bundle.Proof {
+		i := i
+		alter["proof node"] = func(b *ProofBundle) {
+			b.Proof = append([][]byte{}, b.Proof...)
+			b.Proof[i] = append([]byte{}, b.Proof[i]...)
+			b.Proof[i][len(b.Proof[i])-2] ^= 1
+		}
+		alter["truncated proof"] = func(b *ProofBundle) { b.Proof = b.Proof[:i] }
+		for name, f := range alter {
+			altered := bundle
+			f(&altered)
+			_, err := VerifyBundle(altered, root, 7)
+			assert.Equal(t, ErrInvalidBundle, errors.Cause(err), name)
+		}
+	}
+	// nor is the bundle valid against another root or height
+	_, err = VerifyBundle(bundle, sf.RootHash(), 7)
+	assert.Equal(t, ErrInvalidBundle, errors.Cause(err))
+	_, err = VerifyBundle(bundle, root, 8)
+	assert.Equal(t, ErrInvalidBundle, errors.Cause(err))
+}
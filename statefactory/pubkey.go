@@ -0,0 +1,40 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/crypto"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInvalidPublicKey is the error that an address's public key is not in the format of the crypto package
+var ErrInvalidPublicKey = errors.New("invalid public key")
+
+// VerifyPublicKeyOption makes mutations check the format of the public keys they derive account keys from
+// Any bytes hash to a valid-looking key, so without the check funds sent to a malformed key, e.g. a truncated one,
+// land in an account no private key controls. Creations, transfers and single-account updates then fail with
+// ErrInvalidPublicKey first. Accounts already stored under malformed keys stay readable.
+func VerifyPublicKeyOption() Option {
+	return func(sf *stateFactory) {
+		sf.verifyKeys = true
+	}
+}
+
+// checkPublicKeys returns ErrInvalidPublicKey if key verification is on and a public key of the addresses is malformed
+func (sf *stateFactory) checkPublicKeys(addrs ...*iotxaddress.Address) error {
+	if !sf.verifyKeys {
+		return nil
+	}
+	for _, addr := range addrs {
+		if !crypto.IsValidPublicKey(addr.PublicKey) {
+			return errors.Wrapf(ErrInvalidPublicKey, "%d bytes", len(addr.PublicKey))
+		}
+	}
+	return nil
+}
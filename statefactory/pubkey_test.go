@@ -0,0 +1,48 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestVerifyPublicKey(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, VerifyPublicKeyOption())
+	addr := createAccounts(t, sf, 1, 100)[0]
+	truncated := &iotxaddress.Address{PublicKey: addr.PublicKey[:len(addr.PublicKey)-1]}
+
+	assert.Equal(t, ErrInvalidPublicKey, errors.Cause(sf.AddBalance(truncated, big.NewInt(1))))
+	_, err = sf.CreateState(truncated, 0)
+	assert.Equal(t, ErrInvalidPublicKey, errors.Cause(err))
+	assert.Equal(t, ErrInvalidPublicKey, errors.Cause(sf.ApplyTransferTx(addr, truncated, big.NewInt(1), 0)))
+	assert.Equal(t, ErrInvalidPublicKey, errors.Cause(sf.RotateKey(addr, truncated)))
+
+	// nothing was credited or created under the truncated key
+	_, err = sf.GetState(truncated)
+	assert.Equal(t, ErrAccountNotExist, err)
+	balance, err := sf.Balance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), count)
+
+	// without the option any bytes key an account
+	other := NewStateFactory(tr)
+	_, err = other.CreateState(truncated, 0)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/rand"
+
+	"github.com/iotexproject/iotex-core/crypto"
+)
+
+// RandSource returns a pseudo-random generator seeded from the current root hash, e.g. to shuffle a committee
+// Every node at the same root draws the same sequence, so it must only be used at a root all nodes agree on, such as
+// the root committed for a block, never while a block's changes are half applied. The randomness is public and can
+// be steered by whoever chooses the transactions that lead to the root, it is not fit for secrets. The generator is
+// not safe for concurrent use. The virtual state factory is no RandSourcer, its RootHash does not cover its changes.
+func (sf *stateFactory) RandSource() *rand.Rand {
+	root := sf.RootHash()
+	return rand.New(crypto.NewHashSource(root[:]))
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestRandSource(t *testing.T) {
+	tr1, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	tr2, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf1, sf2 := NewStateFactory(tr1), NewStateFactory(tr2)
+	addrs := createAccounts(t, sf1, 3, 100)
+	for _, addr := range addrs {
+		_, err := sf2.CreateState(addr, 100)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, sf1.RootHash(), sf2.RootHash())
+
+	// factories at the same root draw the same sequence
+	r1, r2 := sf1.(RandSourcer).RandSource(), sf2.(RandSourcer).RandSource()
+	seq := make([]int64, 10)
+	for i := range seq {
+		seq[i] = r1.Int63()
+		assert.Equal(t, seq[i], r2.Int63())
+	}
+	assert.Equal(t, sf1.(RandSourcer).RandSource().Perm(10), sf2.(RandSourcer).RandSource().Perm(10))
+
+	// and a different one once the root changes
+	assert.Nil(t, sf1.AddBalance(addrs[0], big.NewInt(1)))
+	r1 = sf1.(RandSourcer).RandSource()
+	same := true
+	for i := range seq {
+		same = same && seq[i] == r1.Int63()
+	}
+	assert.False(t, same)
+
+	// the virtual factory has no root to draw from
+	_, ok := NewVirtualStateFactory(tr1).(RandSourcer)
+	assert.False(t, ok)
+}
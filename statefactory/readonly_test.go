@@ -0,0 +1,80 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// readOnlyKVStore refuses writes once it is read-only, as a store reopened read-only does
+type readOnlyKVStore struct {
+	db.KVStore
+	readOnly bool
+}
+
+var errWriteAttempted = errors.New("write to a read-only store")
+
+func (s *readOnlyKVStore) ReadOnly() bool { return s.readOnly }
+
+func (s *readOnlyKVStore) Put(namespace string, key, value []byte) error {
+	if s.readOnly {
+		return errWriteAttempted
+	}
+	return s.KVStore.Put(namespace, key, value)
+}
+
+func (s *readOnlyKVStore) BatchPut(namespace string, keys, values [][]byte) error {
+	if s.readOnly {
+		return errWriteAttempted
+	}
+	return s.KVStore.BatchPut(namespace, keys, values)
+}
+
+func (s *readOnlyKVStore) PutIfNotExists(namespace string, key, value []byte) error {
+	if s.readOnly {
+		return errWriteAttempted
+	}
+	return s.KVStore.PutIfNotExists(namespace, key, value)
+}
+
+func (s *readOnlyKVStore) Delete(namespace string, key []byte) error {
+	if s.readOnly {
+		return errWriteAttempted
+	}
+	return s.KVStore.Delete(namespace, key)
+}
+
+func TestReadOnlyStore(t *testing.T) {
+	kv := &readOnlyKVStore{KVStore: db.NewMemKVStore()}
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 100)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// a replica on the store turned read-only rejects mutations up front, reads work
+	kv.readOnly = true
+	replica := NewStateFactory(tr)
+	assert.Equal(t, ErrReadOnlyStore, replica.AddBalance(addrs[0], big.NewInt(1)))
+	assert.Equal(t, ErrReadOnlyStore, replica.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(1), 0))
+	_, err = replica.Commit()
+	assert.Equal(t, ErrReadOnlyStore, err)
+	balance, err := replica.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+	nonce, err := replica.Nonce(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), nonce)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// TransferReceipt is the effect of a transfer, enough for a wallet or an explorer to render it without reading back
+type TransferReceipt struct {
+	Sender           *iotxaddress.Address
+	Recipient        *iotxaddress.Address
+	Amount           *big.Int
+	SenderBalance    *big.Int       // sender's balance after the transfer
+	RecipientBalance *big.Int       // recipient's balance after the transfer
+	Root             common.Hash32B // root hash right after the transfer
+}
+
+// ApplyTransferTxWithReceipt applies the transfer as ApplyTransferTx does and returns its receipt
+// The balances are those the transfer wrote, taken while both accounts are still locked. The root hash is taken at the
+// same time, it covers concurrent changes to other accounts applied before it too.
+func (sf *stateFactory) ApplyTransferTxWithReceipt(sender, recipient *iotxaddress.Address, amount *big.Int,
+	expectedNonce uint64) (*TransferReceipt, error) {
+	receipt := &TransferReceipt{}
+	if err := sf.applyTransferTx(sender, recipient, amount, expectedNonce, receipt); err != nil {
+		return nil, err
+	}
+	receipt.Sender, receipt.Recipient, receipt.Amount = sender, recipient, new(big.Int).Set(amount)
+	return receipt, nil
+}
+
+// fillReceipt records the balances a transfer wrote and the root hash after it in the receipt, if it is not nil
+func (sf *stateFactory) fillReceipt(receipt *TransferReceipt, from, to *State) {
+	if receipt == nil {
+		return
+	}
+	receipt.SenderBalance = new(big.Int).Set(from.Balance)
+	receipt.RecipientBalance = new(big.Int).Set(to.Balance)
+	receipt.Root = sf.trie.RootHash()
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestApplyTransferTxWithReceipt(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 100)
+	sender, recipient := addrs[0], addrs[1]
+
+	receipt, err := sf.ApplyTransferTxWithReceipt(sender, recipient, big.NewInt(30), 0)
+	assert.Nil(t, err)
+	assert.Equal(t, sender, receipt.Sender)
+	assert.Equal(t, recipient, receipt.Recipient)
+	assert.Equal(t, 0, receipt.Amount.Cmp(big.NewInt(30)))
+	assert.Equal(t, sf.RootHash(), receipt.Root)
+	state, err := sf.GetState(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, receipt.SenderBalance.Cmp(state.Balance))
+	assert.Equal(t, 0, receipt.SenderBalance.Cmp(big.NewInt(70)))
+	state, err = sf.GetState(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, receipt.RecipientBalance.Cmp(state.Balance))
+	assert.Equal(t, 0, receipt.RecipientBalance.Cmp(big.NewInt(130)))
+
+	// a new recipient and a transfer to self
+	newcomer, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	receipt, err = sf.ApplyTransferTxWithReceipt(sender, newcomer, big.NewInt(20), 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, receipt.RecipientBalance.Cmp(big.NewInt(20)))
+	receipt, err = sf.ApplyTransferTxWithReceipt(sender, sender, big.NewInt(5), 2)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, receipt.SenderBalance.Cmp(big.NewInt(50)))
+	assert.Equal(t, 0, receipt.RecipientBalance.Cmp(big.NewInt(50)))
+
+	// a failed transfer has no receipt
+	receipt, err = sf.ApplyTransferTxWithReceipt(sender, recipient, big.NewInt(51), 3)
+	assert.NotNil(t, err)
+	assert.Nil(t, receipt)
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+)
+
+// StakeInconsistencyKind is the kind of discrepancy ReconcileStakes found
+type StakeInconsistencyKind int
+
+const (
+	// StakeOverCommitted means a voter has voted more than its stake, Recorded is its VotedWeight and Expected its stake
+	StakeOverCommitted StakeInconsistencyKind = iota
+	// StakeVotesMismatch means a voter's VotedWeight, Recorded, differs from the votes the candidates hold for it,
+	// Expected
+	StakeVotesMismatch
+	// StakeUnknownVoter means a candidate holds Recorded votes from Voter, which is not in the voter list
+	StakeUnknownVoter
+	// StakeWeightMismatch means a candidate's VotingWeight, Recorded, differs from the sum of its Voters, Expected
+	StakeWeightMismatch
+)
+
+// StakeInconsistency is a discrepancy between the staking records of accounts
+type StakeInconsistency struct {
+	Kind     StakeInconsistencyKind
+	Account  string         // raw address of the voter or candidate the discrepancy is reported for
+	Voter    common.Hash32B // key of the voter in the candidate's Voters, only set for StakeUnknownVoter
+	Recorded *big.Int
+	Expected *big.Int
+}
+
+// ReconcileStakes cross-checks the votes recorded by the voters against those recorded by the candidates
+// Every voter's VotedWeight must not exceed its stake, i.e. its locked and spendable balance that Vote checks against,
+// and must equal the votes the candidates hold for it, and every candidate's VotingWeight must equal the sum of its
+// Voters. All the discrepancies are returned, voters first, in the order of the voter and candidate lists. It is an
+// audit tool for operators and repairs nothing, see RepairVotingWeights. The trie cannot be iterated, so only votes
+// held by registered candidates are counted, votes still held by an unregistered candidate show as a mismatch. With
+// QueryLimitsOption the discrepancies found before a limit was hit are returned with ErrResourceLimitExceeded, the
+// voters are only checked once every candidate has been read.
+func (sf *stateFactory) ReconcileStakes() ([]StakeInconsistency, error) {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return nil, ErrClosed
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := sf.candidates()
+	if err != nil {
+		return nil, err
+	}
+
+	// the votes held for each voter, summed over the candidates
+	held := make(map[common.Hash32B]*big.Int, len(voters))
+	for _, voter := range voters {
+		held[voterKey(voter)] = big.NewInt(0)
+	}
+	budget := sf.newQueryBudget()
+	var byVoter, byCandidate []StakeInconsistency
+	for _, candidate := range candidates {
+		state, err := sf.scanState(budget, candidate)
+		if errors.Cause(err) == ErrResourceLimitExceeded {
+			return byCandidate, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := big.NewInt(0)
+		var strays []StakeInconsistency
+		for key, v := range state.Voters {
+			sum.Add(sum, v)
+			if total, ok := held[key]; ok {
+				total.Add(total, v)
+				continue
+			}
+			strays = append(strays, StakeInconsistency{Kind: StakeUnknownVoter, Account: candidate.RawAddress,
+				Voter: key, Recorded: new(big.Int).Set(v), Expected: big.NewInt(0)})
+		}
+		sort.Slice(strays, func(i, j int) bool { return bytes.Compare(strays[i].Voter[:], strays[j].Voter[:]) < 0 })
+		byCandidate = append(byCandidate, strays...)
+		weight := state.VotingWeight
+		if weight == nil {
+			weight = big.NewInt(0)
+		}
+		if weight.Cmp(sum) != 0 {
+			byCandidate = append(byCandidate, StakeInconsistency{Kind: StakeWeightMismatch, Account: candidate.RawAddress,
+				Recorded: new(big.Int).Set(weight), Expected: sum})
+		}
+	}
+
+	for _, voter := range voters {
+		state, err := sf.scanState(budget, voter)
+		if errors.Cause(err) == ErrResourceLimitExceeded {
+			return append(byVoter, byCandidate...), err
+		}
+		if err != nil {
+			return nil, err
+		}
+		voted := state.votedWeight()
+		if stake := state.stake(); voted.Cmp(stake) > 0 {
+			byVoter = append(byVoter, StakeInconsistency{Kind: StakeOverCommitted, Account: voter.RawAddress,
+				Recorded: new(big.Int).Set(voted), Expected: stake})
+		}
+		if total := held[voterKey(voter)]; voted.Cmp(total) != 0 {
+			byVoter = append(byVoter, StakeInconsistency{Kind: StakeVotesMismatch, Account: voter.RawAddress,
+				Recorded: new(big.Int).Set(voted), Expected: total})
+		}
+	}
+	return append(byVoter, byCandidate...), nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestReconcileStakes(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+	addrs := createAccounts(t, sf, 3, 100)
+	voter, other, candidate := addrs[0], addrs[1], addrs[2]
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+	assert.Nil(t, sf.Vote(voter, candidate, big.NewInt(60)))
+	assert.Nil(t, sf.Vote(other, candidate, big.NewInt(10)))
+	found, err := sf.ReconcileStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(found))
+
+	// a voter whose votes outgrow its stake
+	assert.Nil(t, sfi.updateState(voter, func(state *State) error {
+		state.VotedWeight = big.NewInt(150)
+		return nil
+	}))
+	// and a candidate holding votes no voter recorded
+	assert.Nil(t, sfi.updateState(candidate, func(state *State) error {
+		state.Voters[voterKey(candidate)] = big.NewInt(5)
+		return nil
+	}))
+	found, err = sf.ReconcileStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, []StakeInconsistency{
+		{Kind: StakeOverCommitted, Account: voter.RawAddress, Recorded: big.NewInt(150), Expected: big.NewInt(100)},
+		{Kind: StakeVotesMismatch, Account: voter.RawAddress, Recorded: big.NewInt(150), Expected: big.NewInt(60)},
+		{Kind: StakeUnknownVoter, Account: candidate.RawAddress, Voter: voterKey(candidate), Recorded: big.NewInt(5),
+			Expected: big.NewInt(0)},
+		{Kind: StakeWeightMismatch, Account: candidate.RawAddress, Recorded: big.NewInt(70), Expected: big.NewInt(75)},
+	}, found)
+}
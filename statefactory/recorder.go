@@ -0,0 +1,540 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"encoding/gob"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	trx "github.com/iotexproject/iotex-core/blockchain/trx"
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrReplayMismatch is the error that a replayed operation did not end in the recorded outcome
+var ErrReplayMismatch = errors.New("replay mismatch")
+
+type (
+	// Recorder is a StateFactory that logs every mutating call to a writer, so the session can be replayed with Replay
+	// Each operation is written with its arguments, whether it failed and the root hash after it, as a gob stream.
+	// Addresses are logged without their private key. Calls should be made from one goroutine, otherwise the logged
+	// order may differ from the order the calls took effect. A Recorder is a Pauser and an Approver, whose calls do
+	// nothing or fail with ErrNotSupported if the factory it records is not.
+	Recorder struct {
+		StateFactory
+		mu  sync.Mutex
+		enc *gob.Encoder
+		err error
+	}
+
+	// operation is a recorded mutating call, only the fields the method takes are set
+	operation struct {
+		Method  string
+		Addrs   []*iotxaddress.Address
+		Amount  *big.Int
+		Fee     *big.Int
+		Ratio   *big.Rat
+		Uint    uint64
+		Data    []byte
+		Name    string
+		Txs     []*trx.Tx
+		Changes []StateChange
+		Votes   []VoteChange
+		Vesting []VestingPoint
+		Init    *State
+		Amounts map[string]*big.Int
+		Genesis []GenesisAccount
+		Failed  bool
+		Root    common.Hash32B
+	}
+)
+
+// NewRecorder creates a Recorder logging the calls to the factory into the writer
+func NewRecorder(sf StateFactory, w io.Writer) *Recorder {
+	return &Recorder{StateFactory: sf, enc: gob.NewEncoder(w)}
+}
+
+// Err returns the first error writing the log, the calls themselves are not affected by it
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// InitGenesis records InitGenesis
+func (r *Recorder) InitGenesis(accounts []GenesisAccount) (common.Hash32B, error) {
+	root, err := r.StateFactory.InitGenesis(accounts)
+	recorded := make([]GenesisAccount, len(accounts))
+	for i, account := range accounts {
+		// as in AddStateWithInit the Address of Init is not logged
+		recorded[i] = GenesisAccount{Address: publicAddress(account.Address), Init: account.Init}
+		recorded[i].Init.Address = nil
+	}
+	return root, r.record(operation{Method: "InitGenesis", Genesis: recorded}, err)
+}
+
+// CreateState records CreateState
+func (r *Recorder) CreateState(addr *iotxaddress.Address, init uint64) (*State, error) {
+	state, err := r.StateFactory.CreateState(addr, init)
+	return state, r.record(operation{Method: "CreateState", Addrs: publicAddresses(addr), Uint: init}, err)
+}
+
+// AddStateWithInit records AddStateWithInit
+func (r *Recorder) AddStateWithInit(addr *iotxaddress.Address, init State) (*State, error) {
+	state, err := r.StateFactory.AddStateWithInit(addr, init)
+	// the Address of init is replaced anyway, it is not logged in case it holds a private key
+	recorded := init
+	recorded.Address = nil
+	return state, r.record(operation{Method: "AddStateWithInit", Addrs: publicAddresses(addr), Init: &recorded}, err)
+}
+
+// AddBalance records AddBalance
+func (r *Recorder) AddBalance(addr *iotxaddress.Address, amount *big.Int) error {
+	err := r.StateFactory.AddBalance(addr, amount)
+	return r.record(operation{Method: "AddBalance", Addrs: publicAddresses(addr), Amount: amount}, err)
+}
+
+// AddBalanceOf records AddBalanceOf
+func (r *Recorder) AddBalanceOf(addr *iotxaddress.Address, asset AssetID, amount *big.Int) error {
+	err := r.StateFactory.AddBalanceOf(addr, asset, amount)
+	return r.record(operation{
+		Method: "AddBalanceOf",
+		Addrs:  publicAddresses(addr),
+		Amount: amount,
+		Uint:   uint64(asset),
+	}, err)
+}
+
+// SubBalanceOf records SubBalanceOf
+func (r *Recorder) SubBalanceOf(addr *iotxaddress.Address, asset AssetID, amount *big.Int) error {
+	err := r.StateFactory.SubBalanceOf(addr, asset, amount)
+	return r.record(operation{
+		Method: "SubBalanceOf",
+		Addrs:  publicAddresses(addr),
+		Amount: amount,
+		Uint:   uint64(asset),
+	}, err)
+}
+
+// Burn records Burn
+func (r *Recorder) Burn(addr *iotxaddress.Address, amount *big.Int) error {
+	err := r.StateFactory.Burn(addr, amount)
+	return r.record(operation{Method: "Burn", Addrs: publicAddresses(addr), Amount: amount}, err)
+}
+
+// UpdateStatesWithTransfer records UpdateStatesWithTransfer
+func (r *Recorder) UpdateStatesWithTransfer(txs []*trx.Tx) error {
+	err := r.StateFactory.UpdateStatesWithTransfer(txs)
+	return r.record(operation{Method: "UpdateStatesWithTransfer", Txs: txs}, err)
+}
+
+// ApplyTransferTx records ApplyTransferTx
+func (r *Recorder) ApplyTransferTx(sender, recipient *iotxaddress.Address, amount *big.Int, nonce uint64) error {
+	err := r.StateFactory.ApplyTransferTx(sender, recipient, amount, nonce)
+	return r.record(operation{
+		Method: "ApplyTransferTx",
+		Addrs:  publicAddresses(sender, recipient),
+		Amount: amount,
+		Uint:   nonce,
+	}, err)
+}
+
+// ApplyTransferTxWithReceipt records ApplyTransferTxWithReceipt as ApplyTransferTx, which has the same effect
+func (r *Recorder) ApplyTransferTxWithReceipt(sender, recipient *iotxaddress.Address, amount *big.Int,
+	nonce uint64) (*TransferReceipt, error) {
+	receipt, err := r.StateFactory.ApplyTransferTxWithReceipt(sender, recipient, amount, nonce)
+	return receipt, r.record(operation{
+		Method: "ApplyTransferTx",
+		Addrs:  publicAddresses(sender, recipient),
+		Amount: amount,
+		Uint:   nonce,
+	}, err)
+}
+
+// AuthorizedTransfer records AuthorizedTransfer
+func (r *Recorder) AuthorizedTransfer(sender, recipient *iotxaddress.Address, amount *big.Int, nonce uint64,
+	signature []byte) error {
+	err := r.StateFactory.AuthorizedTransfer(sender, recipient, amount, nonce, signature)
+	return r.record(operation{
+		Method: "AuthorizedTransfer",
+		Addrs:  publicAddresses(sender, recipient),
+		Amount: amount,
+		Uint:   nonce,
+		Data:   signature,
+	}, err)
+}
+
+// ApplySponsoredTx records ApplySponsoredTx
+func (r *Recorder) ApplySponsoredTx(sender, sponsor, recipient *iotxaddress.Address, amount, fee *big.Int,
+	senderNonce uint64) error {
+	err := r.StateFactory.ApplySponsoredTx(sender, sponsor, recipient, amount, fee, senderNonce)
+	return r.record(operation{
+		Method: "ApplySponsoredTx",
+		Addrs:  publicAddresses(sender, sponsor, recipient),
+		Amount: amount,
+		Fee:    fee,
+		Uint:   senderNonce,
+	}, err)
+}
+
+// Approve records Approve, it fails with ErrNotSupported if the factory is not an Approver
+func (r *Recorder) Approve(owner, spender *iotxaddress.Address, amount *big.Int) error {
+	err := ErrNotSupported
+	if a, ok := r.StateFactory.(Approver); ok {
+		err = a.Approve(owner, spender, amount)
+	}
+	return r.record(operation{Method: "Approve", Addrs: publicAddresses(owner, spender), Amount: amount}, err)
+}
+
+// Allowance returns the allowance of the factory, it fails with ErrNotSupported if the factory is not an Approver
+func (r *Recorder) Allowance(owner, spender *iotxaddress.Address) (*big.Int, error) {
+	a, ok := r.StateFactory.(Approver)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return a.Allowance(owner, spender)
+}
+
+// TransferFrom records TransferFrom, it fails with ErrNotSupported if the factory is not an Approver
+func (r *Recorder) TransferFrom(spender, owner, recipient *iotxaddress.Address, amount *big.Int) error {
+	err := ErrNotSupported
+	if a, ok := r.StateFactory.(Approver); ok {
+		err = a.TransferFrom(spender, owner, recipient, amount)
+	}
+	return r.record(operation{
+		Method: "TransferFrom",
+		Addrs:  publicAddresses(spender, owner, recipient),
+		Amount: amount,
+	}, err)
+}
+
+// SetNonce records SetNonce
+func (r *Recorder) SetNonce(addr *iotxaddress.Address, nonce uint64) error {
+	err := r.StateFactory.SetNonce(addr, nonce)
+	return r.record(operation{Method: "SetNonce", Addrs: publicAddresses(addr), Uint: nonce}, err)
+}
+
+// Commit records Commit
+func (r *Recorder) Commit() (CommitStats, error) {
+	stats, err := r.StateFactory.Commit()
+	return stats, r.record(operation{Method: "Commit"}, err)
+}
+
+// CommitWithHeight records CommitWithHeight
+func (r *Recorder) CommitWithHeight(height uint64) (CommitStats, error) {
+	stats, err := r.StateFactory.CommitWithHeight(height)
+	return stats, r.record(operation{Method: "CommitWithHeight", Uint: height}, err)
+}
+
+// Close records Close
+func (r *Recorder) Close() error {
+	err := r.StateFactory.Close()
+	return r.record(operation{Method: "Close"}, err)
+}
+
+// DeleteAll records DeleteAll
+func (r *Recorder) DeleteAll() error {
+	return r.record(operation{Method: "DeleteAll"}, r.StateFactory.DeleteAll())
+}
+
+// RegisterName records RegisterName
+func (r *Recorder) RegisterName(name string, addr *iotxaddress.Address) error {
+	err := r.StateFactory.RegisterName(name, addr)
+	return r.record(operation{Method: "RegisterName", Addrs: publicAddresses(addr), Name: name}, err)
+}
+
+// Pause records Pause, it does nothing if the factory is not a Pauser
+func (r *Recorder) Pause() {
+	if p, ok := r.StateFactory.(Pauser); ok {
+		p.Pause()
+		r.record(operation{Method: "Pause"}, nil)
+	}
+}
+
+// Resume records Resume, it does nothing if the factory is not a Pauser
+func (r *Recorder) Resume() {
+	if p, ok := r.StateFactory.(Pauser); ok {
+		p.Resume()
+		r.record(operation{Method: "Resume"}, nil)
+	}
+}
+
+// Airdrop records Airdrop
+func (r *Recorder) Airdrop(from *iotxaddress.Address, recipients map[string]*big.Int) error {
+	err := r.StateFactory.Airdrop(from, recipients)
+	return r.record(operation{Method: "Airdrop", Addrs: publicAddresses(from), Amounts: recipients}, err)
+}
+
+// Lock records Lock
+func (r *Recorder) Lock(addr *iotxaddress.Address, amount *big.Int) error {
+	err := r.StateFactory.Lock(addr, amount)
+	return r.record(operation{Method: "Lock", Addrs: publicAddresses(addr), Amount: amount}, err)
+}
+
+// Unlock records Unlock
+func (r *Recorder) Unlock(addr *iotxaddress.Address, amount *big.Int) error {
+	err := r.StateFactory.Unlock(addr, amount)
+	return r.record(operation{Method: "Unlock", Addrs: publicAddresses(addr), Amount: amount}, err)
+}
+
+// RegisterCandidate records RegisterCandidate
+func (r *Recorder) RegisterCandidate(addr *iotxaddress.Address, height uint64) error {
+	err := r.StateFactory.RegisterCandidate(addr, height)
+	return r.record(operation{Method: "RegisterCandidate", Addrs: publicAddresses(addr), Uint: height}, err)
+}
+
+// RepairVotingWeights records RepairVotingWeights
+func (r *Recorder) RepairVotingWeights() (int, error) {
+	repaired, err := r.StateFactory.RepairVotingWeights()
+	return repaired, r.record(operation{Method: "RepairVotingWeights"}, err)
+}
+
+// SweepDustVotes records SweepDustVotes
+func (r *Recorder) SweepDustVotes() (int, error) {
+	swept, err := r.StateFactory.SweepDustVotes()
+	return swept, r.record(operation{Method: "SweepDustVotes"}, err)
+}
+
+// Vote records Vote
+func (r *Recorder) Vote(voter, candidate *iotxaddress.Address, weight *big.Int) error {
+	err := r.StateFactory.Vote(voter, candidate, weight)
+	return r.record(operation{Method: "Vote", Addrs: publicAddresses(voter, candidate), Amount: weight}, err)
+}
+
+// DeleteState records DeleteState
+func (r *Recorder) DeleteState(addr *iotxaddress.Address) error {
+	err := r.StateFactory.DeleteState(addr)
+	return r.record(operation{Method: "DeleteState", Addrs: publicAddresses(addr)}, err)
+}
+
+// ApplyVoteChanges records ApplyVoteChanges
+func (r *Recorder) ApplyVoteChanges(changes []VoteChange) error {
+	err := r.StateFactory.ApplyVoteChanges(changes)
+	recorded := make([]VoteChange, len(changes))
+	for i, change := range changes {
+		recorded[i] = VoteChange{
+			Voter:        publicAddress(change.Voter),
+			OldCandidate: publicAddress(change.OldCandidate),
+			NewCandidate: publicAddress(change.NewCandidate),
+			Weight:       change.Weight,
+		}
+	}
+	return r.record(operation{Method: "ApplyVoteChanges", Votes: recorded}, err)
+}
+
+// ResetAccount records ResetAccount
+func (r *Recorder) ResetAccount(addr *iotxaddress.Address) error {
+	err := r.StateFactory.ResetAccount(addr)
+	return r.record(operation{Method: "ResetAccount", Addrs: publicAddresses(addr)}, err)
+}
+
+// RotateKey records RotateKey
+func (r *Recorder) RotateKey(oldAddr, newAddr *iotxaddress.Address) error {
+	err := r.StateFactory.RotateKey(oldAddr, newAddr)
+	return r.record(operation{Method: "RotateKey", Addrs: publicAddresses(oldAddr, newAddr)}, err)
+}
+
+// Slash records Slash
+func (r *Recorder) Slash(addr *iotxaddress.Address, fraction *big.Rat) error {
+	err := r.StateFactory.Slash(addr, fraction)
+	return r.record(operation{Method: "Slash", Addrs: publicAddresses(addr), Ratio: fraction}, err)
+}
+
+// SetCode records SetCode
+func (r *Recorder) SetCode(addr *iotxaddress.Address, code []byte) error {
+	err := r.StateFactory.SetCode(addr, code)
+	return r.record(operation{Method: "SetCode", Addrs: publicAddresses(addr), Data: code}, err)
+}
+
+// ApplyBatch records ApplyBatch
+func (r *Recorder) ApplyBatch(changes []StateChange) error {
+	err := r.StateFactory.ApplyBatch(changes)
+	recorded := make([]StateChange, len(changes))
+	for i, change := range changes {
+		recorded[i] = change
+		recorded[i].Address = publicAddresses(change.Address)[0]
+	}
+	return r.record(operation{Method: "ApplyBatch", Changes: recorded}, err)
+}
+
+// SetHeight records SetHeight
+func (r *Recorder) SetHeight(height uint64) {
+	r.StateFactory.SetHeight(height)
+	r.record(operation{Method: "SetHeight", Uint: height}, nil)
+}
+
+// SetVesting records SetVesting
+func (r *Recorder) SetVesting(addr *iotxaddress.Address, schedule []VestingPoint) error {
+	err := r.StateFactory.SetVesting(addr, schedule)
+	return r.record(operation{Method: "SetVesting", Addrs: publicAddresses(addr), Vesting: schedule}, err)
+}
+
+// record logs the operation with its outcome and returns the error of the call
+func (r *Recorder) record(op operation, err error) error {
+	op.Failed = err != nil
+	op.Root = r.StateFactory.RootHash()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e := r.enc.Encode(&op); e != nil && r.err == nil {
+		r.err = errors.Wrap(e, "failed to record operation")
+	}
+	return err
+}
+
+// Replay re-executes the operations logged by a Recorder against the factory, which should be fresh
+// It fails with ErrReplayMismatch at the first operation whose root hash after it, or whether it failed, differs from
+// the log.
+func Replay(r io.Reader, sf StateFactory) error {
+	dec := gob.NewDecoder(r)
+	for i := 0; ; i++ {
+		var op operation
+		if err := dec.Decode(&op); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to decode operation %d", i)
+		}
+		known, err := replay(sf, &op)
+		if !known {
+			return errors.Errorf("operation %d: unknown method %s", i, op.Method)
+		}
+		if (err != nil) != op.Failed {
+			return errors.Wrapf(ErrReplayMismatch, "operation %d %s: error %v, recorded failed %t", i, op.Method, err,
+				op.Failed)
+		}
+		if root := sf.RootHash(); root != op.Root {
+			return errors.Wrapf(ErrReplayMismatch, "operation %d %s: root %x, recorded %x", i, op.Method, root, op.Root)
+		}
+	}
+}
+
+// replay executes one recorded operation, it returns false if the method is not known
+func replay(sf StateFactory, op *operation) (bool, error) {
+	addr := func(i int) *iotxaddress.Address {
+		if i < len(op.Addrs) {
+			return op.Addrs[i]
+		}
+		return &iotxaddress.Address{}
+	}
+	var err error
+	switch op.Method {
+	case "InitGenesis":
+		_, err = sf.InitGenesis(op.Genesis)
+	case "CreateState":
+		_, err = sf.CreateState(addr(0), op.Uint)
+	case "AddStateWithInit":
+		var init State
+		if op.Init != nil {
+			init = *op.Init
+		}
+		_, err = sf.AddStateWithInit(addr(0), init)
+	case "AddBalance":
+		err = sf.AddBalance(addr(0), op.Amount)
+	case "AddBalanceOf":
+		err = sf.AddBalanceOf(addr(0), AssetID(op.Uint), op.Amount)
+	case "SubBalanceOf":
+		err = sf.SubBalanceOf(addr(0), AssetID(op.Uint), op.Amount)
+	case "Burn":
+		err = sf.Burn(addr(0), op.Amount)
+	case "UpdateStatesWithTransfer":
+		err = sf.UpdateStatesWithTransfer(op.Txs)
+	case "ApplyTransferTx":
+		err = sf.ApplyTransferTx(addr(0), addr(1), op.Amount, op.Uint)
+	case "AuthorizedTransfer":
+		err = sf.AuthorizedTransfer(addr(0), addr(1), op.Amount, op.Uint, op.Data)
+	case "ApplySponsoredTx":
+		err = sf.ApplySponsoredTx(addr(0), addr(1), addr(2), op.Amount, op.Fee, op.Uint)
+	case "Approve":
+		err = ErrNotSupported
+		if a, ok := sf.(Approver); ok {
+			err = a.Approve(addr(0), addr(1), op.Amount)
+		}
+	case "TransferFrom":
+		err = ErrNotSupported
+		if a, ok := sf.(Approver); ok {
+			err = a.TransferFrom(addr(0), addr(1), addr(2), op.Amount)
+		}
+	case "SetNonce":
+		err = sf.SetNonce(addr(0), op.Uint)
+	case "Commit":
+		_, err = sf.Commit()
+	case "CommitWithHeight":
+		_, err = sf.CommitWithHeight(op.Uint)
+	case "Close":
+		err = sf.Close()
+	case "DeleteAll":
+		err = sf.DeleteAll()
+	case "RegisterName":
+		err = sf.RegisterName(op.Name, addr(0))
+	case "Pause":
+		if p, ok := sf.(Pauser); ok {
+			p.Pause()
+		} else {
+			err = ErrNotSupported
+		}
+	case "Resume":
+		if p, ok := sf.(Pauser); ok {
+			p.Resume()
+		} else {
+			err = ErrNotSupported
+		}
+	case "Airdrop":
+		err = sf.Airdrop(addr(0), op.Amounts)
+	case "Lock":
+		err = sf.Lock(addr(0), op.Amount)
+	case "Unlock":
+		err = sf.Unlock(addr(0), op.Amount)
+	case "RegisterCandidate":
+		err = sf.RegisterCandidate(addr(0), op.Uint)
+	case "RepairVotingWeights":
+		_, err = sf.RepairVotingWeights()
+	case "SweepDustVotes":
+		_, err = sf.SweepDustVotes()
+	case "Vote":
+		err = sf.Vote(addr(0), addr(1), op.Amount)
+	case "ApplyVoteChanges":
+		err = sf.ApplyVoteChanges(op.Votes)
+	case "DeleteState":
+		err = sf.DeleteState(addr(0))
+	case "ResetAccount":
+		err = sf.ResetAccount(addr(0))
+	case "RotateKey":
+		err = sf.RotateKey(addr(0), addr(1))
+	case "Slash":
+		err = sf.Slash(addr(0), op.Ratio)
+	case "SetCode":
+		err = sf.SetCode(addr(0), op.Data)
+	case "ApplyBatch":
+		err = sf.ApplyBatch(op.Changes)
+	case "SetHeight":
+		sf.SetHeight(op.Uint)
+	case "SetVesting":
+		err = sf.SetVesting(addr(0), op.Vesting)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// publicAddresses returns copies of the addresses without the private key
+func publicAddresses(addrs ...*iotxaddress.Address) []*iotxaddress.Address {
+	public := make([]*iotxaddress.Address, len(addrs))
+	for i, addr := range addrs {
+		public[i] = &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}
+	}
+	return public
+}
+
+// publicAddress returns a copy of the address without the private key, nil stays nil
+func publicAddress(addr *iotxaddress.Address) *iotxaddress.Address {
+	if addr == nil {
+		return nil
+	}
+	return publicAddresses(addr)[0]
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestRecordReplay(t *testing.T) {
+	newFactory := func() StateFactory {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		return NewStateFactory(tr)
+	}
+	var log bytes.Buffer
+	rec := NewRecorder(newFactory(), &log)
+
+	addrs := make([]*iotxaddress.Address, 3)
+	for i := range addrs {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		_, err = rec.CreateState(addr, 100)
+		assert.Nil(t, err)
+		addrs[i] = addr
+	}
+	rec.SetHeight(3)
+	assert.Nil(t, rec.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(10), 0))
+	assert.Equal(t, ErrNonceTooLow, rec.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(10), 0))
+	assert.Nil(t, rec.Lock(addrs[1], big.NewInt(20)))
+	assert.Nil(t, rec.RegisterCandidate(addrs[1], 3))
+	assert.Nil(t, rec.Vote(addrs[2], addrs[1], big.NewInt(50)))
+	assert.Nil(t, rec.SetCode(addrs[2], []byte("code")))
+	_, err := rec.Commit()
+	assert.Nil(t, err)
+	assert.Nil(t, rec.Burn(addrs[0], big.NewInt(5)))
+	assert.Nil(t, rec.DeleteState(addrs[0]))
+	assert.Nil(t, rec.Err())
+	// private keys are not logged
+	for _, addr := range addrs {
+		assert.False(t, bytes.Contains(log.Bytes(), addr.PrivateKey))
+	}
+
+	recorded := log.Bytes()
+	sf := newFactory()
+	assert.Nil(t, Replay(bytes.NewReader(recorded), sf))
+	assert.Equal(t, rec.RootHash(), sf.RootHash())
+
+	// replaying against a state that has diverged is caught
+	sf = newFactory()
+	createAccounts(t, sf, 1, 1)
+	assert.Equal(t, ErrReplayMismatch, errors.Cause(Replay(bytes.NewReader(recorded), sf)))
+}
@@ -0,0 +1,27 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+// Reserve hints that about n accounts are about to be written before the next commit, e.g. when importing a snapshot
+// The pending changes, and the changed trie nodes if the trie supports it, are pre-sized to avoid growing them one
+// account at a time. It is only a hint: it has no effect on the state or RootHash, and nothing is reserved if changes
+// are already pending.
+func (sf *stateFactory) Reserve(n int) {
+	if n <= 0 {
+		return
+	}
+	sf.pendingMu.Lock()
+	if len(sf.pending) == 0 {
+		sf.pending = make(map[string]AddressChange, n)
+	}
+	sf.pendingMu.Unlock()
+	if r, ok := sf.trie.(interface {
+		Reserve(int)
+	}); ok {
+		r.Reserve(n)
+	}
+}
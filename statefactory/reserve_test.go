@@ -0,0 +1,95 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestReserve(t *testing.T) {
+	addrs := make([]*iotxaddress.Address, 500)
+	for i := range addrs {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		addrs[i] = addr
+	}
+	load := func(reserve int) StateFactory {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		sf := NewStateFactory(tr)
+		sf.Reserve(reserve)
+		for i, addr := range addrs {
+			_, err := sf.CreateState(addr, uint64(i))
+			assert.Nil(t, err)
+		}
+		return sf
+	}
+	plain := load(0)
+	reserved := load(len(addrs))
+	assert.Equal(t, plain.RootHash(), reserved.RootHash())
+	assert.Equal(t, len(plain.(Monitor).PendingChanges()), len(reserved.(Monitor).PendingChanges()))
+	_, err := plain.Commit()
+	assert.Nil(t, err)
+	_, err = reserved.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, plain.RootHash(), reserved.RootHash())
+
+	// reserving with changes pending keeps them
+	reserved.Reserve(len(addrs))
+	assert.Nil(t, reserved.AddBalance(addrs[0], big.NewInt(5)))
+	assert.Nil(t, plain.AddBalance(addrs[0], big.NewInt(5)))
+	reserved.Reserve(len(addrs))
+	assert.Equal(t, plain.(Monitor).PendingChanges(), reserved.(Monitor).PendingChanges())
+	assert.Equal(t, plain.RootHash(), reserved.RootHash())
+}
+
+func BenchmarkImport(b *testing.B) {
+	benchmarkImport(b, false)
+}
+
+func BenchmarkImportReserved(b *testing.B) {
+	benchmarkImport(b, true)
+}
+
+// benchmarkImport creates a large number of accounts and commits them at once, as importing a snapshot does
+func benchmarkImport(b *testing.B, reserve bool) {
+	const accounts = 10000
+	addrs := make([]*iotxaddress.Address, accounts)
+	for i := range addrs {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		if err != nil {
+			b.Fatal(err)
+		}
+		addrs[i] = addr
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		if err != nil {
+			b.Fatal(err)
+		}
+		sf := NewStateFactory(tr)
+		if reserve {
+			sf.Reserve(accounts)
+		}
+		for _, addr := range addrs {
+			if _, err := sf.CreateState(addr, 10); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if _, err := sf.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
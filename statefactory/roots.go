@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/common/utils"
+	"github.com/iotexproject/iotex-core/db"
+)
+
+const (
+	// rootIndexKVNameSpace is the namespace of the index of recently committed roots in the KV store
+	rootIndexKVNameSpace = "RootIndex"
+
+	// defaultRootWindow is the number of recent roots the index retains if RootIndexOption is given no window
+	defaultRootWindow = 256
+
+	// rootAtHeightLen is the length of an encoded RootAtHeight, the height followed by the root
+	rootAtHeightLen = 8 + 32
+)
+
+var (
+	// recentRootsKey is the key of the recent roots in the root index namespace
+	recentRootsKey = []byte("recent")
+
+	// ErrNoRootIndex is the error that no KV store has been set for the index of committed roots
+	ErrNoRootIndex = errors.New("no root index")
+)
+
+// RootAtHeight is the root hash committed at a block height
+type RootAtHeight struct {
+	Height uint64
+	Root   common.Hash32B
+}
+
+// RootIndexOption sets the KV store CommitWithHeight indexes the committed roots in, by default RecentRoots fails
+// The index retains the roots of the last window heights committed, the default window is 256 if it is not positive.
+// The store may be shared with the trie, the index has its own namespace.
+func RootIndexOption(kv db.KVStore, window int) Option {
+	return func(sf *stateFactory) {
+		if window <= 0 {
+			window = defaultRootWindow
+		}
+		sf.roots, sf.rootWindow = kv, window
+	}
+}
+
+// RecentRoots returns up to the last n roots committed by CommitWithHeight with their heights, newest first
+// It lets a light client joining mid-stream verify a window of header links at once. Only the roots retained by the
+// index are returned, see RootIndexOption, and plain Commit indexes none.
+func (sf *stateFactory) RecentRoots(n int) ([]RootAtHeight, error) {
+	if sf.roots == nil {
+		return nil, ErrNoRootIndex
+	}
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return nil, ErrClosed
+	}
+	recent, err := sf.loadRecentRoots()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n < len(recent) {
+		recent = recent[:n]
+	}
+	return recent, nil
+}
+
+// indexRoot records the root committed at the height in the root index, dropping the roots beyond the window
+// The retained roots are written as one record, so the index is never seen half updated.
+func (sf *stateFactory) indexRoot(height uint64, root common.Hash32B) error {
+	if sf.roots == nil {
+		return nil
+	}
+	recent, err := sf.loadRecentRoots()
+	if err != nil {
+		return err
+	}
+	recent = append([]RootAtHeight{{Height: height, Root: root}}, recent...)
+	if len(recent) > sf.rootWindow {
+		recent = recent[:sf.rootWindow]
+	}
+	value := make([]byte, 0, len(recent)*rootAtHeightLen)
+	for _, r := range recent {
+		value = append(value, utils.Uint64ToBytes(r.Height)...)
+		value = append(value, r.Root[:]...)
+	}
+	return sf.roots.Put(rootIndexKVNameSpace, recentRootsKey, value)
+}
+
+// loadRecentRoots reads the roots retained by the root index, newest first
+func (sf *stateFactory) loadRecentRoots() ([]RootAtHeight, error) {
+	value, err := sf.roots.Get(rootIndexKVNameSpace, recentRootsKey)
+	if cause := errors.Cause(err); cause == db.ErrNotExist || cause == bolt.ErrBucketNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(value)%rootAtHeightLen != 0 {
+		return nil, errors.Errorf("corrupted root index of %d bytes", len(value))
+	}
+	recent := make([]RootAtHeight, len(value)/rootAtHeightLen)
+	for i := range recent {
+		r := value[i*rootAtHeightLen : (i+1)*rootAtHeightLen]
+		recent[i].Height = common.MachineEndian.Uint64(r[:8])
+		copy(recent[i].Root[:], r[8:])
+	}
+	return recent, nil
+}
@@ -0,0 +1,64 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestRecentRoots(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	_, err = NewStateFactory(tr).RecentRoots(1)
+	assert.Equal(t, ErrNoRootIndex, err)
+
+	sf := NewStateFactory(tr, RootIndexOption(kv, 4))
+	recent, err := sf.RecentRoots(4)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(recent))
+	addrs := createAccounts(t, sf, 1, 10)
+	var committed []RootAtHeight
+	for _, height := range []uint64{1, 2, 3, 5, 8} {
+		assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+		_, err = sf.CommitWithHeight(height)
+		assert.Nil(t, err)
+		committed = append(committed, RootAtHeight{Height: height, Root: sf.RootHash()})
+	}
+	// a plain commit is not indexed
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(1)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// newest first, only the window is retained
+	newest := []RootAtHeight{committed[4], committed[3], committed[2], committed[1]}
+	recent, err = sf.RecentRoots(10)
+	assert.Nil(t, err)
+	assert.Equal(t, newest, recent)
+	recent, err = sf.RecentRoots(2)
+	assert.Nil(t, err)
+	assert.Equal(t, newest[:2], recent)
+	recent, err = sf.RecentRoots(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(recent))
+
+	// the index outlives the factory
+	recent, err = NewStateFactory(tr, RootIndexOption(kv, 4)).RecentRoots(4)
+	assert.Nil(t, err)
+	assert.Equal(t, newest, recent)
+
+	assert.Nil(t, sf.DeleteAll())
+	recent, err = sf.RecentRoots(4)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(recent))
+}
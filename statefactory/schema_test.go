@@ -0,0 +1,72 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/crc32"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestSchemaTooNew(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// a leaf written by a later schema, with a field this code does not know
+	future := struct {
+		Nonce   uint64
+		Balance *big.Int
+		Address *iotxaddress.Address
+		Memo    string
+	}{
+		Nonce:   1,
+		Balance: big.NewInt(100),
+		Address: &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress},
+		Memo:    "kept by newer nodes",
+	}
+	var b bytes.Buffer
+	b.Write(make([]byte, stateHeaderLen))
+	assert.Nil(t, gob.NewEncoder(&b).Encode(future))
+	leaf := b.Bytes()
+	leaf[0] = stateFormatNext
+	binary.BigEndian.PutUint32(leaf[1:stateHeaderLen], crc32.ChecksumIEEE(leaf[stateHeaderLen:]))
+	key := iotxaddress.HashPubKey(addr.PublicKey)
+	assert.Nil(t, tr.Upsert(key, leaf))
+
+	// reads and mutations are refused, the leaf is left as written
+	_, err = sf.GetState(addr)
+	assert.Equal(t, ErrSchemaTooNew, errors.Cause(err))
+	_, err = sf.Balance(addr)
+	assert.Equal(t, ErrSchemaTooNew, errors.Cause(err))
+	assert.Equal(t, ErrSchemaTooNew, errors.Cause(sf.AddBalance(addr, big.NewInt(1))))
+	stored, err := tr.Get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, leaf, stored)
+
+	// so is the last reserved version, while a leaf written without checksum still reads
+	leaf[0] = stateFormatReservedEnd - 1
+	_, err = bytesToState(leaf, defaultMaxLeafSize)
+	assert.Equal(t, ErrSchemaTooNew, errors.Cause(err))
+	s := newState(future.Address, 100)
+	var legacy bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&legacy).Encode(s))
+	_, err = bytesToState(legacy.Bytes(), defaultMaxLeafSize)
+	assert.Nil(t, err)
+}
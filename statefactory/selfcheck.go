@@ -0,0 +1,88 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrAddressKeyMismatch is the error that a state's Address does not hash to the key it is stored under
+var ErrAddressKeyMismatch = errors.New("address does not match account key")
+
+// VerifyAddressOption makes GetState, GetStateByKey and the other reads check the stored Address against the key
+// A leaf claiming another account's address is then refused with ErrAddressKeyMismatch. The check costs a hash per
+// read, SelfCheck runs it regardless of the option.
+func VerifyAddressOption() Option {
+	return func(sf *stateFactory) {
+		sf.verifyAddr = true
+	}
+}
+
+// SelfCheck verifies that the state of every account the factory can enumerate is stored under its own key
+// The trie cannot be iterated, so the accounts checked are the candidates, the voters and the accounts changed since
+// the last commit. The first mismatch is reported as ErrAddressKeyMismatch. With StrictDecodeOption the amounts of each
+// state are checked too.
+func (sf *stateFactory) SelfCheck() error {
+	if err := sf.checkOpen(); err != nil {
+		return err
+	}
+	var addrs []*iotxaddress.Address
+	for _, key := range [][]byte{candidateListKey, voterListKey} {
+		list, err := sf.getAddressList(key)
+		if err != nil {
+			return err
+		}
+		addrs = append(addrs, list...)
+	}
+	for _, change := range sf.PendingChanges() {
+		if change.Kind != ChangeDeleted {
+			addrs = append(addrs, change.Address)
+		}
+	}
+	for _, addr := range addrs {
+		key := AccountKeyOf(addr)
+		state, err := getStateByKey(sf.trie, key, sf.maxLeafSize)
+		if err != nil {
+			return sf.checkLeaf(key.Bytes(), err)
+		}
+		if err := checkAddressKey(state, key); err != nil {
+			return sf.checkLeaf(key.Bytes(), err)
+		}
+		if sf.strictDecode {
+			if err := checkConsistency(state); err != nil {
+				return sf.checkLeaf(key.Bytes(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyState runs the checks the factory is configured to run on a decoded state read by the key
+func (sf *stateFactory) verifyState(state *State, key AccountKey) error {
+	if sf.verifyAddr {
+		if err := checkAddressKey(state, key); err != nil {
+			return err
+		}
+	}
+	if sf.strictDecode {
+		return checkConsistency(state)
+	}
+	return nil
+}
+
+// checkAddressKey returns ErrAddressKeyMismatch if the state's Address does not hash to the key
+// A state without an Address claims no account, so it is not a mismatch.
+func checkAddressKey(state *State, key AccountKey) error {
+	if state.Address == nil || bytes.Equal(iotxaddress.HashPubKey(state.Address.PublicKey), key.Bytes()) {
+		return nil
+	}
+	return errors.Wrapf(ErrAddressKeyMismatch, "key %x holds address %s", key.Bytes(), state.Address.RawAddress)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestAddressKeyMismatch(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	verified := NewStateFactory(tr, VerifyAddressOption())
+	addrs := createAccounts(t, sf, 2, 10)
+	assert.Nil(t, sf.SelfCheck())
+	state, err := verified.GetState(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, addrs[0].RawAddress, state.Address.RawAddress)
+
+	// file a state claiming the second address under the first one's key
+	ss, err := stateToBytes(newState(addrs[1], 10))
+	assert.Nil(t, err)
+	assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addrs[0].PublicKey), ss))
+
+	// reads only check the address with VerifyAddressOption
+	_, err = sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	_, err = verified.GetState(addrs[0])
+	assert.Equal(t, ErrAddressKeyMismatch, errors.Cause(err))
+	_, err = verified.Balance(addrs[0])
+	assert.Equal(t, ErrAddressKeyMismatch, errors.Cause(err))
+	_, err = verified.GetStateByKey(AccountKeyOf(addrs[0]))
+	assert.Equal(t, ErrAddressKeyMismatch, errors.Cause(err))
+	_, err = verified.GetState(addrs[1])
+	assert.Nil(t, err)
+
+	// SelfCheck always checks the accounts it can enumerate
+	assert.Equal(t, ErrAddressKeyMismatch, errors.Cause(sf.SelfCheck()))
+}
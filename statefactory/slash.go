@@ -0,0 +1,134 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrInvalidFraction is the error that a slashing fraction is not between 0 and 1
+var ErrInvalidFraction = errors.New("invalid slashing fraction")
+
+// Slash takes the fraction of the account's locked self-stake as a penalty for misbehaving as a delegate
+// The slashed amount, rounded down, is burned as by Burn: credited to the burn address if one is configured, otherwise
+// it leaves the supply. Every vote the account received shrinks by the same fraction, rounded down, and its
+// VotingWeight by their total, the voters get the cut weight back to vote again but keep their balances. As with
+// Unlock, a candidate left below the minimum self-stake is unregistered. All the accounts change together or not at
+// all.
+func (sf *stateFactory) Slash(addr *iotxaddress.Address, fraction *big.Rat) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if fraction == nil || fraction.Sign() < 0 || fraction.Cmp(big.NewRat(1, 1)) > 0 {
+		return ErrInvalidFraction
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return err
+	}
+	keys := [][]byte{iotxaddress.HashPubKey(addr.PublicKey)}
+	if sf.burnAddress != nil {
+		keys = append(keys, iotxaddress.HashPubKey(sf.burnAddress.PublicKey))
+	}
+	for _, voter := range voters {
+		keys = append(keys, iotxaddress.HashPubKey(voter.PublicKey))
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	load := func(addr *iotxaddress.Address) (*State, error) {
+		key := AccountKeyOf(addr)
+		if state, ok := states[key]; ok {
+			return state, nil
+		}
+		state, err := sf.getState(addr)
+		if err != nil {
+			return nil, err
+		}
+		states[key], addrs[key] = state, addr
+		return state, nil
+	}
+	target, err := load(addr)
+	if err != nil {
+		return err
+	}
+	locked := target.lockedBalance()
+	slashed := slashedPart(locked, fraction)
+	target.LockedBalance = new(big.Int).Sub(locked, slashed)
+	unregistered := target.IsCandidate && target.LockedBalance.Cmp(sf.minSelfStake) < 0
+	if unregistered {
+		target.IsCandidate = false
+	}
+
+	voterAddrs := make(map[common.Hash32B]*iotxaddress.Address)
+	for _, voter := range voters {
+		voterAddrs[voterKey(voter)] = voter
+	}
+	totalCut := big.NewInt(0)
+	for key, vote := range target.Voters {
+		cut := slashedPart(vote, fraction)
+		if cut.Sign() == 0 {
+			continue
+		}
+		if left := new(big.Int).Sub(vote, cut); left.Sign() > 0 {
+			target.Voters[key] = left
+		} else {
+			delete(target.Voters, key)
+		}
+		totalCut.Add(totalCut, cut)
+		voter, ok := voterAddrs[key]
+		if !ok {
+			continue
+		}
+		state, err := load(voter)
+		if err != nil {
+			return err
+		}
+		state.VotedWeight = new(big.Int).Sub(state.votedWeight(), cut)
+	}
+	if totalCut.Sign() > 0 {
+		target.VotingWeight = new(big.Int).Sub(target.VotingWeight, totalCut)
+	}
+
+	var created map[AccountKey]bool
+	if sf.burnAddress != nil && slashed.Sign() > 0 {
+		sink, err := load(sf.burnAddress)
+		if err == ErrAccountNotExist {
+			key := AccountKeyOf(sf.burnAddress)
+			sink = newState(sf.burnAddress, 0)
+			states[key], addrs[key] = sink, sf.burnAddress
+			created = map[AccountKey]bool{key: true}
+		} else if err != nil {
+			return err
+		}
+		if err := sink.AddBalance(slashed); err != nil {
+			return err
+		}
+	}
+	if err := sf.writeStates(states, addrs, created); err != nil {
+		return err
+	}
+	if unregistered {
+		return sf.removeFromAddressList(candidateListKey, addr)
+	}
+	return nil
+}
+
+// slashedPart returns the fraction of the amount, rounded down
+func slashedPart(amount *big.Int, fraction *big.Rat) *big.Int {
+	part := new(big.Int).Mul(amount, fraction.Num())
+	return part.Div(part, fraction.Denom())
+}
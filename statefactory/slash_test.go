@@ -0,0 +1,122 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestSlash(t *testing.T) {
+	sink, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	for _, burnToAddress := range []bool{false, true} {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		var opts []Option
+		if burnToAddress {
+			opts = append(opts, BurnAddressOption(sink))
+		}
+		sf := NewStateFactory(tr, opts...)
+		addrs := createAccounts(t, sf, 3, 1000)
+		candidate, voters := addrs[0], addrs[1:]
+		assert.Nil(t, sf.Lock(candidate, big.NewInt(100)))
+		assert.Nil(t, sf.RegisterCandidate(candidate, 0))
+		assert.Nil(t, sf.Vote(voters[0], candidate, big.NewInt(150)))
+		assert.Nil(t, sf.Vote(voters[1], candidate, big.NewInt(55)))
+
+		supply := func() *big.Int {
+			total := big.NewInt(0)
+			for _, addr := range append(addrs, sink) {
+				state, err := sf.GetState(addr)
+				if err == ErrAccountNotExist {
+					continue
+				}
+				assert.Nil(t, err)
+				total.Add(total, state.stake())
+			}
+			return total
+		}
+		check := func(locked, burned int64, votes ...int64) {
+			state, err := sf.GetState(candidate)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, state.LockedBalance.Cmp(big.NewInt(locked)))
+			assert.Nil(t, checkConsistency(state))
+			weight := int64(0)
+			for i, vote := range votes {
+				weight += vote
+				assert.Equal(t, 0, state.Voters[voterKey(voters[i])].Cmp(big.NewInt(vote)))
+				voter, err := sf.GetState(voters[i])
+				assert.Nil(t, err)
+				assert.Equal(t, 0, voter.votedWeight().Cmp(big.NewInt(vote)))
+			}
+			assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(weight)))
+			if burnToAddress {
+				// the supply is kept, the slashed stake sits at the burn address
+				assert.Equal(t, 0, supply().Cmp(big.NewInt(3000)))
+				balance, err := sf.Balance(sink)
+				if burned == 0 {
+					assert.Equal(t, ErrAccountNotExist, err)
+				} else {
+					assert.Nil(t, err)
+					assert.Equal(t, 0, balance.Cmp(big.NewInt(burned)))
+				}
+			} else {
+				// the supply shrinks by the slashed stake
+				assert.Equal(t, 0, supply().Cmp(big.NewInt(3000-burned)))
+			}
+		}
+
+		for _, fraction := range []*big.Rat{nil, big.NewRat(-1, 10), big.NewRat(11, 10)} {
+			assert.Equal(t, ErrInvalidFraction, sf.Slash(candidate, fraction))
+		}
+		check(100, 0, 150, 55)
+
+		// slashing 10% rounds each part down
+		assert.Nil(t, sf.Slash(candidate, big.NewRat(1, 10)))
+		check(90, 10, 135, 50)
+
+		// slashing 100% takes the rest of the stake and every vote
+		assert.Nil(t, sf.Slash(candidate, big.NewRat(1, 1)))
+		check(0, 100)
+		state, err := sf.GetState(candidate)
+		assert.Nil(t, err)
+		assert.Empty(t, state.Voters)
+		assert.True(t, state.IsCandidate)
+
+		// the voters can vote again with the weight they got back
+		assert.Nil(t, sf.Vote(voters[0], candidate, big.NewInt(1000)))
+	}
+}
+
+func TestSlashUnregisters(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MinSelfStakeOption(big.NewInt(50)))
+	candidate := createAccounts(t, sf, 1, 100)[0]
+	assert.Nil(t, sf.Lock(candidate, big.NewInt(60)))
+	assert.Nil(t, sf.RegisterCandidate(candidate, 0))
+	assert.Nil(t, sf.Slash(candidate, big.NewRat(1, 10)))
+	ranked, err := sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Len(t, ranked, 1)
+
+	assert.Nil(t, sf.Slash(candidate, big.NewRat(1, 2)))
+	state, err := sf.GetState(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.LockedBalance.Cmp(big.NewInt(27)))
+	assert.False(t, state.IsCandidate)
+	ranked, err = sf.RankedCandidates()
+	assert.Nil(t, err)
+	assert.Empty(t, ranked)
+}
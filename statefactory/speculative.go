@@ -0,0 +1,83 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// StateReader is the read-only view of account balances and nonces
+type StateReader interface {
+	Balance(*iotxaddress.Address) (*big.Int, error)
+	Nonce(*iotxaddress.Address) (uint64, error)
+}
+
+// SpeculativeView overlays pending adjustments on a StateReader without touching the underlying state
+// It lets the mempool count transactions that are accepted but not mined yet, e.g. to reject a transaction that would
+// overdraw once the pending ones before it are applied. A SpeculativeView is not safe for concurrent use.
+type SpeculativeView struct {
+	reader StateReader
+	deltas map[AccountKey]*big.Int
+	nonces map[AccountKey]uint64
+}
+
+// NewSpeculativeView creates a SpeculativeView on the reader with no adjustments
+func NewSpeculativeView(reader StateReader) *SpeculativeView {
+	return &SpeculativeView{
+		reader: reader,
+		deltas: make(map[AccountKey]*big.Int),
+		nonces: make(map[AccountKey]uint64),
+	}
+}
+
+// Adjust adds the delta to the account's speculative balance, a debit that would overdraw it is rejected
+// An account the reader does not know starts from a zero balance, so a pending credit can create it.
+func (v *SpeculativeView) Adjust(addr *iotxaddress.Address, delta *big.Int) error {
+	balance, err := v.Balance(addr)
+	if err != nil {
+		return err
+	}
+	if new(big.Int).Add(balance, delta).Sign() < 0 {
+		return newInsufficientBalanceError(balance, new(big.Int).Neg(delta))
+	}
+	key := AccountKeyOf(addr)
+	if d, ok := v.deltas[key]; ok {
+		v.deltas[key] = new(big.Int).Add(d, delta)
+	} else {
+		v.deltas[key] = new(big.Int).Set(delta)
+	}
+	return nil
+}
+
+// SetNonce sets the account's speculative nonce
+func (v *SpeculativeView) SetNonce(addr *iotxaddress.Address, nonce uint64) {
+	v.nonces[AccountKeyOf(addr)] = nonce
+}
+
+// Balance returns the account's balance with the pending adjustments applied
+func (v *SpeculativeView) Balance(addr *iotxaddress.Address) (*big.Int, error) {
+	balance, err := v.reader.Balance(addr)
+	switch {
+	case err == ErrAccountNotExist:
+		balance = big.NewInt(0)
+	case err != nil:
+		return nil, err
+	}
+	if d, ok := v.deltas[AccountKeyOf(addr)]; ok {
+		return new(big.Int).Add(balance, d), nil
+	}
+	return new(big.Int).Set(balance), nil
+}
+
+// Nonce returns the account's speculative nonce if set, the reader's otherwise
+func (v *SpeculativeView) Nonce(addr *iotxaddress.Address) (uint64, error) {
+	if nonce, ok := v.nonces[AccountKeyOf(addr)]; ok {
+		return nonce, nil
+	}
+	return v.reader.Nonce(addr)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestSpeculativeView(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 1, 100)
+	sender := addrs[0]
+	recipient, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	view := NewSpeculativeView(sf)
+	assert.Nil(t, view.Adjust(sender, big.NewInt(-60)))
+	assert.Nil(t, view.Adjust(recipient, big.NewInt(60)))
+	view.SetNonce(sender, 1)
+
+	// the second pending debit would overdraw once the first is counted
+	err = view.Adjust(sender, big.NewInt(-50))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(err))
+	assert.Equal(t, 0, err.(*InsufficientBalanceError).Shortfall().Cmp(big.NewInt(10)))
+	assert.Nil(t, view.Adjust(sender, big.NewInt(-40)))
+
+	balance, err := view.Balance(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Sign())
+	balance, err = view.Balance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(60)))
+	nonce, err := view.Nonce(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), nonce)
+
+	// the real state is untouched
+	assert.Equal(t, root, sf.RootHash())
+	balance, err = sf.Balance(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(100)))
+	nonce, err = sf.Nonce(sender)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), nonce)
+	_, err = sf.Balance(recipient)
+	assert.Equal(t, ErrAccountNotExist, err)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ApplySponsoredTx applies the sender's transfer with the fee paid by the sponsor
+// The sender's nonce must equal senderNonce and is incremented, the fee is debited from the sponsor and the amount
+// moves from the sender to the recipient. The accounts are read once and all changes are checked in memory before any
+// write, so if the sponsor cannot pay the fee or the sender cannot pay the amount nothing is changed. The same address
+// may take several roles, e.g. a sponsor paying its own fee. A recipient without an account gets one created.
+func (sf *stateFactory) ApplySponsoredTx(sender, sponsor, recipient *iotxaddress.Address, amount, fee *big.Int,
+	senderNonce uint64) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(sender, sponsor, recipient); err != nil {
+		return err
+	}
+	addrs := []*iotxaddress.Address{sender, sponsor, recipient}
+	keys := []AccountKey{AccountKeyOf(sender), AccountKeyOf(sponsor), AccountKeyOf(recipient)}
+	unlock := sf.lockAccounts(keys[0].Bytes(), keys[1].Bytes(), keys[2].Bytes())
+	defer unlock()
+	if amount.Sign() < 0 || fee.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	if err := sf.policy.AllowTransfer(sender, recipient, amount); err != nil {
+		return err
+	}
+	if err := sf.policy.AllowBalanceChange(sponsor, new(big.Int).Neg(fee)); err != nil {
+		return err
+	}
+	// an address taking several roles shares one State, so its changes add up
+	states := make(map[AccountKey]*State)
+	from, err := sf.getState(sender)
+	if err != nil {
+		return err
+	}
+	states[keys[0]] = from
+	switch {
+	case senderNonce < from.Nonce:
+		return ErrNonceTooLow
+	case senderNonce > from.Nonce:
+		return ErrNonceTooHigh
+	}
+	from.Nonce = senderNonce + 1
+	payer, ok := states[keys[1]]
+	if !ok {
+		if payer, err = sf.getState(sponsor); err != nil {
+			return err
+		}
+		states[keys[1]] = payer
+	}
+	if err := payer.SubBalance(fee); err != nil {
+		return err
+	}
+	if err := from.SubBalance(amount); err != nil {
+		return err
+	}
+	to, ok := states[keys[2]]
+	create := false
+	if !ok {
+		to, err = sf.getState(recipient)
+		create = err == ErrAccountNotExist
+		switch {
+		case create:
+			to = newState(recipient, 0)
+		case err != nil:
+			return err
+		}
+		states[keys[2]] = to
+	}
+	if err := to.AddBalance(amount); err != nil {
+		return err
+	}
+	// encode every State before the first write, so a refused State leaves the trie untouched
+	encoded := make(map[AccountKey][]byte)
+	for key, state := range states {
+		if encoded[key], err = sf.encodeState(state); err != nil {
+			return err
+		}
+	}
+	var count uint64
+	if create {
+		sf.countMu.Lock()
+		defer sf.countMu.Unlock()
+		if count, err = sf.accountCount(); err != nil {
+			return err
+		}
+	}
+	for i, key := range keys {
+		ss, ok := encoded[key]
+		if !ok {
+			// already written for an earlier role
+			continue
+		}
+		if err := sf.trie.Upsert(key.Bytes(), ss); err != nil {
+			return err
+		}
+		delete(encoded, key)
+		if i == 2 && create {
+			sf.touch(recipient, ChangeCreated)
+		} else {
+			sf.touch(addrs[i], ChangeUpdated)
+		}
+	}
+	if create {
+		return sf.putAccountCount(count + 1)
+	}
+	return nil
+}
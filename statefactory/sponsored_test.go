@@ -0,0 +1,89 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestApplySponsoredTx(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 3, 100)
+	sender, sponsor, recipient := addrs[0], addrs[1], addrs[2]
+	assert.Nil(t, sf.SetNonce(sender, 3))
+	assert.Nil(t, sf.SetNonce(sponsor, 7))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	root := sf.RootHash()
+	missing, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	// every failure leaves the state fully untouched
+	for _, c := range []struct {
+		sender, sponsor *iotxaddress.Address
+		amount, fee     int64
+		nonce           uint64
+		err             error
+	}{
+		{sender, sponsor, 10, 1, 2, ErrNonceTooLow},
+		{sender, sponsor, 10, 1, 4, ErrNonceTooHigh},
+		{sender, sponsor, -1, 1, 3, ErrInvalidAmount},
+		{sender, sponsor, 10, -1, 3, ErrInvalidAmount},
+		// the sponsor lacks the fee
+		{sender, sponsor, 10, 101, 3, ErrNotEnoughBalance},
+		{sender, missing, 10, 1, 3, ErrAccountNotExist},
+		// the sender lacks the amount, after the sponsor is already charged in memory
+		{sender, sponsor, 101, 1, 3, ErrNotEnoughBalance},
+		// a sender sponsoring itself must cover both
+		{sender, sender, 95, 10, 3, ErrNotEnoughBalance},
+	} {
+		err := sf.ApplySponsoredTx(c.sender, c.sponsor, recipient, big.NewInt(c.amount), big.NewInt(c.fee), c.nonce)
+		assert.Equal(t, c.err, errors.Cause(err))
+		assert.Equal(t, root, sf.RootHash())
+		assert.Empty(t, sf.(Monitor).PendingChanges())
+	}
+
+	assert.Nil(t, sf.ApplySponsoredTx(sender, sponsor, recipient, big.NewInt(100), big.NewInt(10), 3))
+	for _, c := range []struct {
+		addr    *iotxaddress.Address
+		nonce   uint64
+		balance int64
+	}{
+		{sender, 4, 0},
+		// the sponsor's nonce is not used
+		{sponsor, 7, 90},
+		{recipient, 0, 200},
+	} {
+		nonce, balance, err := sf.NonceAndBalance(c.addr)
+		assert.Nil(t, err)
+		assert.Equal(t, c.nonce, nonce)
+		assert.Equal(t, 0, balance.Cmp(big.NewInt(c.balance)))
+	}
+
+	// the recipient account is created if missing
+	assert.Nil(t, sf.ApplySponsoredTx(recipient, recipient, missing, big.NewInt(50), big.NewInt(5), 0))
+	nonce, balance, err := sf.NonceAndBalance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), nonce)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(145)))
+	balance, err = sf.Balance(missing)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(50)))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), count)
+}
@@ -8,21 +8,56 @@ package statefactory
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
 	"math/big"
+	"math/rand"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 
 	trx "github.com/iotexproject/iotex-core/blockchain/trx"
 	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/iotxaddress"
 	"github.com/iotexproject/iotex-core/trie"
 )
 
+const (
+	// defaultMaxVoters is the default maximum number of voters per candidate
+	defaultMaxVoters = 1000
+
+	// defaultMaxLeafSize is the default maximum size in bytes of a state leaf that is decoded
+	defaultMaxLeafSize = 1 << 20
+
+	// stateFormatChecksum is the format version of a State leaf carrying a checksum, it is not a valid first byte of a
+	// gob stream, which tells it apart from leaves written without checksum
+	stateFormatChecksum = 0x80
+	// stateFormatCompressed is the format version of a State leaf carrying a checksum and a compressed payload, see
+	// CompressLeavesOption
+	stateFormatCompressed = 0x81
+	// stateFormatNext is the first format version unknown to this code. Versions from it up to 0xf7 are reserved for
+	// later schemas, 0xf8 to 0xff start a plain gob stream longer than 127 bytes.
+	stateFormatNext = 0x82
+	// stateFormatReservedEnd is the end of the format versions reserved for later schemas
+	stateFormatReservedEnd = 0xf8
+	// stateHeaderLen is the length of the format version and the CRC-32 checksum
+	stateHeaderLen = 5
+)
+
 var (
 	stateFactoryKVNameSpace = "StateFactory"
 
+	// EmptyRootHash is the root hash of the state without any account
+	EmptyRootHash = trie.EmptyRoot
+
 	// ErrNotEnoughBalance is the error that the balance is not enough
 	ErrNotEnoughBalance = errors.New("not enough balance")
 
@@ -34,56 +69,485 @@ var (
 
 	// ErrFailedToUnmarshalState is the error that the state un-marshaling is failed
 	ErrFailedToUnmarshalState = errors.New("failed to unmarshal state")
+
+	// ErrLeafCorrupted is the error that the state leaf does not match its checksum
+	ErrLeafCorrupted = errors.New("state leaf corrupted")
+
+	// ErrLeafTooLarge is the error that the state leaf exceeds the maximum size to decode
+	ErrLeafTooLarge = errors.New("state leaf too large")
+
+	// ErrSchemaTooNew is the error that the state leaf was written with a newer schema than this code knows
+	ErrSchemaTooNew = errors.New("state schema too new")
+
+	// ErrFactoryPaused is the error that the state factory is paused and rejects mutations
+	ErrFactoryPaused = errors.New("state factory is paused")
+
+	// ErrClosed is the error that the state factory has been closed
+	ErrClosed = errors.New("state factory is closed")
+
+	// ErrReadOnlyStore is the error that the state factory is on a read-only KV store and rejects mutations
+	ErrReadOnlyStore = errors.New("state store is read-only")
+
+	// ErrNonceTooLow is the error that the nonce is lower than the account's next nonce
+	ErrNonceTooLow = errors.New("nonce too low")
+
+	// ErrNonceTooHigh is the error that the nonce is higher than the account's next nonce
+	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrInvalidAmount is the error that the transfer amount is negative
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// errNoSnapshots is the error that the trie cannot take snapshots, which walking it needs
+	errNoSnapshots = errors.New("the trie does not support snapshots")
+
+	// ErrNotSupported is the error that the state factory, or the trie under it, does not support the operation
+	ErrNotSupported = errors.New("operation not supported")
 )
 
 type (
+	// CommitStats is what a commit has persisted, only the nodes changed since last commit are written
+	CommitStats struct {
+		Leaves   int           // number of leaf nodes written
+		Nodes    int           // number of branch and extension nodes written
+		Bytes    int           // total bytes of the nodes written
+		Duration time.Duration // time spent to commit
+	}
+
+	// InsufficientBalanceError is the error that the balance is not enough to spend the amount
+	// Its cause is ErrNotEnoughBalance, so errors.Cause(err) == ErrNotEnoughBalance still holds.
+	InsufficientBalanceError struct {
+		Balance *big.Int
+		Amount  *big.Int
+	}
+
 	// State is the canonical representation of an account.
 	State struct {
-		Nonce        uint64
-		Balance      *big.Int
-		Address      *iotxaddress.Address
-		IsCandidate  bool
-		VotingWeight *big.Int
-		Voters       map[common.Hash32B]*big.Int
+		Nonce         uint64
+		Balance       *big.Int
+		LockedBalance *big.Int // self-stake moved out of Balance, not spendable until unlocked
+		Address       *iotxaddress.Address
+		IsCandidate   bool
+		VotingWeight  *big.Int
+		Voters        map[common.Hash32B]*big.Int
+		// RegistrationHeight is the block height the account registered as a candidate
+		RegistrationHeight uint64
+		// VotedWeight is the total weight the account has voted to candidates
+		VotedWeight *big.Int
+		// CodeHash is the hash of the code deployed to a contract account, zero for an externally owned account
+		CodeHash common.Hash32B
+		// VestingSchedule lists the heights at which parts of the balance unlock, see SetVesting
+		VestingSchedule []VestingPoint
+		// Assets are the balances of the assets other than the native coin, by ascending ID, see AddBalanceOf
+		Assets []AssetBalance
+		// Allowances are the amounts spenders may move from the balance with TransferFrom, by spender key, see Approve
+		Allowances map[common.Hash32B]*big.Int
+		// height is the block height the State is read at, it is not stored
+		height uint64
 	}
 
 	// StateFactory defines an interface for managing states
 	StateFactory interface {
 		CreateState(*iotxaddress.Address, uint64) (*State, error)
 		Balance(*iotxaddress.Address) (*big.Int, error)
+		BalanceDecimal(*iotxaddress.Address) (string, error)
+		AddBalance(*iotxaddress.Address, *big.Int) error
+		Burn(*iotxaddress.Address, *big.Int) error
 		UpdateStatesWithTransfer([]*trx.Tx) error
+		ApplyTransferTx(*iotxaddress.Address, *iotxaddress.Address, *big.Int, uint64) error
+		ApplySponsoredTx(*iotxaddress.Address, *iotxaddress.Address, *iotxaddress.Address, *big.Int, *big.Int, uint64) error
+		AuthorizedTransfer(*iotxaddress.Address, *iotxaddress.Address, *big.Int, uint64, []byte) error
 		SetNonce(*iotxaddress.Address, uint64) error
 		Nonce(*iotxaddress.Address) (uint64, error)
+		NonceAndBalance(*iotxaddress.Address) (uint64, *big.Int, error)
 		RootHash() common.Hash32B
+		Commit() (CommitStats, error)
+		CommitWithHeight(uint64) (CommitStats, error)
+		Compact() error
+		DeleteAll() error
+		CheckParams() error
+		RegisterName(string, *iotxaddress.Address) error
+		ResolveName(string) (*iotxaddress.Address, error)
+		Lock(*iotxaddress.Address, *big.Int) error
+		Unlock(*iotxaddress.Address, *big.Int) error
+		RegisterCandidate(*iotxaddress.Address, uint64) error
+		RankedCandidates() ([]CandidateInfo, error)
+		TallyVotes() (map[string]*big.Int, common.Hash32B, error)
+		RepairVotingWeights() (int, error)
+		Vote(*iotxaddress.Address, *iotxaddress.Address, *big.Int) error
+		ApplyVoteChanges([]VoteChange) error
+		AuditVotingStakes() ([]string, error)
+		DeleteState(*iotxaddress.Address) error
+		ResetAccount(*iotxaddress.Address) error
+		RotateKey(*iotxaddress.Address, *iotxaddress.Address) error
+		AccountCount() (uint64, error)
+		GetState(*iotxaddress.Address) (*State, error)
+		GetStateByKey(AccountKey) (*State, error)
+		SetCode(*iotxaddress.Address, []byte) error
+		Code(*iotxaddress.Address) ([]byte, error)
+		IsContract(*iotxaddress.Address) (bool, error)
+		ApplyBatch([]StateChange) error
+		SetHeight(uint64)
+		SetVesting(*iotxaddress.Address, []VestingPoint) error
+		Vested(*iotxaddress.Address, uint64) (*big.Int, error)
+		Commitment(*big.Int) (*StateCommitment, error)
+		SelfCheck() error
+		SetMeta(*iotxaddress.Address, []byte, []byte) error
+		GetMeta(*iotxaddress.Address, []byte) ([]byte, error)
+		Preload([]*iotxaddress.Address) error
+		ApplyOnce(common.Hash32B, func() error) error
+		Reserve(int)
+		Slash(*iotxaddress.Address, *big.Rat) error
+		Close() error
+		BalanceOf(*iotxaddress.Address, AssetID) (*big.Int, error)
+		AddBalanceOf(*iotxaddress.Address, AssetID, *big.Int) error
+		SubBalanceOf(*iotxaddress.Address, AssetID, *big.Int) error
+		SupplyDelta() (*big.Int, error)
+		EffectivePower(*iotxaddress.Address) (*big.Int, error)
+		ReconcileStakes() ([]StakeInconsistency, error)
+		InvalidateCache(*iotxaddress.Address) error
+		AddStateWithInit(*iotxaddress.Address, State) (*State, error)
+		ApplyTransferTxWithReceipt(*iotxaddress.Address, *iotxaddress.Address, *big.Int, uint64) (*TransferReceipt, error)
+		SweepDustVotes() (int, error)
+		Airdrop(*iotxaddress.Address, map[string]*big.Int) error
+		RecentRoots(int) ([]RootAtHeight, error)
+		InitGenesis([]GenesisAccount) (common.Hash32B, error)
+		EstimateAccess(*iotxaddress.Address) (int, error)
+		BalanceAtRoot(common.Hash32B, iotxaddress.Address) (*big.Int, error)
+		TopBalances(int) ([]*State, error)
+		AccountActivity(*iotxaddress.Address) (ActivityInfo, error)
+	}
+
+	// The optional features of a StateFactory are defined by the interfaces below, which callers type-assert. The
+	// StateFactory returned by NewStateFactory implements all of them, the virtual one none.
+
+	// Pauser is a StateFactory whose mutations can be paused, e.g. for maintenance
+	Pauser interface {
+		Pause()
+		Resume()
+	}
+
+	// Prover is a StateFactory proving the states of accounts to clients holding only the root
+	Prover interface {
+		ProofBundle(*iotxaddress.Address) (ProofBundle, error)
+		MultiProof([]iotxaddress.Address) (MultiProof, error)
+	}
+
+	// Exporter is a StateFactory writing snapshots of its state
+	Exporter interface {
+		ExportState(io.Writer) (common.Hash32B, error)
+	}
+
+	// Monitor is a StateFactory reporting its health, the changes it has pending and the writes of its last commit
+	Monitor interface {
+		Health() HealthStatus
+		PendingChanges() []AddressChange
+		LastCommitWrites() []KVWrite
+	}
+
+	// RandSourcer is a StateFactory drawing randomness from its root
+	RandSourcer interface {
+		RandSource() *rand.Rand
+	}
+
+	// Approver is a StateFactory letting account owners approve spenders, see Approve
+	Approver interface {
+		Approve(*iotxaddress.Address, *iotxaddress.Address, *big.Int) error
+		Allowance(*iotxaddress.Address, *iotxaddress.Address) (*big.Int, error)
+		TransferFrom(*iotxaddress.Address, *iotxaddress.Address, *iotxaddress.Address, *big.Int) error
 	}
 
 	// stateFactory implements StateFactory interface
 	stateFactory struct {
-		trie trie.Trie
+		height       uint64       // accessed atomically, first to keep it 64-bit aligned
+		mu           sync.RWMutex // guards paused, mutations hold it for reading and Commit for writing, see lockAccounts
+		paused       bool
+		closed       bool // set by Close, guarded by mu
+		closeTrie    bool // Close also closes the trie
+		readOnly     bool // the trie's KV store is read-only, see NewStateFactory
+		compress     bool // leaves are written compressed, see CompressLeavesOption
+		trie         trie.Trie
+		minSelfStake *big.Int
+		maxVoters    int
+		minVote      *big.Int // smallest weight a vote may cast, see MinVoteWeightOption
+		stakeWeight  *big.Rat // weight of the self-stake in the effective power, see SelfStakeWeightOption
+		queryLimits  QueryLimits
+		maxLeafSize  int
+		committed    bool // reads see the last committed root instead of the uncommitted changes
+		verifyAddr   bool // reads check the stored Address against the account key
+		verifyKeys   bool // mutations check the public keys they key accounts by, see VerifyPublicKeyOption
+		strictDecode bool // reads check the amounts of the decoded state, see StrictDecodeOption
+		format       DecimalFormat
+		tiers        []*big.Int // lower bounds of the balance tiers above the first, see BalanceTiersOption
+		meta         db.KVStore // local account metadata, outside the trie
+		lastHeight   uint64     // height of the last CommitWithHeight, guarded by mu
+		heightKnown  bool       // whether CommitWithHeight has committed a height since creation or DeleteAll
+		commitErr    error      // error of the last commit, nil if it succeeded, guarded by mu
+		log          Logger
+		appliedTxs   db.KVStore // hashes of the transactions applied by ApplyOnce
+		roots        db.KVStore // index of the recently committed roots, see RootIndexOption
+		rootWindow   int        // number of roots the index retains
+		balances     db.KVStore // index of the accounts by balance, see BalanceIndexOption
+		onceMu       sync.Mutex // serializes ApplyOnce
+		policy       MutationPolicy
+		chainID      uint32
+		burnAddress  *iotxaddress.Address
+		pending      map[string]AddressChange    // accounts changed since last commit, keyed by account key
+		applied      map[common.Hash32B]struct{} // hashes ApplyOnce recorded since last commit
+		unindexed    map[AccountKey]bool         // accounts a commit failed to rank in the balance index, guarded by mu
+		pendingMu    sync.Mutex                  // guards pending and applied
+		countMu      sync.Mutex                  // serializes updates of the account count
+		listMu       sync.Mutex                  // serializes updates of the candidate and voter lists
+		accountLocks [accountLockStripes]sync.Mutex
 	}
+
+	// Option sets an optional parameter of the state factory
+	Option func(*stateFactory)
 )
 
+// storedState is the gob payload of a leaf with a format version, State with its maps stored as slices sorted by key
+// gob writes the entries of a map in random order, so two encodings of the same account would differ, and with them
+// the root.
+type storedState struct {
+	Nonce              uint64
+	Balance            *big.Int
+	LockedBalance      *big.Int
+	Address            *iotxaddress.Address
+	IsCandidate        bool
+	VotingWeight       *big.Int
+	Voters             []keyedAmount
+	RegistrationHeight uint64
+	VotedWeight        *big.Int
+	CodeHash           common.Hash32B
+	VestingSchedule    []VestingPoint
+	Assets             []AssetBalance
+	Allowances         []keyedAmount
+}
+
+// keyedAmount is an entry of a map from a key hash to an amount, as stored in a leaf
+type keyedAmount struct {
+	Key    common.Hash32B
+	Amount *big.Int
+}
+
+func init() {
+	// gob numbers the types in the order it first meets them, registering the leaf types up front keeps their numbers,
+	// which are part of every leaf, the same whatever the process encodes first
+	gob.NewEncoder(ioutil.Discard).Encode(&storedState{})
+}
+
+// sortedAmounts returns the entries of the map sorted by key, nil if there are none
+func sortedAmounts(m map[common.Hash32B]*big.Int) []keyedAmount {
+	if len(m) == 0 {
+		return nil
+	}
+	entries := make([]keyedAmount, 0, len(m))
+	for key, amount := range m {
+		entries = append(entries, keyedAmount{Key: key, Amount: amount})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Key[:], entries[j].Key[:]) < 0
+	})
+	return entries
+}
+
+// amountMap returns the entries as a map, nil if there are none
+func amountMap(entries []keyedAmount) map[common.Hash32B]*big.Int {
+	if len(entries) == 0 {
+		return nil
+	}
+	m := make(map[common.Hash32B]*big.Int, len(entries))
+	for _, e := range entries {
+		m[e.Key] = e.Amount
+	}
+	return m
+}
+
+// stateToBytes serializes the State as the checksum format version, the CRC-32 of the payload and the gob payload
+// The payload is a storedState, so the leaf only depends on the account: the voters and allowances are written in key
+// order, and an empty map is written as no map.
 func stateToBytes(s *State) ([]byte, error) {
+	if err := checkInvariants(s); err != nil {
+		return nil, err
+	}
+	stored := storedState{
+		Nonce:              s.Nonce,
+		Balance:            s.Balance,
+		LockedBalance:      s.LockedBalance,
+		IsCandidate:        s.IsCandidate,
+		VotingWeight:       s.VotingWeight,
+		Voters:             sortedAmounts(s.Voters),
+		RegistrationHeight: s.RegistrationHeight,
+		VotedWeight:        s.VotedWeight,
+		CodeHash:           s.CodeHash,
+		VestingSchedule:    s.VestingSchedule,
+		Assets:             s.Assets,
+		Allowances:         sortedAmounts(s.Allowances),
+	}
+	if s.Address != nil {
+		// only the public part of the address is stored, so the leaf does not depend on how the address was built
+		stored.Address = &iotxaddress.Address{PublicKey: s.Address.PublicKey, RawAddress: s.Address.RawAddress}
+	}
 	var ss bytes.Buffer
+	ss.Write(make([]byte, stateHeaderLen))
 	e := gob.NewEncoder(&ss)
-	if err := e.Encode(s); err != nil {
+	if err := e.Encode(&stored); err != nil {
 		return nil, ErrFailedToMarshalState
 	}
-	return ss.Bytes(), nil
+	b := ss.Bytes()
+	b[0] = stateFormatChecksum
+	binary.BigEndian.PutUint32(b[1:stateHeaderLen], crc32.ChecksumIEEE(b[stateHeaderLen:]))
+	return b, nil
 }
 
-func bytesToState(ss []byte) (*State, error) {
-	var state State
+// bytesToState de-serializes the State, verifying the checksum if the leaf has one and decompressing it if compressed
+// Leaves written before checksums were added are a plain gob State, whose first byte is never a format version, the
+// others carry a storedState, or a gob State if written before the payload was one. An empty leaf or payload, e.g.
+// left by a partial write, is reported as ErrLeafCorrupted since the key is present. A leaf over maxSize bytes is
+// refused with ErrLeafTooLarge before decoding, bounding the time and memory a crafted leaf can cost.
+// A schema adding State fields must come with a new format version: gob drops the fields it does not know, so an old
+// node decoding such a leaf and writing it back would destroy them. A leaf with a format version this code does not
+// know is refused with ErrSchemaTooNew instead, whether it is read alone or on the way to a mutation.
+func bytesToState(ss []byte, maxSize int) (*State, error) {
+	if len(ss) > maxSize {
+		return nil, errors.Wrapf(ErrLeafTooLarge, "%d bytes, limit %d", len(ss), maxSize)
+	}
+	if len(ss) > 0 && ss[0] >= stateFormatNext && ss[0] < stateFormatReservedEnd {
+		return nil, errors.Wrapf(ErrSchemaTooNew, "format version %#x", ss[0])
+	}
+	versioned := len(ss) > 0 && (ss[0] == stateFormatChecksum || ss[0] == stateFormatCompressed)
+	if versioned {
+		if len(ss) < stateHeaderLen ||
+			binary.BigEndian.Uint32(ss[1:stateHeaderLen]) != crc32.ChecksumIEEE(ss[stateHeaderLen:]) {
+			return nil, ErrLeafCorrupted
+		}
+		if ss[0] == stateFormatCompressed {
+			var err error
+			if ss, err = decompressPayload(ss[stateHeaderLen:], maxSize); err != nil {
+				return nil, err
+			}
+		} else {
+			ss = ss[stateHeaderLen:]
+		}
+	}
+	if len(ss) == 0 {
+		return nil, ErrLeafCorrupted
+	}
 	e := gob.NewDecoder(bytes.NewBuffer(ss))
-	if err := e.Decode(&state); err != nil {
-		return nil, ErrFailedToUnmarshalState
+	if !versioned {
+		var state State
+		if err := e.Decode(&state); err != nil {
+			return nil, ErrFailedToUnmarshalState
+		}
+		return &state, nil
+	}
+	var stored storedState
+	if err := e.Decode(&stored); err != nil {
+		// gob refuses the map of Voters or Allowances of a State where storedState has a slice
+		var state State
+		if err := gob.NewDecoder(bytes.NewBuffer(ss)).Decode(&state); err != nil {
+			return nil, ErrFailedToUnmarshalState
+		}
+		return &state, nil
 	}
-	return &state, nil
+	return &State{
+		Nonce:              stored.Nonce,
+		Balance:            stored.Balance,
+		LockedBalance:      stored.LockedBalance,
+		Address:            stored.Address,
+		IsCandidate:        stored.IsCandidate,
+		VotingWeight:       stored.VotingWeight,
+		Voters:             amountMap(stored.Voters),
+		RegistrationHeight: stored.RegistrationHeight,
+		VotedWeight:        stored.VotedWeight,
+		CodeHash:           stored.CodeHash,
+		VestingSchedule:    stored.VestingSchedule,
+		Assets:             stored.Assets,
+		Allowances:         amountMap(stored.Allowances),
+	}, nil
+}
+
+func newInsufficientBalanceError(balance, amount *big.Int) *InsufficientBalanceError {
+	return &InsufficientBalanceError{Balance: new(big.Int).Set(balance), Amount: new(big.Int).Set(amount)}
+}
+
+// Shortfall returns how much more balance is needed to spend the amount
+func (e *InsufficientBalanceError) Shortfall() *big.Int {
+	return new(big.Int).Sub(e.Amount, e.Balance)
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	return fmt.Sprintf("%s: balance %s, amount %s, short of %s", ErrNotEnoughBalance, e.Balance, e.Amount, e.Shortfall())
+}
+
+// Cause returns ErrNotEnoughBalance
+func (e *InsufficientBalanceError) Cause() error {
+	return ErrNotEnoughBalance
+}
+
+// Unwrap returns ErrNotEnoughBalance
+func (e *InsufficientBalanceError) Unwrap() error {
+	return ErrNotEnoughBalance
 }
 
 // NewStateFactory creates a new stateFactory
-func NewStateFactory(trie trie.Trie) StateFactory {
-	return &stateFactory{trie: trie}
+// A trie on a read-only KV store, e.g. the store of a reporting replica, is detected here: reads work as usual, while
+// mutations and commits fail up front with ErrReadOnlyStore instead of deep in the trie. With RootIndexOption the last
+// committed height is read back from the index, see CommitWithHeight.
+func NewStateFactory(trie trie.Trie, opts ...Option) StateFactory {
+	sf := &stateFactory{trie: trie, minSelfStake: big.NewInt(0), maxVoters: defaultMaxVoters,
+		minVote: big.NewInt(0), maxLeafSize: defaultMaxLeafSize, policy: permissivePolicy{},
+		pending: make(map[string]AddressChange), log: nopLogger{}}
+	if r, ok := trie.(interface {
+		ReadOnly() bool
+	}); ok {
+		sf.readOnly = r.ReadOnly()
+	}
+	for _, opt := range opts {
+		opt(sf)
+	}
+	sf.restoreHeight()
+	return sf
+}
+
+// MinSelfStakeOption sets the minimum locked balance an account needs to be a candidate, default is 0
+func MinSelfStakeOption(stake *big.Int) Option {
+	return func(sf *stateFactory) {
+		sf.minSelfStake = new(big.Int).Set(stake)
+	}
+}
+
+// MaxVotersOption sets the maximum number of voters per candidate, default is 1000
+// The bound keeps a candidate's leaf, which holds all its voters, small and cheap to decode.
+func MaxVotersOption(max int) Option {
+	return func(sf *stateFactory) {
+		sf.maxVoters = max
+	}
+}
+
+// MinVoteWeightOption sets the smallest weight a vote may cast, default is 0
+// Together with MaxVotersOption it keeps dust votes from filling a candidate's voters, see SweepDustVotes for the
+// votes cast before the minimum was raised.
+func MinVoteWeightOption(min *big.Int) Option {
+	return func(sf *stateFactory) {
+		sf.minVote = new(big.Int).Set(min)
+	}
+}
+
+// MaxLeafSizeOption sets the maximum size in bytes of a state leaf the factory decodes, larger leaves are refused
+func MaxLeafSizeOption(max int) Option {
+	return func(sf *stateFactory) {
+		sf.maxLeafSize = max
+	}
+}
+
+// ReadCommittedOption makes Balance, Nonce, NonceAndBalance and GetState read the state as of the last commit
+// By default reads observe every mutation made since the last commit, as a validator simulating the pending block
+// needs. Callers that want confirmed state only, e.g. RPCs, can set this option on a factory sharing the same trie.
+// Mutations always apply on top of the uncommitted changes regardless. The trie must support reading committed entries.
+func ReadCommittedOption() Option {
+	return func(sf *stateFactory) {
+		sf.committed = true
+	}
 }
 
 // RootHash returns the hash of the root node of the trie
@@ -91,124 +555,641 @@ func (sf *stateFactory) RootHash() common.Hash32B {
 	return sf.trie.RootHash()
 }
 
+// Commit persists the state changes since last commit to DB in a batch
+// Only the leaves of the changed accounts and the nodes on their path to root are written. The committed root also
+// covers the hash of the governance parameters in effect. The transactions ApplyOnce recorded are persisted with it.
+// The changed accounts are ranked again in the balance index if BalanceIndexOption is set, a failure to index them is
+// logged and the next commit ranks them, the commit stands.
+func (sf *stateFactory) Commit() (CommitStats, error) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	return sf.commit()
+}
+
+// commit persists the state changes, the caller must hold sf.mu for writing
+func (sf *stateFactory) commit() (CommitStats, error) {
+	if sf.closed {
+		return CommitStats{}, ErrClosed
+	}
+	if sf.readOnly {
+		return CommitStats{}, ErrReadOnlyStore
+	}
+	start := time.Now()
+	if err := sf.putParams(); err != nil {
+		sf.commitErr = err
+		return CommitStats{}, err
+	}
+	stats, err := sf.trie.Commit(nil, nil)
+	sf.commitErr = err
+	if err != nil {
+		sf.log.Error("failed to commit", "error", err)
+		return CommitStats{}, err
+	}
+	if err := sf.persistApplied(); err != nil {
+		sf.log.Error("failed to record the applied transactions", "error", err)
+	}
+	if err := sf.indexBalances(); err != nil {
+		sf.log.Error("failed to index balances", "error", err)
+	}
+	sf.resetPending()
+	return CommitStats{Leaves: stats.Leaves, Nodes: stats.Nodes, Bytes: stats.Bytes, Duration: time.Since(start)}, nil
+}
+
+// Compact asks the underlying KV store to reclaim the space of trie nodes deleted by commits
+// It is a no-op returning nil if the trie or its KV store does not support compaction.
+func (sf *stateFactory) Compact() error {
+	if c, ok := sf.trie.(db.Compacter); ok {
+		return c.Compact()
+	}
+	return nil
+}
+
+// DeleteAll wipes the state, removing every trie node from the KV store so the factory is empty again
+// Other namespaces of a shared KV store are untouched. It fails if the trie or its KV store cannot delete its nodes.
+// The last height committed by CommitWithHeight, the roots indexed for RecentRoots, the balance index and the
+// transactions ApplyOnce recorded since the last commit are forgotten, so the chain can be replayed from the start.
+func (sf *stateFactory) DeleteAll() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	d, ok := sf.trie.(interface {
+		DeleteAll() error
+	})
+	if !ok {
+		return errors.New("the trie does not support deleting all nodes")
+	}
+	if err := d.DeleteAll(); err != nil {
+		return err
+	}
+	sf.resetPending()
+	sf.pendingMu.Lock()
+	sf.applied = nil
+	sf.pendingMu.Unlock()
+	sf.heightKnown = false
+	if err := sf.deleteBalanceIndex(); err != nil {
+		return err
+	}
+	if sf.roots != nil {
+		return sf.roots.Delete(rootIndexKVNameSpace, recentRootsKey)
+	}
+	return nil
+}
+
+// Pause makes the factory reject all mutations with ErrFactoryPaused, reads keep working
+func (sf *stateFactory) Pause() {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	sf.paused = true
+	sf.log.Info("state factory paused")
+}
+
+// Resume lifts a previous Pause
+func (sf *stateFactory) Resume() {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	sf.paused = false
+	sf.log.Info("state factory resumed")
+}
+
 // CreateState adds a new State with initial balance to the factory
+// If the account already exists it is left untouched and its State is returned, so a repeated or concurrent call for
+// the same address never resets the funds credited to it in the meantime.
 func (sf *stateFactory) CreateState(addr *iotxaddress.Address, init uint64) (*State, error) {
-	balance := big.NewInt(0)
-	balance.SetUint64(init)
-	s := State{Address: addr, Balance: balance}
-	mstate, err := stateToBytes(&s)
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return nil, err
+	}
+	return sf.createState(addr, init)
+}
+
+// createState adds the State as CreateState does, the caller must hold sf.mu
+func (sf *stateFactory) createState(addr *iotxaddress.Address, init uint64) (*State, error) {
+	if err := sf.checkPublicKeys(addr); err != nil {
+		return nil, err
+	}
+	key := iotxaddress.HashPubKey(addr.PublicKey)
+	unlock := sf.lockAccounts(key)
+	defer unlock()
+	state, err := sf.getState(addr)
+	if err == nil {
+		return state, nil
+	}
+	if err != ErrAccountNotExist {
+		return nil, err
+	}
+	s := newState(addr, init)
+	mstate, err := sf.encodeState(s)
 	if err != nil {
 		return nil, err
 	}
-	if err := sf.trie.Upsert(iotxaddress.HashPubKey(addr.PublicKey), mstate); err != nil {
+	if err := sf.trie.Upsert(key, mstate); err != nil {
+		return nil, err
+	}
+	sf.touch(addr, ChangeCreated)
+	if err := sf.addAccountCount(1); err != nil {
 		return nil, err
 	}
-	return &s, nil
+	return s, nil
 }
 
 // Balance returns balance.
 func (sf *stateFactory) Balance(addr *iotxaddress.Address) (*big.Int, error) {
-	state, err := sf.getState(addr)
+	state, err := sf.readState(addr)
 	if err != nil {
 		return nil, err
 	}
 	return state.Balance, nil
 }
 
+// AddBalance adds the amount to the balance of the given address
+func (sf *stateFactory) AddBalance(addr *iotxaddress.Address, amount *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.policy.AllowBalanceChange(addr, amount); err != nil {
+		return err
+	}
+	return sf.updateState(addr, func(state *State) error {
+		return state.AddBalance(amount)
+	})
+}
+
 // UpdateStatesWithTransfer updates a State from the given value transfer
+// The changes are committed to the trie at once, so like Commit it excludes every other mutation meanwhile. The
+// transfers are applied in order, an account taking part in several of them sees the earlier ones. Nothing is staged
+// unless all of them succeed, the states are then written together and committed as Commit does.
 func (sf *stateFactory) UpdateStatesWithTransfer(txs []*trx.Tx) error {
-	var ss []byte
-	transferK := [][]byte{}
-	transferV := [][]byte{}
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	created := make(map[AccountKey]bool)
 	for _, tx := range txs {
 		// check sender
-		sender, err := sf.getState(&iotxaddress.Address{PublicKey: tx.SenderPublicKey})
-		if err != nil {
+		from := &iotxaddress.Address{PublicKey: tx.SenderPublicKey}
+		if err := sf.policy.AllowTransfer(from, tx.Recipient, tx.Amount); err != nil {
 			return err
 		}
+		skey := AccountKeyOf(from)
+		sender, ok := states[skey]
+		if !ok {
+			var err error
+			if sender, err = sf.getState(from); err != nil {
+				return err
+			}
+			states[skey], addrs[skey] = sender, from
+		}
 		if tx.Amount.Cmp(sender.Balance) == 1 {
-			return ErrNotEnoughBalance
+			return newInsufficientBalanceError(sender.Balance, tx.Amount)
 		}
 		// check recipient
-		receiver, err := sf.getState(tx.Recipient)
-		switch {
-		case err == ErrAccountNotExist:
-			if _, e := sf.CreateState(tx.Recipient, 0); e != nil {
-				return e
+		rkey := AccountKeyOf(tx.Recipient)
+		receiver, ok := states[rkey]
+		if !ok {
+			var err error
+			receiver, err = sf.getState(tx.Recipient)
+			switch {
+			case err == ErrAccountNotExist:
+				if err := sf.checkPublicKeys(tx.Recipient); err != nil {
+					return err
+				}
+				receiver = newState(tx.Recipient, 0)
+				created[rkey] = true
+			case err != nil:
+				return err
 			}
-		case err != nil:
-			return err
+			states[rkey], addrs[rkey] = receiver, tx.Recipient
 		}
 		// update sender balance
 		if err := sender.SubBalance(tx.Amount); err != nil {
 			return err
 		}
-		if ss, err = stateToBytes(sender); err != nil {
-			return err
-		}
-		transferK = append(transferK, iotxaddress.HashPubKey(tx.SenderPublicKey))
-		transferV = append(transferV, ss)
 		// update recipient balance
 		if err := receiver.AddBalance(tx.Amount); err != nil {
 			return err
 		}
-		if ss, err = stateToBytes(receiver); err != nil {
+	}
+	if err := sf.writeStates(states, addrs, created); err != nil {
+		return err
+	}
+	_, err := sf.commit()
+	return err
+}
+
+// ApplyTransferTx moves the amount from the sender to the recipient and increments the sender's nonce
+// The expected nonce must equal the sender's current nonce. The sender is read once and all checks happen before any
+// write, so on a nonce or balance error nothing is changed. The factory cannot be paused while the transfer is applied.
+// A recipient without an account gets one created. The two accounts are locked, so transfers between other accounts
+// can be applied concurrently.
+func (sf *stateFactory) ApplyTransferTx(sender, recipient *iotxaddress.Address, amount *big.Int,
+	expectedNonce uint64) error {
+	return sf.applyTransferTx(sender, recipient, amount, expectedNonce, nil)
+}
+
+// applyTransferTx applies the transfer as ApplyTransferTx does, filling the receipt if it is not nil
+func (sf *stateFactory) applyTransferTx(sender, recipient *iotxaddress.Address, amount *big.Int, expectedNonce uint64,
+	receipt *TransferReceipt) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(sender, recipient); err != nil {
+		return err
+	}
+	senderKey := iotxaddress.HashPubKey(sender.PublicKey)
+	recipientKey := iotxaddress.HashPubKey(recipient.PublicKey)
+	unlock := sf.lockAccounts(senderKey, recipientKey)
+	defer unlock()
+	if amount.Sign() < 0 {
+		return ErrInvalidAmount
+	}
+	if err := sf.policy.AllowTransfer(sender, recipient, amount); err != nil {
+		return err
+	}
+	from, err := sf.getState(sender)
+	if err != nil {
+		return err
+	}
+	switch {
+	case expectedNonce < from.Nonce:
+		return ErrNonceTooLow
+	case expectedNonce > from.Nonce:
+		return ErrNonceTooHigh
+	}
+	if err := from.SubBalance(amount); err != nil {
+		return err
+	}
+	from.Nonce = expectedNonce + 1
+	if bytes.Equal(senderKey, recipientKey) {
+		// transfer to self only bumps the nonce
+		if err := from.AddBalance(amount); err != nil {
 			return err
 		}
-		transferK = append(transferK, iotxaddress.HashPubKey(tx.Recipient.PublicKey))
-		transferV = append(transferV, ss)
+		ss, err := sf.encodeState(from)
+		if err != nil {
+			return err
+		}
+		if err := sf.trie.Upsert(senderKey, ss); err != nil {
+			return err
+		}
+		sf.touch(sender, ChangeUpdated)
+		sf.fillReceipt(receipt, from, from)
+		return nil
 	}
-	// commit the state changes to Trie in a batch
-	return sf.trie.Commit(transferK, transferV)
+	to, err := sf.getState(recipient)
+	create := err == ErrAccountNotExist
+	switch {
+	case create:
+		to = newState(recipient, 0)
+	case err != nil:
+		return err
+	}
+	var count uint64
+	if create {
+		sf.countMu.Lock()
+		defer sf.countMu.Unlock()
+		if count, err = sf.accountCount(); err != nil {
+			return err
+		}
+	}
+	if err := to.AddBalance(amount); err != nil {
+		return err
+	}
+	fromBytes, err := sf.encodeState(from)
+	if err != nil {
+		return err
+	}
+	toBytes, err := sf.encodeState(to)
+	if err != nil {
+		return err
+	}
+	if err := sf.trie.Upsert(senderKey, fromBytes); err != nil {
+		return err
+	}
+	if err := sf.trie.Upsert(recipientKey, toBytes); err != nil {
+		return err
+	}
+	sf.touch(sender, ChangeUpdated)
+	if create {
+		sf.touch(recipient, ChangeCreated)
+	} else {
+		sf.touch(recipient, ChangeUpdated)
+	}
+	if create {
+		if err := sf.putAccountCount(count + 1); err != nil {
+			return err
+		}
+	}
+	sf.fillReceipt(receipt, from, to)
+	return nil
 }
 
 // Nonce returns the nonce for the given address
 func (sf *stateFactory) Nonce(addr *iotxaddress.Address) (uint64, error) {
-	state, err := sf.getState(addr)
+	state, err := sf.readState(addr)
 	if err != nil {
 		return 0, err
 	}
 	return state.Nonce, nil
 }
 
+// NonceAndBalance returns both the nonce and balance for the given address with a single read of the state
+func (sf *stateFactory) NonceAndBalance(addr *iotxaddress.Address) (uint64, *big.Int, error) {
+	state, err := sf.readState(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	return state.Nonce, state.Balance, nil
+}
+
 // SetNonce sets nonce to a given value
 func (sf *stateFactory) SetNonce(addr *iotxaddress.Address, value uint64) error {
-	state, err := sf.getState(addr)
-	if err != nil {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
 		return err
 	}
-	state.Nonce = value
+	return sf.updateState(addr, func(state *State) error {
+		state.Nonce = value
+		return nil
+	})
+}
+
+// GetState returns the State of the account, use State.IsContract to tell contract accounts from externally owned ones
+func (sf *stateFactory) GetState(addr *iotxaddress.Address) (*State, error) {
+	return sf.readState(addr)
+}
+
+// checkOpen returns ErrClosed if the factory has been closed
+func (sf *stateFactory) checkOpen() error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if sf.closed {
+		return ErrClosed
+	}
+	return nil
+}
 
-	mstate, err := stateToBytes(state)
+// writable returns an error if the factory currently rejects mutations, the caller must hold sf.mu
+func (sf *stateFactory) writable() error {
+	switch {
+	case sf.closed:
+		return ErrClosed
+	case sf.readOnly:
+		return ErrReadOnlyStore
+	case sf.paused:
+		return ErrFactoryPaused
+	}
+	return nil
+}
+
+// getState pulls an existing State, including the changes since the last commit
+func (sf *stateFactory) getState(addr *iotxaddress.Address) (*State, error) {
+	return sf.loadState(addr, false)
+}
+
+// readState pulls an existing State for Balance, Nonce, NonceAndBalance and GetState, as of the last commit if the
+// factory reads committed state, mutations must use getState instead
+// The read takes the account lock, so it is ordered before or after a concurrent mutation of the account, never in
+// the middle of one.
+func (sf *stateFactory) readState(addr *iotxaddress.Address) (*State, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	unlock := sf.lockAccounts(iotxaddress.HashPubKey(addr.PublicKey))
+	defer unlock()
+	return sf.loadState(addr, sf.committed)
+}
+
+// loadState pulls an existing State, as of the last commit if committed is true
+func (sf *stateFactory) loadState(addr *iotxaddress.Address, committed bool) (*State, error) {
+	return sf.loadStateByKey(AccountKeyOf(addr), committed)
+}
+
+// loadStateByKey pulls an existing State by its account key, as of the last commit if committed is true
+func (sf *stateFactory) loadStateByKey(key AccountKey, committed bool) (*State, error) {
+	var state *State
+	var err error
+	if committed {
+		state, err = getCommittedStateByKey(sf.trie, key, sf.maxLeafSize)
+	} else {
+		state, err = getStateByKey(sf.trie, key, sf.maxLeafSize)
+	}
+	if err == nil {
+		err = sf.verifyState(state, key)
+	}
 	if err != nil {
+		return nil, sf.checkLeaf(key.Bytes(), err)
+	}
+	state.height = sf.currentHeight()
+	return state, nil
+}
+
+// getStateByKey pulls an existing State from the trie by its account key
+func getStateByKey(tr trie.Trie, key AccountKey, maxLeafSize int) (*State, error) {
+	mstate, err := tr.Get(key.Bytes())
+	return decodeLeaf(mstate, err, maxLeafSize)
+}
+
+// getCommittedStateByKey pulls an existing State from the trie by its account key as of the last commit
+func getCommittedStateByKey(tr trie.Trie, key AccountKey, maxLeafSize int) (*State, error) {
+	c, ok := tr.(interface {
+		GetCommitted([]byte) ([]byte, error)
+	})
+	if !ok {
+		return nil, errors.New("the trie does not support reading committed entries")
+	}
+	mstate, err := c.GetCommitted(key.Bytes())
+	return decodeLeaf(mstate, err, maxLeafSize)
+}
+
+// decodeLeaf de-serializes the State read from the trie, mapping a missing entry to ErrAccountNotExist
+func decodeLeaf(mstate []byte, err error, maxLeafSize int) (*State, error) {
+	if errors.Cause(err) == trie.ErrNotExist {
+		return nil, ErrAccountNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytesToState(mstate, maxLeafSize)
+}
+
+// updateState pulls an existing State, applies the mutation in place and writes it back
+// the trie is left untouched if the mutation does not change the encoded State
+func (sf *stateFactory) updateState(addr *iotxaddress.Address, mutate func(*State) error) error {
+	if err := sf.checkPublicKeys(addr); err != nil {
 		return err
 	}
 	key := iotxaddress.HashPubKey(addr.PublicKey)
-	if err := sf.trie.Upsert(key, mstate); err != nil {
+	unlock := sf.lockAccounts(key)
+	defer unlock()
+	mstate, err := sf.trie.Get(key)
+	if errors.Cause(err) == trie.ErrNotExist {
+		return ErrAccountNotExist
+	}
+	if err != nil {
+		return err
+	}
+	state, err := bytesToState(mstate, sf.maxLeafSize)
+	if err != nil {
+		return sf.checkLeaf(key, err)
+	}
+	state.height = sf.currentHeight()
+	if err := mutate(state); err != nil {
+		return err
+	}
+	ss, err := sf.encodeState(state)
+	if err != nil {
 		return err
 	}
+	if bytes.Equal(ss, mstate) {
+		// no-op update, avoid dirtying the trie, the encoding only depends on the State, see stateToBytes
+		return nil
+	}
+	if err := sf.trie.Upsert(key, ss); err != nil {
+		return err
+	}
+	sf.touch(addr, ChangeUpdated)
 	return nil
 }
 
-// getState pulls an existing State
-func (sf *stateFactory) getState(addr *iotxaddress.Address) (*State, error) {
-	mstate, err := sf.trie.Get(iotxaddress.HashPubKey(addr.PublicKey))
+// writeStates writes the States changed together in memory, in key order, the caller holds their account locks
+// Every State is encoded before the first write, so a refused State leaves the trie untouched. The accounts in created
+// did not exist before and are counted.
+func (sf *stateFactory) writeStates(states map[AccountKey]*State, addrs map[AccountKey]*iotxaddress.Address,
+	created map[AccountKey]bool) error {
+	return sf.writeChanges(states, addrs, created, nil)
+}
+
+// writeChanges writes the States as writeStates does, then deletes the accounts in deleted, whose locks the caller
+// holds as well
+func (sf *stateFactory) writeChanges(states map[AccountKey]*State, addrs map[AccountKey]*iotxaddress.Address,
+	created map[AccountKey]bool, deleted map[AccountKey]*iotxaddress.Address) error {
+	sorted := make([]AccountKey, 0, len(states))
+	encoded := make(map[AccountKey][]byte)
+	for key, state := range states {
+		ss, err := sf.encodeState(state)
+		if err != nil {
+			return err
+		}
+		sorted = append(sorted, key)
+		encoded[key] = ss
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	for _, key := range sorted {
+		if err := sf.trie.Upsert(key.Bytes(), encoded[key]); err != nil {
+			return err
+		}
+		if created[key] {
+			sf.touch(addrs[key], ChangeCreated)
+		} else {
+			sf.touch(addrs[key], ChangeUpdated)
+		}
+	}
+	for key, addr := range deleted {
+		if err := sf.trie.Delete(key.Bytes()); err != nil {
+			return err
+		}
+		sf.touch(addr, ChangeDeleted)
+	}
+	if delta := len(created) - len(deleted); delta != 0 {
+		return sf.addAccountCount(int64(delta))
+	}
+	return nil
+}
+
+// getAddressList returns the list of addresses stored in the trie under the key
+func (sf *stateFactory) getAddressList(key []byte) ([]*iotxaddress.Address, error) {
+	value, err := sf.trie.Get(key)
 	if errors.Cause(err) == trie.ErrNotExist {
-		return nil, ErrAccountNotExist
+		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	state, err := bytesToState(mstate)
+	if len(value) == 0 {
+		return nil, sf.checkLeaf(key, errors.Wrap(ErrLeafCorrupted, "empty address list"))
+	}
+	var addrs []*iotxaddress.Address
+	if err := gob.NewDecoder(bytes.NewBuffer(value)).Decode(&addrs); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal address list")
+	}
+	return addrs, nil
+}
+
+// putAddressList stores the list sorted by account key, only the public part of addresses is kept
+func (sf *stateFactory) putAddressList(key []byte, addrs []*iotxaddress.Address) error {
+	list := make([]*iotxaddress.Address, len(addrs))
+	for i, addr := range addrs {
+		list[i] = &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(iotxaddress.HashPubKey(list[i].PublicKey), iotxaddress.HashPubKey(list[j].PublicKey)) < 0
+	})
+	var ss bytes.Buffer
+	if err := gob.NewEncoder(&ss).Encode(list); err != nil {
+		return errors.Wrap(err, "failed to marshal address list")
+	}
+	return sf.trie.Upsert(key, ss.Bytes())
+}
+
+// addToAddressList adds the address to the list if not there yet
+func (sf *stateFactory) addToAddressList(key []byte, addr *iotxaddress.Address) error {
+	sf.listMu.Lock()
+	defer sf.listMu.Unlock()
+	addrs, err := sf.getAddressList(key)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return state, nil
+	for _, a := range addrs {
+		if bytes.Equal(a.PublicKey, addr.PublicKey) {
+			return nil
+		}
+	}
+	return sf.putAddressList(key, append(addrs, addr))
 }
 
-//======================================
+// removeFromAddressList removes the address from the list
+func (sf *stateFactory) removeFromAddressList(key []byte, addr *iotxaddress.Address) error {
+	sf.listMu.Lock()
+	defer sf.listMu.Unlock()
+	addrs, err := sf.getAddressList(key)
+	if err != nil {
+		return err
+	}
+	for i, a := range addrs {
+		if bytes.Equal(a.PublicKey, addr.PublicKey) {
+			return sf.putAddressList(key, append(addrs[:i], addrs[i+1:]...))
+		}
+	}
+	return nil
+}
+
+// ======================================
 // functions for State
-//======================================
+// ======================================
+// newState creates a State with the initial balance, every big.Int field is freshly allocated so no two States ever
+// share a pointer
+func newState(addr *iotxaddress.Address, init uint64) *State {
+	return &State{
+		Address:       addr,
+		Balance:       new(big.Int).SetUint64(init),
+		LockedBalance: big.NewInt(0),
+		VotingWeight:  big.NewInt(0),
+	}
+}
+
 func (st *State) AddBalance(amount *big.Int) error {
 	st.Balance.Add(st.Balance, amount)
 	return nil
@@ -217,21 +1198,27 @@ func (st *State) AddBalance(amount *big.Int) error {
 func (st *State) SubBalance(amount *big.Int) error {
 	// make sure there's enough fund to spend
 	if amount.Cmp(st.Balance) == 1 {
-		return ErrNotEnoughBalance
+		return newInsufficientBalanceError(st.Balance, amount)
+	}
+	// the part of the balance still vesting cannot be spent
+	if new(big.Int).Sub(st.Balance, amount).Cmp(st.unvested(st.height)) < 0 {
+		return ErrFundsVesting
 	}
 	st.Balance.Sub(st.Balance, amount)
 	return nil
 }
 
-//======================================
+// ======================================
 // functions for VirtualStateFactory
-//======================================
+// ======================================
 const hashedAddressLen = 20
 
 type hashedAddress [hashedAddressLen]byte
 
 // VirtualStateFactory implements StateFactory interface, tracks changes in a map but never commits to trie/db
+// It supports the reads and nonce changes the actpool needs, the other operations fail with ErrNotSupported.
 type virtualStateFactory struct {
+	height  uint64 // accessed atomically, first to keep it 64-bit aligned, see SetHeight
 	changes map[hashedAddress]*State
 	mu      sync.Mutex
 	trie    trie.Trie
@@ -243,17 +1230,15 @@ func NewVirtualStateFactory(trie trie.Trie) StateFactory {
 }
 
 func (vs *virtualStateFactory) CreateState(addr *iotxaddress.Address, init uint64) (*State, error) {
-	balance := big.NewInt(0)
-	balance.SetUint64(init)
-	s := State{Address: addr, Balance: balance}
-	mstate, err := stateToBytes(&s)
+	s := newState(addr, init)
+	mstate, err := stateToBytes(s)
 	if err != nil {
 		return nil, err
 	}
 	if err := vs.trie.Upsert(iotxaddress.HashPubKey(addr.PublicKey), mstate); err != nil {
 		return nil, err
 	}
-	return &s, nil
+	return s, nil
 }
 
 func (vs *virtualStateFactory) Balance(addr *iotxaddress.Address) (*big.Int, error) {
@@ -284,6 +1269,46 @@ func (vs *virtualStateFactory) Nonce(addr *iotxaddress.Address) (uint64, error)
 	return vs.changes[key].Nonce, nil
 }
 
+// NonceAndBalance returns the nonce and balance if the account exists
+func (vs *virtualStateFactory) NonceAndBalance(addr *iotxaddress.Address) (uint64, *big.Int, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	var key hashedAddress
+	k := iotxaddress.HashPubKey(addr.PublicKey)
+	copy(key[:], k[:hashedAddressLen])
+	if val, ok := vs.changes[key]; ok {
+		return val.Nonce, val.Balance, nil
+	}
+
+	state, err := vs.getState(addr)
+	if err != nil {
+		return 0, nil, err
+	}
+	vs.changes[key] = state
+	return state.Nonce, state.Balance, nil
+}
+
+// AddBalance adds the amount to the balance if the account exists
+func (vs *virtualStateFactory) AddBalance(addr *iotxaddress.Address, amount *big.Int) error {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	var key hashedAddress
+	k := iotxaddress.HashPubKey(addr.PublicKey)
+	copy(key[:], k[:hashedAddressLen])
+	if _, ok := vs.changes[key]; ok {
+		return vs.changes[key].AddBalance(amount)
+	}
+
+	state, err := vs.getState(addr)
+	if err != nil {
+		return err
+	}
+	vs.changes[key] = state
+	return vs.changes[key].AddBalance(amount)
+}
+
 // SetNonce returns the nonce if the account exists
 func (vs *virtualStateFactory) SetNonce(addr *iotxaddress.Address, value uint64) error {
 	vs.mu.Lock()
@@ -311,22 +1336,276 @@ func (vs *virtualStateFactory) UpdateStatesWithTransfer([]*trx.Tx) error {
 	return nil
 }
 
+func (vs *virtualStateFactory) ApplyTransferTx(*iotxaddress.Address, *iotxaddress.Address, *big.Int, uint64) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ApplySponsoredTx(*iotxaddress.Address, *iotxaddress.Address, *iotxaddress.Address,
+	*big.Int, *big.Int, uint64) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) AuthorizedTransfer(*iotxaddress.Address, *iotxaddress.Address, *big.Int, uint64,
+	[]byte) error {
+	return ErrNotSupported
+}
+
 func (vs *virtualStateFactory) RootHash() common.Hash32B {
 	// TODO
 	return [32]byte{}
 }
 
-func (vs *virtualStateFactory) getState(addr *iotxaddress.Address) (*State, error) {
-	mstate, err := vs.trie.Get(iotxaddress.HashPubKey(addr.PublicKey))
-	if errors.Cause(err) == trie.ErrNotExist {
-		return nil, ErrAccountNotExist
+// Commit is a no-op since virtual changes are never committed
+func (vs *virtualStateFactory) Commit() (CommitStats, error) {
+	return CommitStats{}, nil
+}
+
+func (vs *virtualStateFactory) CommitWithHeight(uint64) (CommitStats, error) {
+	return CommitStats{}, nil
+}
+
+func (vs *virtualStateFactory) Compact() error {
+	return nil
+}
+
+func (vs *virtualStateFactory) DeleteAll() error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) CheckParams() error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RegisterName(string, *iotxaddress.Address) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ResolveName(name string) (*iotxaddress.Address, error) {
+	return resolveName(vs.trie, name)
+}
+
+func (vs *virtualStateFactory) Lock(*iotxaddress.Address, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Unlock(*iotxaddress.Address, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RegisterCandidate(*iotxaddress.Address, uint64) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RankedCandidates() ([]CandidateInfo, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) TallyVotes() (map[string]*big.Int, common.Hash32B, error) {
+	return nil, common.ZeroHash32B, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RepairVotingWeights() (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Vote(*iotxaddress.Address, *iotxaddress.Address, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ApplyVoteChanges([]VoteChange) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) AuditVotingStakes() ([]string, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) DeleteState(*iotxaddress.Address) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ResetAccount(*iotxaddress.Address) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RotateKey(*iotxaddress.Address, *iotxaddress.Address) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) AccountCount() (uint64, error) {
+	return 0, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) GetState(addr *iotxaddress.Address) (*State, error) {
+	return vs.getState(addr)
+}
+
+func (vs *virtualStateFactory) GetStateByKey(key AccountKey) (*State, error) {
+	return getStateByKey(vs.trie, key, defaultMaxLeafSize)
+}
+
+func (vs *virtualStateFactory) SetCode(*iotxaddress.Address, []byte) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Code(*iotxaddress.Address) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+// SetHeight sets the height the States are read at, as the factory does
+func (vs *virtualStateFactory) SetHeight(height uint64) {
+	atomic.StoreUint64(&vs.height, height)
+}
+
+func (vs *virtualStateFactory) SetVesting(*iotxaddress.Address, []VestingPoint) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Vested(*iotxaddress.Address, uint64) (*big.Int, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Commitment(*big.Int) (*StateCommitment, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Burn(*iotxaddress.Address, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) BalanceDecimal(*iotxaddress.Address) (string, error) {
+	return "", ErrNotSupported
+}
+
+func (vs *virtualStateFactory) SelfCheck() error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) SetMeta(*iotxaddress.Address, []byte, []byte) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) GetMeta(*iotxaddress.Address, []byte) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Preload([]*iotxaddress.Address) error {
+	// no cache to warm
+	return nil
+}
+
+func (vs *virtualStateFactory) ApplyOnce(common.Hash32B, func() error) error {
+	return ErrNotSupported
+}
+
+// Reserve pre-sizes the virtual changes if there are none yet
+func (vs *virtualStateFactory) Reserve(n int) {
+	if n <= 0 {
+		return
 	}
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	if len(vs.changes) == 0 {
+		vs.changes = make(map[hashedAddress]*State, n)
+	}
+}
+
+func (vs *virtualStateFactory) Slash(*iotxaddress.Address, *big.Rat) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Close() error {
+	// nothing is held open
+	return nil
+}
+
+func (vs *virtualStateFactory) BalanceOf(*iotxaddress.Address, AssetID) (*big.Int, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) AddBalanceOf(*iotxaddress.Address, AssetID, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) SubBalanceOf(*iotxaddress.Address, AssetID, *big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) SupplyDelta() (*big.Int, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) EffectivePower(*iotxaddress.Address) (*big.Int, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ReconcileStakes() ([]StakeInconsistency, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) InvalidateCache(*iotxaddress.Address) error {
+	// no cache to invalidate
+	return nil
+}
+
+func (vs *virtualStateFactory) AddStateWithInit(*iotxaddress.Address, State) (*State, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ApplyTransferTxWithReceipt(*iotxaddress.Address, *iotxaddress.Address, *big.Int,
+	uint64) (*TransferReceipt, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) SweepDustVotes() (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) Airdrop(*iotxaddress.Address, map[string]*big.Int) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) RecentRoots(int) ([]RootAtHeight, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) InitGenesis([]GenesisAccount) (common.Hash32B, error) {
+	return common.ZeroHash32B, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) EstimateAccess(*iotxaddress.Address) (int, error) {
+	return 0, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) BalanceAtRoot(common.Hash32B, iotxaddress.Address) (*big.Int, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) TopBalances(int) ([]*State, error) {
+	return nil, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) AccountActivity(*iotxaddress.Address) (ActivityInfo, error) {
+	return ActivityInfo{}, ErrNotSupported
+}
+
+func (vs *virtualStateFactory) ApplyBatch([]StateChange) error {
+	return ErrNotSupported
+}
+
+func (vs *virtualStateFactory) IsContract(addr *iotxaddress.Address) (bool, error) {
+	state, err := vs.getState(addr)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	state, err := bytesToState(mstate)
+	return state.IsContract(), nil
+}
+
+func (vs *virtualStateFactory) getState(addr *iotxaddress.Address) (*State, error) {
+	state, err := getStateByKey(vs.trie, AccountKeyOf(addr), defaultMaxLeafSize)
 	if err != nil {
 		return nil, err
 	}
+	state.height = atomic.LoadUint64(&vs.height)
 	return state, nil
 }
@@ -7,13 +7,22 @@
 package statefactory
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"hash/crc32"
 	"math/big"
+	"os"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 
+	trx "github.com/iotexproject/iotex-core/blockchain/trx"
 	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/common/utils"
+	"github.com/iotexproject/iotex-core/db"
 	"github.com/iotexproject/iotex-core/iotxaddress"
 	"github.com/iotexproject/iotex-core/test/mock/mock_trie"
 	"github.com/iotexproject/iotex-core/trie"
@@ -26,12 +35,60 @@ func TestEncodeDecode(t *testing.T) {
 	ss, _ := stateToBytes(&State{Address: addr, Nonce: 0x10})
 	assert.NotEmpty(t, ss)
 
-	state, _ := bytesToState(ss)
+	state, _ := bytesToState(ss, defaultMaxLeafSize)
 	assert.Equal(t, addr.RawAddress, state.Address.RawAddress)
 	assert.Equal(t, addr.PublicKey, state.Address.PublicKey)
 	assert.Equal(t, uint64(0x10), state.Nonce)
 }
 
+func TestLeafChecksum(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	s := newState(&iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}, 100)
+	ss, err := stateToBytes(s)
+	assert.Nil(t, err)
+
+	// flipping any byte is detected
+	for i := range ss {
+		corrupted := make([]byte, len(ss))
+		copy(corrupted, ss)
+		corrupted[i] ^= 0x01
+		state, err := bytesToState(corrupted, defaultMaxLeafSize)
+		assert.Nil(t, state)
+		assert.NotNil(t, err)
+		if i > 0 {
+			assert.Equal(t, ErrLeafCorrupted, err)
+		}
+	}
+	_, err = bytesToState(ss[:3], defaultMaxLeafSize)
+	assert.Equal(t, ErrLeafCorrupted, err)
+
+	// leaves written without checksum still read
+	var legacy bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&legacy).Encode(s))
+	state, err := bytesToState(legacy.Bytes(), defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(100)))
+	// the payload of a leaf with checksum is the State with its maps as sorted slices
+	var stored bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&stored).Encode(&storedState{Nonce: s.Nonce, Balance: s.Balance,
+		LockedBalance: s.LockedBalance, Address: s.Address, VotingWeight: s.VotingWeight, VotedWeight: s.VotedWeight}))
+	assert.Equal(t, stored.Bytes(), ss[stateHeaderLen:])
+
+	// as do leaves with checksum written before, which carry a State
+	s.Voters = map[common.Hash32B]*big.Int{voterKey(addr): big.NewInt(5)}
+	var older bytes.Buffer
+	older.Write(make([]byte, stateHeaderLen))
+	assert.Nil(t, gob.NewEncoder(&older).Encode(s))
+	leaf := older.Bytes()
+	leaf[0] = stateFormatChecksum
+	binary.BigEndian.PutUint32(leaf[1:stateHeaderLen], crc32.ChecksumIEEE(leaf[stateHeaderLen:]))
+	state, err = bytesToState(leaf, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(100)))
+	assert.Equal(t, 0, state.Voters[voterKey(addr)].Cmp(big.NewInt(5)))
+}
+
 func TestRootHash(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -42,13 +99,26 @@ func TestRootHash(t *testing.T) {
 	assert.Equal(t, common.ZeroHash32B, sf.RootHash())
 }
 
+func TestEmptyRootHash(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	assert.Equal(t, EmptyRootHash, sf.RootHash())
+
+	createAccounts(t, sf, 1, 0)
+	assert.NotEqual(t, EmptyRootHash, sf.RootHash())
+}
+
 func TestCreateState(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	trie := mock_trie.NewMockTrie(ctrl)
-	sf := NewStateFactory(trie)
-	trie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1)
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	sf := NewStateFactory(mtrie)
+	// a new account also increments the account count, which a mock trie must store as it cannot be recounted
+	mtrie.EXPECT().Get(accountCountKey).Times(1).Return(utils.Uint64ToBytes(0), nil)
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(nil, trie.ErrNotExist)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(2)
 	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
 	assert.Nil(t, err)
 	state, _ := sf.CreateState(addr, 0)
@@ -57,6 +127,42 @@ func TestCreateState(t *testing.T) {
 	assert.Equal(t, addr.RawAddress, state.Address.RawAddress)
 }
 
+func TestCreateStateNoAliasing(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	a, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	b, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	sa, err := sf.CreateState(a, 0)
+	assert.Nil(t, err)
+	sb, err := sf.CreateState(b, 0)
+	assert.Nil(t, err)
+	assert.False(t, sa.Balance == sb.Balance)
+	assert.False(t, sa.LockedBalance == sb.LockedBalance)
+	assert.False(t, sa.VotingWeight == sb.VotingWeight)
+
+	// mutating one account leaves the other untouched
+	sa.VotingWeight.SetInt64(7)
+	sa.Balance.SetInt64(7)
+	assert.Equal(t, 0, sb.VotingWeight.Sign())
+	assert.Equal(t, 0, sb.Balance.Sign())
+	assert.Nil(t, sfi.updateState(a, func(state *State) error {
+		state.VotingWeight.SetInt64(9)
+		return nil
+	}))
+	state, err := sfi.getState(b)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Sign())
+	state, err = sfi.getState(a)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(9)))
+}
+
 func TestBalance(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -74,6 +180,502 @@ func TestBalance(t *testing.T) {
 	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(30)))
 }
 
+func TestAddBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	sf := NewStateFactory(mtrie)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	mstate, _ := stateToBytes(&State{Address: addr, Balance: big.NewInt(20)})
+
+	// adding zero does not change the state, trie should not be updated
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(0)
+	assert.Nil(t, sf.AddBalance(addr, big.NewInt(0)))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Do(func(key, value []byte) error {
+		state, _ := bytesToState(value, defaultMaxLeafSize)
+		assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(30)))
+		return nil
+	})
+	assert.Nil(t, sf.AddBalance(addr, big.NewInt(10)))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(nil, trie.ErrNotExist)
+	assert.Equal(t, ErrAccountNotExist, sf.AddBalance(addr, big.NewInt(10)))
+}
+
+func TestTrieWriteError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	sf := NewStateFactory(mtrie)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	recipient, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	mstate, _ := stateToBytes(&State{Address: addr, Balance: big.NewInt(20)})
+	errWrite := errors.New("disk full")
+
+	// a failed trie write is returned to the caller, never reported as success
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(nil, trie.ErrNotExist)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Return(errWrite)
+	_, err = sf.CreateState(addr, 10)
+	assert.Equal(t, errWrite, err)
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Return(errWrite)
+	assert.Equal(t, errWrite, sf.AddBalance(addr, big.NewInt(10)))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Return(errWrite)
+	assert.Equal(t, errWrite, sf.SetNonce(addr, 1))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(2).Return(mstate, nil)
+	mtrie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Return(errWrite)
+	assert.Equal(t, errWrite, sf.ApplyTransferTx(addr, recipient, big.NewInt(5), 0))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	mtrie.EXPECT().Delete(gomock.Any()).Times(1).Return(errWrite)
+	assert.Equal(t, errWrite, sf.DeleteState(addr))
+
+	// nothing is recorded as changed
+	assert.Empty(t, sf.(Monitor).PendingChanges())
+}
+
+func TestNoOpUpdateRootHash(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(addr, 20)
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	// no-op updates keep the root stable
+	assert.Nil(t, sf.AddBalance(addr, big.NewInt(0)))
+	assert.Equal(t, root, sf.RootHash())
+	assert.Nil(t, sf.SetNonce(addr, 0))
+	assert.Equal(t, root, sf.RootHash())
+
+	assert.Nil(t, sf.AddBalance(addr, big.NewInt(5)))
+	assert.NotEqual(t, root, sf.RootHash())
+	balance, err := sf.Balance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(25)))
+}
+
+func TestNoOpUpdateCandidate(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 7, 20)
+	candidate := addrs[0]
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+	for _, voter := range addrs[1:] {
+		assert.Nil(t, sf.Vote(voter, candidate, big.NewInt(1)))
+	}
+	root := sf.RootHash()
+	leaf, err := tr.Get(iotxaddress.HashPubKey(candidate.PublicKey))
+	assert.Nil(t, err)
+
+	// the leaf of a candidate with several voters encodes the same every time, so a no-op update never rewrites it
+	for i := 0; i < 20; i++ {
+		assert.Nil(t, sf.AddBalance(candidate, big.NewInt(0)))
+		assert.Equal(t, root, sf.RootHash())
+	}
+	ss, err := tr.Get(iotxaddress.HashPubKey(candidate.PublicKey))
+	assert.Nil(t, err)
+	assert.Equal(t, leaf, ss)
+}
+
+func TestPause(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(addr, 20)
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	sf.(Pauser).Pause()
+	// mutations are rejected
+	other, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(other, 10)
+	assert.Equal(t, ErrFactoryPaused, err)
+	assert.Equal(t, ErrFactoryPaused, sf.AddBalance(addr, big.NewInt(5)))
+	assert.Equal(t, ErrFactoryPaused, sf.SetNonce(addr, 1))
+	assert.Equal(t, ErrFactoryPaused, sf.UpdateStatesWithTransfer(nil))
+	assert.Equal(t, ErrFactoryPaused, sf.RegisterName("alfa", addr))
+	assert.Equal(t, root, sf.RootHash())
+	// reads keep working
+	n, b, err := sf.NonceAndBalance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), n)
+	assert.Equal(t, 0, b.Cmp(big.NewInt(20)))
+
+	sf.(Pauser).Resume()
+	assert.Nil(t, sf.AddBalance(addr, big.NewInt(5)))
+	assert.Nil(t, sf.SetNonce(addr, 1))
+	n, b, err = sf.NonceAndBalance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), n)
+	assert.Equal(t, 0, b.Cmp(big.NewInt(25)))
+}
+
+func TestCommitWritesDirtyLeaves(t *testing.T) {
+	dao := &countingKVStore{KVStore: db.NewMemKVStore()}
+	tr, err := trie.NewTrieSharedDB(dao)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 100, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// touch 1% of the accounts
+	dao.leaves = 0
+	assert.Nil(t, sf.AddBalance(addrs[42], big.NewInt(5)))
+	stats, err := sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, dao.leaves)
+	assert.Equal(t, 1, stats.Leaves)
+	assert.NotEqual(t, 0, stats.Nodes)
+	assert.NotEqual(t, 0, stats.Bytes)
+
+	// the reported leaves are the accounts changed in the block
+	dao.leaves = 0
+	for _, i := range []int{3, 17, 64} {
+		assert.Nil(t, sf.SetNonce(addrs[i], 1))
+	}
+	stats, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, stats.Leaves)
+	assert.Equal(t, dao.leaves, stats.Leaves)
+
+	// nothing changed, nothing written
+	dao.leaves = 0
+	stats, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, dao.leaves)
+	assert.Equal(t, 0, stats.Leaves)
+	assert.Equal(t, 0, stats.Nodes)
+	assert.Equal(t, 0, stats.Bytes)
+
+	// root equals a full recompute of the same accounts
+	tr1, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf1 := NewStateFactory(tr1)
+	for i := len(addrs) - 1; i >= 0; i-- {
+		init := uint64(10)
+		if i == 42 {
+			init = 15
+		}
+		_, err := sf1.CreateState(addrs[i], init)
+		assert.Nil(t, err)
+		if i == 3 || i == 17 || i == 64 {
+			assert.Nil(t, sf1.SetNonce(addrs[i], 1))
+		}
+	}
+	_, err = sf1.Commit()
+	assert.Nil(t, err)
+	assert.Equal(t, sf.RootHash(), sf1.RootHash())
+}
+
+func TestCompact(t *testing.T) {
+	// the store does not support compaction
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	assert.Nil(t, NewStateFactory(tr).Compact())
+
+	dao := &compactingKVStore{KVStore: db.NewMemKVStore()}
+	tr, err = trie.NewTrieSharedDB(dao)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 10, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// the updated account's old nodes are deleted on commit, then compacted
+	dao.deleted = 0
+	assert.Nil(t, sf.AddBalance(addrs[3], big.NewInt(5)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.NotEqual(t, 0, dao.deleted)
+	assert.Equal(t, 0, dao.compacted)
+	assert.Nil(t, sf.Compact())
+	assert.Equal(t, 1, dao.compacted)
+	assert.Equal(t, 0, dao.deleted)
+}
+
+func TestAddressEncoding(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	derived, err := iotxaddress.GetAddress(addr.PublicKey, true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	literal := &iotxaddress.Address{PublicKey: addr.PublicKey, RawAddress: addr.RawAddress}
+
+	var leaves [][]byte
+	var roots []common.Hash32B
+	for _, a := range []*iotxaddress.Address{addr, derived, literal} {
+		ss, err := stateToBytes(newState(a, 100))
+		assert.Nil(t, err)
+		leaves = append(leaves, ss)
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		sf := NewStateFactory(tr)
+		_, err = sf.CreateState(a, 100)
+		assert.Nil(t, err)
+		roots = append(roots, sf.RootHash())
+	}
+	for i := 1; i < len(leaves); i++ {
+		assert.Equal(t, leaves[0], leaves[i])
+		assert.Equal(t, roots[0], roots[i])
+	}
+	// the private key is never stored
+	assert.False(t, bytes.Contains(leaves[0], addr.PrivateKey))
+	state, err := bytesToState(leaves[0], defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Nil(t, state.Address.PrivateKey)
+	assert.Equal(t, addr.PublicKey, state.Address.PublicKey)
+}
+
+func TestEmptyLeaf(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+
+	assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addr.PublicKey), []byte{}))
+	_, err = sf.GetState(addr)
+	assert.Equal(t, ErrLeafCorrupted, err)
+	_, err = sf.Balance(addr)
+	assert.Equal(t, ErrLeafCorrupted, err)
+	// a checksummed leaf with an empty payload
+	_, err = bytesToState([]byte{stateFormatChecksum, 0, 0, 0, 0}, defaultMaxLeafSize)
+	assert.Equal(t, ErrLeafCorrupted, err)
+
+	assert.Nil(t, tr.Upsert(candidateListKey, []byte{}))
+	_, err = sf.RankedCandidates()
+	assert.Equal(t, ErrLeafCorrupted, errors.Cause(err))
+}
+
+func TestLeafTooLarge(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MaxLeafSizeOption(1024))
+	addrs := createAccounts(t, sf, 2, 10)
+
+	// a leaf claiming a huge voters map is refused by its size before gob allocates anything
+	s := newState(addrs[0], 10)
+	s.Voters = make(map[common.Hash32B]*big.Int)
+	for i := 0; i < 100; i++ {
+		s.Voters[common.Hash32B{byte(i)}] = big.NewInt(int64(i))
+	}
+	ss, err := stateToBytes(s)
+	assert.Nil(t, err)
+	assert.True(t, len(ss) > 1024)
+	assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addrs[0].PublicKey), ss))
+	_, err = sf.GetState(addrs[0])
+	assert.Equal(t, ErrLeafTooLarge, errors.Cause(err))
+	assert.Equal(t, ErrLeafTooLarge, errors.Cause(sf.AddBalance(addrs[0], big.NewInt(1))))
+	_, err = bytesToState(ss, defaultMaxLeafSize)
+	assert.Nil(t, err)
+
+	// leaves within the limit still read
+	balance, err := sf.Balance(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+}
+
+func TestReadCommitted(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	confirmed := NewStateFactory(tr, ReadCommittedOption())
+	addrs := createAccounts(t, sf, 2, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+
+	// mutate without committing, as when simulating the pending block
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(5)))
+	assert.Nil(t, sf.SetNonce(addrs[0], 3))
+	created := createAccounts(t, sf, 1, 7)[0]
+
+	// by default reads observe the uncommitted changes
+	balance, err := sf.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(15)))
+	nonce, err := sf.Nonce(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), nonce)
+	state, err := sf.GetState(created)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(7)))
+
+	// with ReadCommittedOption reads see the last committed root
+	balance, err = confirmed.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+	nonce, err = confirmed.Nonce(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), nonce)
+	_, err = confirmed.GetState(created)
+	assert.Equal(t, ErrAccountNotExist, err)
+	balance, err = confirmed.Balance(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+
+	// mutations still apply on top of the uncommitted changes
+	assert.Nil(t, confirmed.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(1), 3))
+	balance, err = confirmed.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+
+	// once committed both agree
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	n, b, err := confirmed.NonceAndBalance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), n)
+	assert.Equal(t, 0, b.Cmp(big.NewInt(14)))
+	state, err = confirmed.GetState(created)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(7)))
+}
+
+func TestSubBalanceShortfall(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	state := newState(addr, 65)
+
+	err = state.SubBalance(big.NewInt(100))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(err))
+	balanceErr, ok := err.(*InsufficientBalanceError)
+	assert.True(t, ok)
+	assert.Equal(t, 0, balanceErr.Shortfall().Cmp(big.NewInt(35)))
+	assert.Equal(t, "not enough balance: balance 65, amount 100, short of 35", err.Error())
+	// the balance is untouched
+	assert.Equal(t, 0, state.Balance.Cmp(big.NewInt(65)))
+
+	// the shortfall is reported through transfers as well
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 2, 65)
+	err = sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(70), 0)
+	balanceErr, ok = err.(*InsufficientBalanceError)
+	assert.True(t, ok)
+	assert.Equal(t, 0, balanceErr.Shortfall().Cmp(big.NewInt(5)))
+}
+
+func TestApplyTransferTx(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 2, 100)
+	assert.Nil(t, sf.SetNonce(addrs[0], 3))
+	root := sf.RootHash()
+
+	// wrong nonce or not enough balance leaves the state untouched
+	assert.Equal(t, ErrNonceTooLow, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(10), 2))
+	assert.Equal(t, ErrNonceTooHigh, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(10), 4))
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(101), 3)))
+	assert.Equal(t, ErrInvalidAmount, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(-1), 3))
+	assert.Equal(t, root, sf.RootHash())
+
+	assert.Nil(t, sf.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(100), 3))
+	nonce, balance, err := sf.NonceAndBalance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(4), nonce)
+	assert.Equal(t, 0, balance.Sign())
+	nonce, balance, err = sf.NonceAndBalance(addrs[1])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), nonce)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(200)))
+
+	// the recipient account is created if missing
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	assert.Nil(t, sf.ApplyTransferTx(addrs[1], addr, big.NewInt(50), 0))
+	balance, err = sf.Balance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(50)))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+
+	// a transfer to self only bumps the nonce
+	assert.Nil(t, sf.ApplyTransferTx(addr, addr, big.NewInt(50), 0))
+	nonce, balance, err = sf.NonceAndBalance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), nonce)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(50)))
+}
+
+func TestUpdateStatesWithTransfer(t *testing.T) {
+	kv := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, BalanceIndexOption(kv))
+
+	addrs := createAccounts(t, sf, 2, 100)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	transfer := func(from, to *iotxaddress.Address, amount int64) *trx.Tx {
+		return &trx.Tx{SenderPublicKey: from.PublicKey, Recipient: to, Amount: big.NewInt(amount)}
+	}
+	balanceOf := func(addr *iotxaddress.Address) int64 {
+		balance, err := sf.Balance(addr)
+		assert.Nil(t, err)
+		return balance.Int64()
+	}
+
+	// a later transfer spends what an earlier one credited, to an account it created
+	assert.Nil(t, sf.UpdateStatesWithTransfer([]*trx.Tx{
+		transfer(addrs[0], addrs[1], 60),
+		transfer(addrs[1], addr, 150),
+		transfer(addr, addrs[0], 30),
+	}))
+	assert.Equal(t, int64(70), balanceOf(addrs[0]))
+	assert.Equal(t, int64(10), balanceOf(addrs[1]))
+	assert.Equal(t, int64(120), balanceOf(addr))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(3), count)
+	// the transfers are committed as Commit does, so the balance index ranks them
+	top, err := sf.TopBalances(1)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(top))
+	assert.Equal(t, addr.RawAddress, top[0].Address.RawAddress)
+	assert.Equal(t, 0, len(sf.(Monitor).PendingChanges()))
+
+	// a failing transfer stages none of the batch
+	root := sf.RootHash()
+	err = sf.UpdateStatesWithTransfer([]*trx.Tx{
+		transfer(addrs[0], addrs[1], 70),
+		transfer(addrs[1], addr, 81),
+	})
+	assert.Equal(t, ErrNotEnoughBalance, errors.Cause(err))
+	assert.Equal(t, root, sf.RootHash())
+	assert.Equal(t, int64(70), balanceOf(addrs[0]))
+	assert.Equal(t, int64(10), balanceOf(addrs[1]))
+}
+
 func TestNonce(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -94,10 +696,10 @@ func TestNonce(t *testing.T) {
 
 	trie.EXPECT().Get(gomock.Any()).Times(1).Return(nil, nil)
 	_, err = sf.Nonce(addr)
-	assert.Equal(t, ErrFailedToUnmarshalState, err)
+	assert.Equal(t, ErrLeafCorrupted, err)
 
 	trie.EXPECT().Upsert(gomock.Any(), gomock.Any()).Times(1).Do(func(key, value []byte) error {
-		state, _ := bytesToState(value)
+		state, _ := bytesToState(value, defaultMaxLeafSize)
 		assert.Equal(t, uint64(0x11), state.Nonce)
 		return nil
 	})
@@ -106,6 +708,28 @@ func TestNonce(t *testing.T) {
 	err = sf.SetNonce(addr, uint64(0x11))
 }
 
+func TestNonceAndBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mtrie := mock_trie.NewMockTrie(ctrl)
+	sf := NewStateFactory(mtrie)
+
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	mstate, _ := stateToBytes(&State{Address: addr, Nonce: 0x10, Balance: big.NewInt(20)})
+	// both values should come from a single read of the trie
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(mstate, nil)
+	n, b, err := sf.NonceAndBalance(addr)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0x10), n)
+	assert.Equal(t, 0, b.Cmp(big.NewInt(20)))
+
+	mtrie.EXPECT().Get(gomock.Any()).Times(1).Return(nil, trie.ErrNotExist)
+	_, _, err = sf.NonceAndBalance(addr)
+	assert.Equal(t, ErrAccountNotExist, err)
+}
+
 func TestVirtualNonce(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -149,3 +773,205 @@ func TestVirtualNonce(t *testing.T) {
 	assert.Equal(t, ErrAccountNotExist, err)
 	assert.Equal(t, 0, len(vsf.changes))
 }
+
+func TestVirtualNotSupported(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	addrs := createAccounts(t, NewStateFactory(tr), 2, 10)
+	vs := NewVirtualStateFactory(tr)
+
+	// the operations the virtual factory does not track fail instead of silently doing nothing
+	assert.Equal(t, ErrNotSupported, vs.ApplyTransferTx(addrs[0], addrs[1], big.NewInt(1), 0))
+	assert.Equal(t, ErrNotSupported, vs.ApplySponsoredTx(addrs[0], addrs[1], addrs[1], big.NewInt(1), big.NewInt(1), 0))
+	assert.Equal(t, ErrNotSupported, vs.AuthorizedTransfer(addrs[0], addrs[1], big.NewInt(1), 0, nil))
+	_, err = vs.BalanceOf(addrs[0], NativeAsset)
+	assert.Equal(t, ErrNotSupported, err)
+	_, err = vs.Vested(addrs[0], 0)
+	assert.Equal(t, ErrNotSupported, err)
+	_, err = vs.BalanceAtRoot(vs.RootHash(), *addrs[0])
+	assert.Equal(t, ErrNotSupported, err)
+	balance, err := vs.Balance(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(10)))
+}
+
+func TestOptionalFeatures(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 1, 10)
+	vs := NewVirtualStateFactory(tr)
+
+	// the factory has every optional feature, the virtual one none
+	for _, f := range []StateFactory{sf, vs} {
+		_, pauser := f.(Pauser)
+		_, prover := f.(Prover)
+		_, exporter := f.(Exporter)
+		_, monitor := f.(Monitor)
+		_, randSourcer := f.(RandSourcer)
+		_, approver := f.(Approver)
+		assert.Equal(t, f == sf, pauser && prover && exporter && monitor && randSourcer && approver)
+		assert.Equal(t, f == sf, pauser || prover || exporter || monitor || randSourcer || approver)
+	}
+
+	// the virtual factory implements the remaining methods rather than ignoring them, it reads the States at the
+	// height set as the factory does
+	vs.SetHeight(9)
+	vs.Reserve(4)
+	state, err := vs.GetState(addrs[0])
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(9), state.height)
+}
+
+func TestDeleteAll(t *testing.T) {
+	kv := db.NewMemKVStore()
+	assert.Nil(t, kv.Put("other", []byte("key"), []byte("value")))
+	tr, err := trie.NewTrieSharedDB(kv)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 5, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.NotEqual(t, EmptyRootHash, sf.RootHash())
+
+	assert.Nil(t, sf.DeleteAll())
+	assert.Equal(t, EmptyRootHash, sf.RootHash())
+	assert.Equal(t, 0, len(sf.(Monitor).PendingChanges()))
+	count, err := sf.AccountCount()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), count)
+	_, err = sf.Balance(addrs[0])
+	assert.Equal(t, ErrAccountNotExist, err)
+	value, err := kv.Get("other", []byte("key"))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	// the factory is usable again
+	createAccounts(t, sf, 1, 10)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+}
+
+func BenchmarkNonceAndBalance(b *testing.B) {
+	sf, addr := benchmarkStateFactory(b)
+	defer os.Remove(testTriePath)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := sf.NonceAndBalance(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkNonceThenBalance(b *testing.B) {
+	sf, addr := benchmarkStateFactory(b)
+	defer os.Remove(testTriePath)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sf.Nonce(addr); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := sf.Balance(addr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const testTriePath = "trie.test"
+
+// benchmarkStateFactory creates a state factory on a real trie holding a single account
+func benchmarkStateFactory(b *testing.B) (StateFactory, *iotxaddress.Address) {
+	os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sf := NewStateFactory(tr)
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := sf.CreateState(addr, 100); err != nil {
+		b.Fatal(err)
+	}
+	return sf, addr
+}
+
+func BenchmarkCommitDirty(b *testing.B) {
+	dao := &countingKVStore{KVStore: db.NewMemKVStore()}
+	tr, err := trie.NewTrieSharedDB(dao)
+	if err != nil {
+		b.Fatal(err)
+	}
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(b, sf, 1000, 10)
+	if _, err := sf.Commit(); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// a block touching 1% of the accounts
+		dao.leaves = 0
+		for j := 0; j < 10; j++ {
+			if err := sf.AddBalance(addrs[(i*10+j)%len(addrs)], big.NewInt(1)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if _, err := sf.Commit(); err != nil {
+			b.Fatal(err)
+		}
+		if dao.leaves != 10 {
+			b.Fatalf("expect 10 leaves written, got %d", dao.leaves)
+		}
+	}
+}
+
+// countingKVStore counts the leaf nodes written in batch
+type countingKVStore struct {
+	db.KVStore
+	leaves int
+}
+
+func (c *countingKVStore) BatchPut(namespace string, key [][]byte, value [][]byte) error {
+	for _, v := range value {
+		// first byte of serialized leaf node is 0
+		if len(v) > 0 && v[0] == 0 {
+			c.leaves++
+		}
+	}
+	return c.KVStore.BatchPut(namespace, key, value)
+}
+
+// compactingKVStore records the deletes since the last compaction and the compactions
+type compactingKVStore struct {
+	db.KVStore
+	deleted   int
+	compacted int
+}
+
+func (c *compactingKVStore) Delete(namespace string, key []byte) error {
+	c.deleted++
+	return c.KVStore.Delete(namespace, key)
+}
+
+func (c *compactingKVStore) Compact() error {
+	c.deleted = 0
+	c.compacted++
+	return nil
+}
+
+// createAccounts creates the number of accounts with the initial balance
+func createAccounts(t assert.TestingT, sf StateFactory, num int, init uint64) []*iotxaddress.Address {
+	addrs := make([]*iotxaddress.Address, num)
+	for i := range addrs {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		_, err = sf.CreateState(addr, init)
+		assert.Nil(t, err)
+		addrs[i] = addr
+	}
+	return addrs
+}
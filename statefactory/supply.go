@@ -0,0 +1,40 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+)
+
+// SupplyDelta returns the signed change of the native coin supply since the last commit, e.g. rewards minus burns
+// The supply is the total balance and locked balance of all accounts. Each account changed since the last commit is
+// compared with its state as of the last commit, which the trie must support reading. Transfers between accounts
+// cancel out, funds burned to a burn address stay in the supply.
+func (sf *stateFactory) SupplyDelta() (*big.Int, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	delta := big.NewInt(0)
+	for _, change := range sf.PendingChanges() {
+		key := AccountKeyOf(change.Address)
+		if change.Kind != ChangeDeleted {
+			state, err := getStateByKey(sf.trie, key, sf.maxLeafSize)
+			if err != nil {
+				return nil, sf.checkLeaf(key.Bytes(), err)
+			}
+			delta.Add(delta, state.stake())
+		}
+		if change.Kind != ChangeCreated {
+			state, err := getCommittedStateByKey(sf.trie, key, sf.maxLeafSize)
+			if err != nil {
+				return nil, sf.checkLeaf(key.Bytes(), err)
+			}
+			delta.Sub(delta, state.stake())
+		}
+	}
+	return delta, nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestSupplyDelta(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	addrs := createAccounts(t, sf, 3, 100)
+	delta, err := sf.SupplyDelta()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delta.Cmp(big.NewInt(300)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	delta, err = sf.SupplyDelta()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delta.Sign())
+
+	// a block rewarding the producer and burning a fee, with transfers and locks in between
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(50)))
+	assert.Nil(t, sf.ApplyTransferTx(addrs[1], addrs[2], big.NewInt(30), 0))
+	assert.Nil(t, sf.Lock(addrs[2], big.NewInt(20)))
+	assert.Nil(t, sf.Burn(addrs[1], big.NewInt(8)))
+	delta, err = sf.SupplyDelta()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delta.Cmp(big.NewInt(42)))
+
+	// a deleted account takes its funds out of the supply
+	assert.Nil(t, sf.DeleteState(addrs[2]))
+	delta, err = sf.SupplyDelta()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delta.Cmp(big.NewInt(42-130)))
+
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	delta, err = sf.SupplyDelta()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, delta.Sign())
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// SyncPolicyOption sets how often the trie's KV store is flushed to disk after Commit, see trie.SyncPolicy
+// trie.SyncAlways makes every commit that returned survive a power loss, trie.SyncPeriodic and trie.SyncNever trade
+// the commits since the last flush, and with BoltDB the integrity of the file, on a power loss for throughput. Without
+// the option the store flushes as it does by default. It has no effect if the trie cannot defer flushing.
+func SyncPolicyOption(policy trie.SyncPolicy) Option {
+	return func(sf *stateFactory) {
+		if s, ok := sf.trie.(interface {
+			SetSyncPolicy(trie.SyncPolicy)
+		}); ok {
+			s.SetSyncPolicy(policy)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// syncCountingKVStore is an in-memory KV store counting the flushes to disk it is asked for
+type syncCountingKVStore struct {
+	db.KVStore
+	noSync bool
+	syncs  int
+}
+
+func (s *syncCountingKVStore) SetNoSync(noSync bool) { s.noSync = noSync }
+
+func (s *syncCountingKVStore) Sync() error {
+	s.syncs++
+	return nil
+}
+
+func TestSyncPolicyOption(t *testing.T) {
+	for _, c := range []struct {
+		policy trie.SyncPolicy
+		syncs  int
+	}{
+		{trie.SyncPolicy{Mode: trie.SyncAlways}, 6},
+		{trie.SyncPolicy{Mode: trie.SyncPeriodic, Commits: 4}, 1},
+		{trie.SyncPolicy{Mode: trie.SyncNever}, 0},
+	} {
+		dao := &syncCountingKVStore{KVStore: db.NewMemKVStore()}
+		tr, err := trie.NewTrieSharedDB(dao)
+		assert.Nil(t, err)
+		sf := NewStateFactory(tr, SyncPolicyOption(c.policy))
+		assert.True(t, dao.noSync)
+		for i := 0; i < 6; i++ {
+			createAccounts(t, sf, 1, 10)
+			_, err := sf.Commit()
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, c.syncs, dao.syncs)
+	}
+}
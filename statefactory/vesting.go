@@ -0,0 +1,87 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"sort"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// ErrFundsVesting is the error that the amount would spend a part of the balance that has not vested yet
+var ErrFundsVesting = errors.New("funds are still vesting")
+
+// VestingPoint is the amount of an account's balance that unlocks at the height
+type VestingPoint struct {
+	Height uint64
+	Amount *big.Int
+}
+
+// SetHeight sets the height of the block being applied, vesting points up to and including it are unlocked
+func (sf *stateFactory) SetHeight(height uint64) {
+	atomic.StoreUint64(&sf.height, height)
+}
+
+// SetVesting replaces the vesting schedule of the account
+// The vesting amounts are part of the balance, until a point's height is reached its amount cannot be spent or locked.
+// An empty schedule removes vesting.
+func (sf *stateFactory) SetVesting(addr *iotxaddress.Address, schedule []VestingPoint) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	var sorted []VestingPoint
+	for _, point := range schedule {
+		if point.Amount == nil || point.Amount.Sign() <= 0 {
+			return ErrInvalidAmount
+		}
+		sorted = append(sorted, VestingPoint{Height: point.Height, Amount: new(big.Int).Set(point.Amount)})
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Height < sorted[j].Height })
+	return sf.updateState(addr, func(state *State) error {
+		state.VestingSchedule = sorted
+		return nil
+	})
+}
+
+// Vested returns the amount of the account's vesting schedule that has unlocked at the height
+func (sf *stateFactory) Vested(addr *iotxaddress.Address, height uint64) (*big.Int, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	state, err := sf.getState(addr)
+	if err != nil {
+		return nil, err
+	}
+	vested := big.NewInt(0)
+	for _, point := range state.VestingSchedule {
+		if point.Height <= height {
+			vested.Add(vested, point.Amount)
+		}
+	}
+	return vested, nil
+}
+
+// currentHeight returns the height set by SetHeight
+func (sf *stateFactory) currentHeight() uint64 {
+	return atomic.LoadUint64(&sf.height)
+}
+
+// unvested returns the amount of the vesting schedule still locked at the height
+func (st *State) unvested(height uint64) *big.Int {
+	locked := big.NewInt(0)
+	for _, point := range st.VestingSchedule {
+		if point.Height > height {
+			locked.Add(locked, point.Amount)
+		}
+	}
+	return locked
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+package statefactory
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestVesting(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+
+	addrs := createAccounts(t, sf, 2, 100)
+	holder, recipient := addrs[0], addrs[1]
+	assert.Equal(t, ErrInvalidAmount, sf.SetVesting(holder, []VestingPoint{{Height: 10, Amount: big.NewInt(0)}}))
+	assert.Nil(t, sf.SetVesting(holder, []VestingPoint{
+		{Height: 20, Amount: big.NewInt(30)},
+		{Height: 10, Amount: big.NewInt(50)},
+	}))
+
+	// before the schedule starts only the 20 outside of it can be spent
+	sf.SetHeight(5)
+	vested, err := sf.Vested(holder, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vested.Sign())
+	assert.Equal(t, ErrFundsVesting, sf.ApplyTransferTx(holder, recipient, big.NewInt(21), 0))
+	assert.Equal(t, ErrFundsVesting, sf.Lock(holder, big.NewInt(21)))
+	assert.Nil(t, sf.ApplyTransferTx(holder, recipient, big.NewInt(20), 0))
+
+	// during the schedule the first point has unlocked
+	sf.SetHeight(10)
+	vested, err = sf.Vested(holder, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vested.Cmp(big.NewInt(50)))
+	assert.Equal(t, ErrFundsVesting, sf.ApplyTransferTx(holder, recipient, big.NewInt(51), 1))
+	assert.Nil(t, sf.ApplyTransferTx(holder, recipient, big.NewInt(50), 1))
+
+	// after the schedule completes the whole balance is spendable
+	sf.SetHeight(20)
+	vested, err = sf.Vested(holder, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, vested.Cmp(big.NewInt(80)))
+	assert.Nil(t, sf.ApplyTransferTx(holder, recipient, big.NewInt(30), 2))
+	balance, err := sf.Balance(holder)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Sign())
+	balance, err = sf.Balance(recipient)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, balance.Cmp(big.NewInt(200)))
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+var (
+	// voterListKey is the trie key of the list of accounts that have voted
+	voterListKey = func() []byte {
+		digest := blake2b.Sum256([]byte("voters"))
+		return digest[7:27]
+	}()
+
+	// ErrNotCandidate is the error that the account is not a registered candidate
+	ErrNotCandidate = errors.New("the account is not a candidate")
+
+	// ErrInvalidVoteWeight is the error that the vote weight is not positive
+	ErrInvalidVoteWeight = errors.New("invalid vote weight")
+
+	// ErrInsufficientStake is the error that the voter's votes would exceed its stake
+	ErrInsufficientStake = errors.New("insufficient stake")
+
+	// ErrTooManyVoters is the error that the candidate already has the maximum number of voters
+	ErrTooManyVoters = errors.New("too many voters")
+
+	// ErrVoteTooSmall is the error that the vote weight is below the minimum, see MinVoteWeightOption
+	ErrVoteTooSmall = errors.New("vote weight below the minimum")
+)
+
+// Vote casts the weight from the voter to the candidate
+// A voter's stake is its total balance, both spendable and locked. The weight plus the votes the voter has already cast
+// must not exceed the stake, so a voter may split its stake across candidates up to that limit. A candidate accepts
+// votes from at most the maximum number of voters, see MaxVotersOption, an existing voter can always add weight. Every
+// vote must cast at least the minimum weight, see MinVoteWeightOption. Both accounts are locked while the checks run
+// and written together, so the voter's VotedWeight never goes up without the candidate's Voters.
+func (sf *stateFactory) Vote(voter *iotxaddress.Address, candidate *iotxaddress.Address, weight *big.Int) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	if weight.Sign() <= 0 {
+		return ErrInvalidVoteWeight
+	}
+	if err := sf.checkVoteWeight(weight); err != nil {
+		return err
+	}
+	if err := sf.checkPublicKeys(voter, candidate); err != nil {
+		return err
+	}
+	vkey, ckey := AccountKeyOf(voter), AccountKeyOf(candidate)
+	unlock := sf.lockAccounts(vkey.Bytes(), ckey.Bytes())
+	defer unlock()
+	cstate, err := sf.getState(candidate)
+	if err != nil {
+		return err
+	}
+	if !cstate.IsCandidate {
+		return ErrNotCandidate
+	}
+	if _, ok := cstate.Voters[voterKey(voter)]; !ok && len(cstate.Voters) >= sf.maxVoters {
+		return ErrTooManyVoters
+	}
+	// a candidate voting for itself shares one State
+	states := map[AccountKey]*State{ckey: cstate}
+	addrs := map[AccountKey]*iotxaddress.Address{ckey: candidate}
+	vstate, ok := states[vkey]
+	if !ok {
+		if vstate, err = sf.getState(voter); err != nil {
+			return err
+		}
+		states[vkey], addrs[vkey] = vstate, voter
+	}
+	voted := new(big.Int).Add(vstate.votedWeight(), weight)
+	if voted.Cmp(vstate.stake()) > 0 {
+		return ErrInsufficientStake
+	}
+	vstate.VotedWeight = voted
+	cstate.addVote(voterKey(voter), weight)
+	cstate.VotingWeight = new(big.Int).Add(cstate.VotingWeight, weight)
+	if err := sf.writeStates(states, addrs, nil); err != nil {
+		return err
+	}
+	return sf.addToAddressList(voterListKey, voter)
+}
+
+// AuditVotingStakes returns the raw addresses of voters whose cast votes currently exceed their stake
+// Vote enforces the limit when votes are cast, but the stake may drop afterwards, e.g. by a transfer. With
+// QueryLimitsOption the voters found among those read before a limit was hit are returned with
+// ErrResourceLimitExceeded.
+func (sf *stateFactory) AuditVotingStakes() ([]string, error) {
+	if err := sf.checkOpen(); err != nil {
+		return nil, err
+	}
+	voters, err := sf.getAddressList(voterListKey)
+	if err != nil {
+		return nil, err
+	}
+	budget := sf.newQueryBudget()
+	var overStaked []string
+	for _, voter := range voters {
+		state, err := sf.scanState(budget, voter)
+		if errors.Cause(err) == ErrResourceLimitExceeded {
+			return overStaked, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		if state.votedWeight().Cmp(state.stake()) > 0 {
+			overStaked = append(overStaked, voter.RawAddress)
+		}
+	}
+	return overStaked, nil
+}
+
+// checkVoteWeight returns ErrVoteTooSmall if the weight is below the minimum a vote may cast
+func (sf *stateFactory) checkVoteWeight(weight *big.Int) error {
+	if weight.Cmp(sf.minVote) < 0 {
+		return errors.Wrapf(ErrVoteTooSmall, "weight %s, minimum %s", weight, sf.minVote)
+	}
+	return nil
+}
+
+// voterKey returns the key of the voter in a candidate's Voters
+func voterKey(addr *iotxaddress.Address) common.Hash32B {
+	return blake2b.Sum256(addr.PublicKey)
+}
+
+// votedWeight returns the total weight voted by the account, treating a missing value as zero
+func (st *State) votedWeight() *big.Int {
+	if st.VotedWeight == nil {
+		return big.NewInt(0)
+	}
+	return st.VotedWeight
+}
+
+// addVote adds the weight to the vote of the voter in Voters, which is nil until the account is first voted for
+func (st *State) addVote(key common.Hash32B, weight *big.Int) {
+	if st.Voters == nil {
+		st.Voters = make(map[common.Hash32B]*big.Int)
+	}
+	if v, ok := st.Voters[key]; ok {
+		st.Voters[key] = new(big.Int).Add(v, weight)
+	} else {
+		st.Voters[key] = new(big.Int).Set(weight)
+	}
+}
+
+// stake returns the total balance the account can vote with
+func (st *State) stake() *big.Int {
+	return new(big.Int).Add(st.Balance, st.lockedBalance())
+}
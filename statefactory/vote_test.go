@@ -0,0 +1,238 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/iotxaddress"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestVote(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	voter, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(voter, 100)
+	assert.Nil(t, err)
+	var candidates []*iotxaddress.Address
+	for i := 0; i < 2; i++ {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		_, err = sf.CreateState(addr, 0)
+		assert.Nil(t, err)
+		candidates = append(candidates, addr)
+	}
+
+	assert.Equal(t, ErrNotCandidate, sf.Vote(voter, candidates[0], big.NewInt(1)))
+	assert.Nil(t, sf.RegisterCandidate(candidates[0], 1))
+	assert.Nil(t, sf.RegisterCandidate(candidates[1], 1))
+	assert.Equal(t, ErrInvalidVoteWeight, sf.Vote(voter, candidates[0], big.NewInt(0)))
+
+	// locked balance counts towards the stake, exactly the stake is allowed
+	assert.Nil(t, sf.Lock(voter, big.NewInt(30)))
+	assert.Equal(t, ErrInsufficientStake, sf.Vote(voter, candidates[0], big.NewInt(101)))
+	assert.Nil(t, sf.Vote(voter, candidates[0], big.NewInt(100)))
+	assert.Equal(t, ErrInsufficientStake, sf.Vote(voter, candidates[1], big.NewInt(1)))
+	state, err := sfi.getState(candidates[0])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(100)))
+	assert.Equal(t, 0, state.Voters[voterKey(voter)].Cmp(big.NewInt(100)))
+}
+
+func TestVoteSplitStake(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+
+	voter, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(voter, 100)
+	assert.Nil(t, err)
+	var candidates []*iotxaddress.Address
+	for i := 0; i < 2; i++ {
+		addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+		assert.Nil(t, err)
+		_, err = sf.CreateState(addr, 0)
+		assert.Nil(t, err)
+		assert.Nil(t, sf.RegisterCandidate(addr, 1))
+		candidates = append(candidates, addr)
+	}
+
+	assert.Nil(t, sf.Vote(voter, candidates[0], big.NewInt(40)))
+	assert.Nil(t, sf.Vote(voter, candidates[1], big.NewInt(50)))
+	assert.Equal(t, ErrInsufficientStake, sf.Vote(voter, candidates[1], big.NewInt(11)))
+	assert.Nil(t, sf.Vote(voter, candidates[1], big.NewInt(10)))
+	state, err := sfi.getState(candidates[1])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(60)))
+	state, err = sfi.getState(voter)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.VotedWeight.Cmp(big.NewInt(100)))
+
+	// spending after voting leaves the voter over-staked
+	overStaked, err := sf.AuditVotingStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(overStaked))
+	assert.Nil(t, sfi.updateState(voter, func(state *State) error {
+		return state.SubBalance(big.NewInt(1))
+	}))
+	overStaked, err = sf.AuditVotingStakes()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{voter.RawAddress}, overStaked)
+}
+
+func TestMaxVoters(t *testing.T) {
+	defer os.Remove(testTriePath)
+	tr, err := trie.NewTrie(testTriePath)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MaxVotersOption(3))
+	sfi := sf.(*stateFactory)
+
+	candidate, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	_, err = sf.CreateState(candidate, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+
+	voters := createAccounts(t, sf, 4, 10)
+	for _, voter := range voters[:3] {
+		assert.Nil(t, sf.Vote(voter, candidate, big.NewInt(1)))
+	}
+	assert.Equal(t, ErrTooManyVoters, sf.Vote(voters[3], candidate, big.NewInt(1)))
+	state, err := sfi.getState(voters[3])
+	assert.Nil(t, err)
+	assert.Equal(t, 0, state.votedWeight().Sign())
+
+	// an existing voter can still add weight
+	assert.Nil(t, sf.Vote(voters[0], candidate, big.NewInt(2)))
+	state, err = sfi.getState(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(state.Voters))
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(5)))
+}
+
+func TestMaxVotersConcurrent(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, MaxVotersOption(3))
+	addrs := createAccounts(t, sf, 33, 10)
+	candidate, voters := addrs[0], addrs[1:]
+	assert.Nil(t, sf.RegisterCandidate(candidate, 1))
+
+	// new voters racing for the last places never push the candidate past the cap
+	var accepted int32
+	var wg sync.WaitGroup
+	for _, voter := range voters {
+		wg.Add(1)
+		go func(voter *iotxaddress.Address) {
+			defer wg.Done()
+			err := sf.Vote(voter, candidate, big.NewInt(1))
+			if err == nil {
+				atomic.AddInt32(&accepted, 1)
+				return
+			}
+			assert.Equal(t, ErrTooManyVoters, err)
+		}(voter)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), accepted)
+	state, err := sf.GetState(candidate)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(state.Voters))
+	assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(3)))
+}
+
+func TestEmptyVoters(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	never := newState(addr, 100)
+
+	// a candidate whose only voter left and that stepped down is left with an empty map
+	once := newState(addr, 100)
+	once.IsCandidate = true
+	once.addVote(voterKey(addr), big.NewInt(10))
+	delete(once.Voters, voterKey(addr))
+	once.IsCandidate = false
+	assert.NotNil(t, once.Voters)
+
+	// both encode alike, so they hash alike in the trie
+	neverBytes, err := stateToBytes(never)
+	assert.Nil(t, err)
+	onceBytes, err := stateToBytes(once)
+	assert.Nil(t, err)
+	assert.Equal(t, neverBytes, onceBytes)
+	var roots []common.Hash32B
+	for _, ss := range [][]byte{neverBytes, onceBytes} {
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addr.PublicKey), ss))
+		roots = append(roots, tr.RootHash())
+	}
+	assert.Equal(t, roots[0], roots[1])
+
+	// a decoded account has no Voters, voting for it creates them
+	decoded, err := bytesToState(onceBytes, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Nil(t, decoded.Voters)
+	decoded.addVote(voterKey(addr), big.NewInt(5))
+	assert.Equal(t, 0, decoded.Voters[voterKey(addr)].Cmp(big.NewInt(5)))
+}
+
+func TestVotersEncoding(t *testing.T) {
+	addr, err := iotxaddress.NewAddress(true, []byte{0xa4, 0x00, 0x00, 0x00})
+	assert.Nil(t, err)
+	voters := make([]common.Hash32B, 6)
+	for i := range voters {
+		voters[i][0] = byte(0xf0 - i)
+	}
+
+	// gob writes map entries in random order, a candidate with several voters must still encode the same every time
+	var leaf []byte
+	var root common.Hash32B
+	for i := 0; i < 20; i++ {
+		state := newState(addr, 100)
+		state.IsCandidate = true
+		for j, voter := range voters {
+			state.addVote(voter, big.NewInt(int64(j+1)))
+		}
+		ss, err := stateToBytes(state)
+		assert.Nil(t, err)
+		tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+		assert.Nil(t, err)
+		assert.Nil(t, tr.Upsert(iotxaddress.HashPubKey(addr.PublicKey), ss))
+		if i == 0 {
+			leaf, root = ss, tr.RootHash()
+			continue
+		}
+		assert.Equal(t, leaf, ss)
+		assert.Equal(t, root, tr.RootHash())
+	}
+
+	// the voters read back as they were
+	decoded, err := bytesToState(leaf, defaultMaxLeafSize)
+	assert.Nil(t, err)
+	assert.Equal(t, len(voters), len(decoded.Voters))
+	for j, voter := range voters {
+		assert.Equal(t, 0, decoded.Voters[voter].Cmp(big.NewInt(int64(j+1))))
+	}
+}
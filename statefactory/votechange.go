@@ -0,0 +1,137 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/iotxaddress"
+)
+
+// VoteChange moves the weight of the voter's votes from the old candidate to the new one
+// A nil OldCandidate casts new votes, limited by the voter's stake as in Vote. A nil NewCandidate withdraws the votes.
+type VoteChange struct {
+	Voter        *iotxaddress.Address
+	OldCandidate *iotxaddress.Address
+	NewCandidate *iotxaddress.Address
+	Weight       *big.Int
+}
+
+// ApplyVoteChanges applies the vote changes of an epoch transition, either all of them or none
+// The changes are applied in order to the accounts read once each, so every affected candidate's VotingWeight and
+// Voters are written once, consistently. Any invalid change, e.g. to an account that is not a candidate or withdrawing
+// more than was voted or casting less than the minimum vote weight, fails the whole batch before anything is written.
+func (sf *stateFactory) ApplyVoteChanges(changes []VoteChange) error {
+	sf.mu.RLock()
+	defer sf.mu.RUnlock()
+	if err := sf.writable(); err != nil {
+		return err
+	}
+	var keys [][]byte
+	for _, change := range changes {
+		for _, addr := range []*iotxaddress.Address{change.Voter, change.OldCandidate, change.NewCandidate} {
+			if addr != nil {
+				keys = append(keys, iotxaddress.HashPubKey(addr.PublicKey))
+			}
+		}
+	}
+	unlock := sf.lockAccounts(keys...)
+	defer unlock()
+	states := make(map[AccountKey]*State)
+	addrs := make(map[AccountKey]*iotxaddress.Address)
+	load := func(addr *iotxaddress.Address) (*State, error) {
+		key := AccountKeyOf(addr)
+		if state, ok := states[key]; ok {
+			return state, nil
+		}
+		state, err := sf.getState(addr)
+		if err != nil {
+			return nil, err
+		}
+		states[key], addrs[key] = state, addr
+		return state, nil
+	}
+	var newVoters []*iotxaddress.Address
+	for i, change := range changes {
+		if err := sf.applyVoteChange(change, load); err != nil {
+			return errors.Wrapf(err, "vote change %d", i)
+		}
+		if change.OldCandidate == nil && change.NewCandidate != nil {
+			newVoters = append(newVoters, change.Voter)
+		}
+	}
+	if err := sf.writeStates(states, addrs, nil); err != nil {
+		return err
+	}
+	for _, voter := range newVoters {
+		if err := sf.addToAddressList(voterListKey, voter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyVoteChange applies the change to the States returned by load, which are changed in memory only
+func (sf *stateFactory) applyVoteChange(change VoteChange, load func(*iotxaddress.Address) (*State, error)) error {
+	if change.Weight == nil || change.Weight.Sign() <= 0 {
+		return ErrInvalidVoteWeight
+	}
+	if change.OldCandidate == nil && change.NewCandidate == nil {
+		return errors.Wrap(ErrInvalidVoteWeight, "no candidate")
+	}
+	voter, err := load(change.Voter)
+	if err != nil {
+		return err
+	}
+	key := voterKey(change.Voter)
+	if change.OldCandidate != nil {
+		old, err := load(change.OldCandidate)
+		if err != nil {
+			return err
+		}
+		voted, ok := old.Voters[key]
+		if !ok || voted.Cmp(change.Weight) < 0 {
+			return errors.Wrap(ErrInvalidVoteWeight, "more than voted to the old candidate")
+		}
+		if left := new(big.Int).Sub(voted, change.Weight); left.Sign() > 0 {
+			old.Voters[key] = left
+		} else {
+			delete(old.Voters, key)
+		}
+		old.VotingWeight = new(big.Int).Sub(old.VotingWeight, change.Weight)
+	}
+	if change.NewCandidate != nil {
+		if err := sf.checkVoteWeight(change.Weight); err != nil {
+			return err
+		}
+		candidate, err := load(change.NewCandidate)
+		if err != nil {
+			return err
+		}
+		if !candidate.IsCandidate {
+			return ErrNotCandidate
+		}
+		if _, ok := candidate.Voters[key]; !ok && len(candidate.Voters) >= sf.maxVoters {
+			return ErrTooManyVoters
+		}
+		candidate.addVote(key, change.Weight)
+		candidate.VotingWeight = new(big.Int).Add(candidate.VotingWeight, change.Weight)
+	}
+	switch {
+	case change.OldCandidate == nil:
+		voted := new(big.Int).Add(voter.votedWeight(), change.Weight)
+		if voted.Cmp(voter.stake()) > 0 {
+			return ErrInsufficientStake
+		}
+		voter.VotedWeight = voted
+	case change.NewCandidate == nil:
+		voter.VotedWeight = new(big.Int).Sub(voter.votedWeight(), change.Weight)
+	}
+	return nil
+}
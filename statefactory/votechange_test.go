@@ -0,0 +1,83 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestApplyVoteChanges(t *testing.T) {
+	tr, err := trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr)
+	sfi := sf.(*stateFactory)
+	addrs := createAccounts(t, sf, 5, 100)
+	voters, candidates, other := addrs[:2], addrs[2:4], addrs[4]
+	for _, c := range candidates {
+		assert.Nil(t, sf.RegisterCandidate(c, 1))
+	}
+	assert.Nil(t, sf.Vote(voters[0], candidates[0], big.NewInt(60)))
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	root := sf.RootHash()
+
+	// one invalid change reverts the whole batch
+	valid := []VoteChange{
+		{Voter: voters[0], OldCandidate: candidates[0], NewCandidate: candidates[1], Weight: big.NewInt(20)},
+		{Voter: voters[1], NewCandidate: candidates[0], Weight: big.NewInt(50)},
+		{Voter: voters[0], OldCandidate: candidates[0], Weight: big.NewInt(10)},
+	}
+	for _, invalid := range []VoteChange{
+		{Voter: voters[1], NewCandidate: other, Weight: big.NewInt(1)},
+		{Voter: voters[1], OldCandidate: candidates[1], NewCandidate: candidates[0], Weight: big.NewInt(1)},
+		{Voter: voters[0], OldCandidate: candidates[0], Weight: big.NewInt(31)},
+		{Voter: voters[1], NewCandidate: candidates[1], Weight: big.NewInt(51)},
+		{Voter: voters[1], NewCandidate: candidates[1], Weight: big.NewInt(0)},
+	} {
+		assert.NotNil(t, sf.ApplyVoteChanges(append(valid, invalid)))
+		assert.Equal(t, root, sf.RootHash())
+		assert.Empty(t, sf.(Monitor).PendingChanges())
+	}
+	err = sf.ApplyVoteChanges(append(valid, VoteChange{Voter: voters[1], NewCandidate: other, Weight: big.NewInt(1)}))
+	assert.Equal(t, ErrNotCandidate, errors.Cause(err))
+
+	assert.Nil(t, sf.ApplyVoteChanges(valid))
+	for _, c := range []struct {
+		idx    int
+		weight int64
+		voters map[int]int64
+	}{
+		{0, 80, map[int]int64{0: 30, 1: 50}},
+		{1, 20, map[int]int64{0: 20}},
+	} {
+		state, err := sfi.getState(candidates[c.idx])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, state.VotingWeight.Cmp(big.NewInt(c.weight)))
+		assert.Equal(t, len(c.voters), len(state.Voters))
+		for v, w := range c.voters {
+			assert.Equal(t, 0, state.Voters[voterKey(voters[v])].Cmp(big.NewInt(w)))
+		}
+	}
+	for i, weight := range []int64{50, 50} {
+		state, err := sfi.getState(voters[i])
+		assert.Nil(t, err)
+		assert.Equal(t, 0, state.votedWeight().Cmp(big.NewInt(weight)))
+	}
+	overStaked, err := sf.AuditVotingStakes()
+	assert.Nil(t, err)
+	assert.Empty(t, overStaked)
+	n, err := sf.RepairVotingWeights()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+// KVWrite is a raw record a commit wrote to or deleted from the KV store, see CaptureWritesOption
+type KVWrite struct {
+	Namespace string
+	Key       []byte
+	OldValue  []byte // nil if the key was not stored before
+	NewValue  []byte // nil if the key was deleted
+}
+
+// CaptureWritesOption makes every commit record the raw records it writes to the KV store, see LastCommitWrites
+// It is a debugging aid, e.g. to diff the storage-level effect of a block against another node's. The trie reads every
+// key it writes beforehand to learn the old value, so commits get slower. It has no effect if the trie cannot record.
+func CaptureWritesOption() Option {
+	return func(sf *stateFactory) {
+		if c, ok := sf.trie.(interface {
+			CaptureWrites(bool)
+		}); ok {
+			c.CaptureWrites(true)
+		}
+	}
+}
+
+// LastCommitWrites returns the records the last commit wrote, sorted by key, nil without CaptureWritesOption
+func (sf *stateFactory) LastCommitWrites() []KVWrite {
+	c, ok := sf.trie.(interface {
+		LastCommitWrites() []trie.KVWrite
+	})
+	if !ok {
+		return nil
+	}
+	var writes []KVWrite
+	for _, w := range c.LastCommitWrites() {
+		writes = append(writes, KVWrite{Namespace: w.Namespace, Key: w.Key, OldValue: w.OldValue, NewValue: w.NewValue})
+	}
+	return writes
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package statefactory
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+	"github.com/iotexproject/iotex-core/trie"
+)
+
+func TestLastCommitWrites(t *testing.T) {
+	dao := db.NewMemKVStore()
+	tr, err := trie.NewTrieSharedDB(dao)
+	assert.Nil(t, err)
+	sf := NewStateFactory(tr, CaptureWritesOption())
+	sfi := sf.(*stateFactory)
+	addrs := createAccounts(t, sf, 3, 100)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	state, err := sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	oldLeaf, err := sfi.encodeState(state)
+	assert.Nil(t, err)
+
+	// a block changing one balance writes its new leaf and deletes the old one
+	assert.Nil(t, sf.AddBalance(addrs[0], big.NewInt(5)))
+	state, err = sf.GetState(addrs[0])
+	assert.Nil(t, err)
+	newLeaf, err := sfi.encodeState(state)
+	assert.Nil(t, err)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	writes := sf.(Monitor).LastCommitWrites()
+	var written, deleted bool
+	for _, w := range writes {
+		stored, err := dao.Get(w.Namespace, w.Key)
+		if w.NewValue == nil {
+			assert.NotNil(t, err)
+			deleted = deleted || bytes.Contains(w.OldValue, oldLeaf)
+			continue
+		}
+		assert.Equal(t, w.NewValue, stored)
+		written = written || bytes.Contains(w.NewValue, newLeaf)
+	}
+	assert.True(t, written)
+	assert.True(t, deleted)
+
+	// the leaves of untouched accounts are not written again
+	for _, addr := range addrs[1:] {
+		state, err := sf.GetState(addr)
+		assert.Nil(t, err)
+		leaf, err := sfi.encodeState(state)
+		assert.Nil(t, err)
+		for _, w := range writes {
+			assert.False(t, bytes.Contains(w.NewValue, leaf))
+		}
+	}
+
+	// nothing is recorded without the option
+	tr, err = trie.NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(t, err)
+	sf = NewStateFactory(tr)
+	createAccounts(t, sf, 1, 100)
+	_, err = sf.Commit()
+	assert.Nil(t, err)
+	assert.Nil(t, sf.(Monitor).LastCommitWrites())
+}
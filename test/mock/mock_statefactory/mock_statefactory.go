@@ -10,7 +10,9 @@ import (
 	common "github.com/iotexproject/iotex-core/common"
 	iotxaddress "github.com/iotexproject/iotex-core/iotxaddress"
 	statefactory "github.com/iotexproject/iotex-core/statefactory"
+	io "io"
 	big "math/big"
+	rand "math/rand"
 	reflect "reflect"
 )
 
@@ -38,16 +40,16 @@ func (m *MockStateFactory) EXPECT() *MockStateFactoryMockRecorder {
 }
 
 // CreateState mocks base method
-func (m *MockStateFactory) CreateState(arg0 *iotxaddress.Address) (*statefactory.State, error) {
-	ret := m.ctrl.Call(m, "CreateState", arg0)
+func (m *MockStateFactory) CreateState(arg0 *iotxaddress.Address, arg1 uint64) (*statefactory.State, error) {
+	ret := m.ctrl.Call(m, "CreateState", arg0, arg1)
 	ret0, _ := ret[0].(*statefactory.State)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateState indicates an expected call of CreateState
-func (mr *MockStateFactoryMockRecorder) CreateState(arg0 interface{}) *gomock.Call {
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockStateFactory)(nil).CreateState), arg0)
+func (mr *MockStateFactoryMockRecorder) CreateState(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateState", reflect.TypeOf((*MockStateFactory)(nil).CreateState), arg0, arg1)
 }
 
 // Balance mocks base method
@@ -63,6 +65,43 @@ func (mr *MockStateFactoryMockRecorder) Balance(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Balance", reflect.TypeOf((*MockStateFactory)(nil).Balance), arg0)
 }
 
+// BalanceDecimal mocks base method
+func (m *MockStateFactory) BalanceDecimal(arg0 *iotxaddress.Address) (string, error) {
+	ret := m.ctrl.Call(m, "BalanceDecimal", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceDecimal indicates an expected call of BalanceDecimal
+func (mr *MockStateFactoryMockRecorder) BalanceDecimal(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceDecimal", reflect.TypeOf((*MockStateFactory)(nil).BalanceDecimal), arg0)
+}
+
+// AddBalance mocks base method
+func (m *MockStateFactory) AddBalance(arg0 *iotxaddress.Address, arg1 *big.Int) error {
+	ret := m.ctrl.Call(m, "AddBalance", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddBalance indicates an expected call of AddBalance
+func (mr *MockStateFactoryMockRecorder) AddBalance(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBalance", reflect.TypeOf((*MockStateFactory)(nil).AddBalance), arg0, arg1)
+}
+
+// Burn mocks base method
+func (m *MockStateFactory) Burn(arg0 *iotxaddress.Address, arg1 *big.Int) error {
+	ret := m.ctrl.Call(m, "Burn", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Burn indicates an expected call of Burn
+func (mr *MockStateFactoryMockRecorder) Burn(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Burn", reflect.TypeOf((*MockStateFactory)(nil).Burn), arg0, arg1)
+}
+
 // UpdateStatesWithTransfer mocks base method
 func (m *MockStateFactory) UpdateStatesWithTransfer(arg0 []*trx.Tx) error {
 	ret := m.ctrl.Call(m, "UpdateStatesWithTransfer", arg0)
@@ -75,6 +114,42 @@ func (mr *MockStateFactoryMockRecorder) UpdateStatesWithTransfer(arg0 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatesWithTransfer", reflect.TypeOf((*MockStateFactory)(nil).UpdateStatesWithTransfer), arg0)
 }
 
+// ApplyTransferTx mocks base method
+func (m *MockStateFactory) ApplyTransferTx(arg0, arg1 *iotxaddress.Address, arg2 *big.Int, arg3 uint64) error {
+	ret := m.ctrl.Call(m, "ApplyTransferTx", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyTransferTx indicates an expected call of ApplyTransferTx
+func (mr *MockStateFactoryMockRecorder) ApplyTransferTx(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyTransferTx", reflect.TypeOf((*MockStateFactory)(nil).ApplyTransferTx), arg0, arg1, arg2, arg3)
+}
+
+// ApplySponsoredTx mocks base method
+func (m *MockStateFactory) ApplySponsoredTx(arg0, arg1, arg2 *iotxaddress.Address, arg3, arg4 *big.Int, arg5 uint64) error {
+	ret := m.ctrl.Call(m, "ApplySponsoredTx", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplySponsoredTx indicates an expected call of ApplySponsoredTx
+func (mr *MockStateFactoryMockRecorder) ApplySponsoredTx(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplySponsoredTx", reflect.TypeOf((*MockStateFactory)(nil).ApplySponsoredTx), arg0, arg1, arg2, arg3, arg4, arg5)
+}
+
+// AuthorizedTransfer mocks base method
+func (m *MockStateFactory) AuthorizedTransfer(arg0, arg1 *iotxaddress.Address, arg2 *big.Int, arg3 uint64, arg4 []byte) error {
+	ret := m.ctrl.Call(m, "AuthorizedTransfer", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AuthorizedTransfer indicates an expected call of AuthorizedTransfer
+func (mr *MockStateFactoryMockRecorder) AuthorizedTransfer(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuthorizedTransfer", reflect.TypeOf((*MockStateFactory)(nil).AuthorizedTransfer), arg0, arg1, arg2, arg3, arg4)
+}
+
 // SetNonce mocks base method
 func (m *MockStateFactory) SetNonce(arg0 *iotxaddress.Address, arg1 uint64) error {
 	ret := m.ctrl.Call(m, "SetNonce", arg0, arg1)
@@ -100,6 +175,20 @@ func (mr *MockStateFactoryMockRecorder) Nonce(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Nonce", reflect.TypeOf((*MockStateFactory)(nil).Nonce), arg0)
 }
 
+// NonceAndBalance mocks base method
+func (m *MockStateFactory) NonceAndBalance(arg0 *iotxaddress.Address) (uint64, *big.Int, error) {
+	ret := m.ctrl.Call(m, "NonceAndBalance", arg0)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(*big.Int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// NonceAndBalance indicates an expected call of NonceAndBalance
+func (mr *MockStateFactoryMockRecorder) NonceAndBalance(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NonceAndBalance", reflect.TypeOf((*MockStateFactory)(nil).NonceAndBalance), arg0)
+}
+
 // RootHash mocks base method
 func (m *MockStateFactory) RootHash() common.Hash32B {
 	ret := m.ctrl.Call(m, "RootHash")
@@ -111,3 +200,970 @@ func (m *MockStateFactory) RootHash() common.Hash32B {
 func (mr *MockStateFactoryMockRecorder) RootHash() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RootHash", reflect.TypeOf((*MockStateFactory)(nil).RootHash))
 }
+
+// Commit mocks base method
+func (m *MockStateFactory) Commit() (statefactory.CommitStats, error) {
+	ret := m.ctrl.Call(m, "Commit")
+	ret0, _ := ret[0].(statefactory.CommitStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Commit indicates an expected call of Commit
+func (mr *MockStateFactoryMockRecorder) Commit() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commit", reflect.TypeOf((*MockStateFactory)(nil).Commit))
+}
+
+// CommitWithHeight mocks base method
+func (m *MockStateFactory) CommitWithHeight(arg0 uint64) (statefactory.CommitStats, error) {
+	ret := m.ctrl.Call(m, "CommitWithHeight", arg0)
+	ret0, _ := ret[0].(statefactory.CommitStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CommitWithHeight indicates an expected call of CommitWithHeight
+func (mr *MockStateFactoryMockRecorder) CommitWithHeight(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitWithHeight", reflect.TypeOf((*MockStateFactory)(nil).CommitWithHeight), arg0)
+}
+
+// Compact mocks base method
+func (m *MockStateFactory) Compact() error {
+	ret := m.ctrl.Call(m, "Compact")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Compact indicates an expected call of Compact
+func (mr *MockStateFactoryMockRecorder) Compact() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Compact", reflect.TypeOf((*MockStateFactory)(nil).Compact))
+}
+
+// DeleteAll mocks base method
+func (m *MockStateFactory) DeleteAll() error {
+	ret := m.ctrl.Call(m, "DeleteAll")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAll indicates an expected call of DeleteAll
+func (mr *MockStateFactoryMockRecorder) DeleteAll() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAll", reflect.TypeOf((*MockStateFactory)(nil).DeleteAll))
+}
+
+// CheckParams mocks base method
+func (m *MockStateFactory) CheckParams() error {
+	ret := m.ctrl.Call(m, "CheckParams")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckParams indicates an expected call of CheckParams
+func (mr *MockStateFactoryMockRecorder) CheckParams() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckParams", reflect.TypeOf((*MockStateFactory)(nil).CheckParams))
+}
+
+// RegisterName mocks base method
+func (m *MockStateFactory) RegisterName(arg0 string, arg1 *iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "RegisterName", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterName indicates an expected call of RegisterName
+func (mr *MockStateFactoryMockRecorder) RegisterName(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterName", reflect.TypeOf((*MockStateFactory)(nil).RegisterName), arg0, arg1)
+}
+
+// ResolveName mocks base method
+func (m *MockStateFactory) ResolveName(arg0 string) (*iotxaddress.Address, error) {
+	ret := m.ctrl.Call(m, "ResolveName", arg0)
+	ret0, _ := ret[0].(*iotxaddress.Address)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveName indicates an expected call of ResolveName
+func (mr *MockStateFactoryMockRecorder) ResolveName(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveName", reflect.TypeOf((*MockStateFactory)(nil).ResolveName), arg0)
+}
+
+// Lock mocks base method
+func (m *MockStateFactory) Lock(arg0 *iotxaddress.Address, arg1 *big.Int) error {
+	ret := m.ctrl.Call(m, "Lock", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Lock indicates an expected call of Lock
+func (mr *MockStateFactoryMockRecorder) Lock(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Lock", reflect.TypeOf((*MockStateFactory)(nil).Lock), arg0, arg1)
+}
+
+// Unlock mocks base method
+func (m *MockStateFactory) Unlock(arg0 *iotxaddress.Address, arg1 *big.Int) error {
+	ret := m.ctrl.Call(m, "Unlock", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Unlock indicates an expected call of Unlock
+func (mr *MockStateFactoryMockRecorder) Unlock(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unlock", reflect.TypeOf((*MockStateFactory)(nil).Unlock), arg0, arg1)
+}
+
+// RegisterCandidate mocks base method
+func (m *MockStateFactory) RegisterCandidate(arg0 *iotxaddress.Address, arg1 uint64) error {
+	ret := m.ctrl.Call(m, "RegisterCandidate", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RegisterCandidate indicates an expected call of RegisterCandidate
+func (mr *MockStateFactoryMockRecorder) RegisterCandidate(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterCandidate", reflect.TypeOf((*MockStateFactory)(nil).RegisterCandidate), arg0, arg1)
+}
+
+// RankedCandidates mocks base method
+func (m *MockStateFactory) RankedCandidates() ([]statefactory.CandidateInfo, error) {
+	ret := m.ctrl.Call(m, "RankedCandidates")
+	ret0, _ := ret[0].([]statefactory.CandidateInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RankedCandidates indicates an expected call of RankedCandidates
+func (mr *MockStateFactoryMockRecorder) RankedCandidates() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RankedCandidates", reflect.TypeOf((*MockStateFactory)(nil).RankedCandidates))
+}
+
+// TallyVotes mocks base method
+func (m *MockStateFactory) TallyVotes() (map[string]*big.Int, common.Hash32B, error) {
+	ret := m.ctrl.Call(m, "TallyVotes")
+	ret0, _ := ret[0].(map[string]*big.Int)
+	ret1, _ := ret[1].(common.Hash32B)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// TallyVotes indicates an expected call of TallyVotes
+func (mr *MockStateFactoryMockRecorder) TallyVotes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TallyVotes", reflect.TypeOf((*MockStateFactory)(nil).TallyVotes))
+}
+
+// RepairVotingWeights mocks base method
+func (m *MockStateFactory) RepairVotingWeights() (int, error) {
+	ret := m.ctrl.Call(m, "RepairVotingWeights")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RepairVotingWeights indicates an expected call of RepairVotingWeights
+func (mr *MockStateFactoryMockRecorder) RepairVotingWeights() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairVotingWeights", reflect.TypeOf((*MockStateFactory)(nil).RepairVotingWeights))
+}
+
+// Vote mocks base method
+func (m *MockStateFactory) Vote(arg0, arg1 *iotxaddress.Address, arg2 *big.Int) error {
+	ret := m.ctrl.Call(m, "Vote", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Vote indicates an expected call of Vote
+func (mr *MockStateFactoryMockRecorder) Vote(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Vote", reflect.TypeOf((*MockStateFactory)(nil).Vote), arg0, arg1, arg2)
+}
+
+// ApplyVoteChanges mocks base method
+func (m *MockStateFactory) ApplyVoteChanges(arg0 []statefactory.VoteChange) error {
+	ret := m.ctrl.Call(m, "ApplyVoteChanges", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyVoteChanges indicates an expected call of ApplyVoteChanges
+func (mr *MockStateFactoryMockRecorder) ApplyVoteChanges(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyVoteChanges", reflect.TypeOf((*MockStateFactory)(nil).ApplyVoteChanges), arg0)
+}
+
+// AuditVotingStakes mocks base method
+func (m *MockStateFactory) AuditVotingStakes() ([]string, error) {
+	ret := m.ctrl.Call(m, "AuditVotingStakes")
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AuditVotingStakes indicates an expected call of AuditVotingStakes
+func (mr *MockStateFactoryMockRecorder) AuditVotingStakes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AuditVotingStakes", reflect.TypeOf((*MockStateFactory)(nil).AuditVotingStakes))
+}
+
+// DeleteState mocks base method
+func (m *MockStateFactory) DeleteState(arg0 *iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "DeleteState", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteState indicates an expected call of DeleteState
+func (mr *MockStateFactoryMockRecorder) DeleteState(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteState", reflect.TypeOf((*MockStateFactory)(nil).DeleteState), arg0)
+}
+
+// ResetAccount mocks base method
+func (m *MockStateFactory) ResetAccount(arg0 *iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "ResetAccount", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResetAccount indicates an expected call of ResetAccount
+func (mr *MockStateFactoryMockRecorder) ResetAccount(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetAccount", reflect.TypeOf((*MockStateFactory)(nil).ResetAccount), arg0)
+}
+
+// RotateKey mocks base method
+func (m *MockStateFactory) RotateKey(arg0, arg1 *iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "RotateKey", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateKey indicates an expected call of RotateKey
+func (mr *MockStateFactoryMockRecorder) RotateKey(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKey", reflect.TypeOf((*MockStateFactory)(nil).RotateKey), arg0, arg1)
+}
+
+// AccountCount mocks base method
+func (m *MockStateFactory) AccountCount() (uint64, error) {
+	ret := m.ctrl.Call(m, "AccountCount")
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AccountCount indicates an expected call of AccountCount
+func (mr *MockStateFactoryMockRecorder) AccountCount() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccountCount", reflect.TypeOf((*MockStateFactory)(nil).AccountCount))
+}
+
+// GetState mocks base method
+func (m *MockStateFactory) GetState(arg0 *iotxaddress.Address) (*statefactory.State, error) {
+	ret := m.ctrl.Call(m, "GetState", arg0)
+	ret0, _ := ret[0].(*statefactory.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetState indicates an expected call of GetState
+func (mr *MockStateFactoryMockRecorder) GetState(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetState", reflect.TypeOf((*MockStateFactory)(nil).GetState), arg0)
+}
+
+// GetStateByKey mocks base method
+func (m *MockStateFactory) GetStateByKey(arg0 statefactory.AccountKey) (*statefactory.State, error) {
+	ret := m.ctrl.Call(m, "GetStateByKey", arg0)
+	ret0, _ := ret[0].(*statefactory.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStateByKey indicates an expected call of GetStateByKey
+func (mr *MockStateFactoryMockRecorder) GetStateByKey(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStateByKey", reflect.TypeOf((*MockStateFactory)(nil).GetStateByKey), arg0)
+}
+
+// SetCode mocks base method
+func (m *MockStateFactory) SetCode(arg0 *iotxaddress.Address, arg1 []byte) error {
+	ret := m.ctrl.Call(m, "SetCode", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetCode indicates an expected call of SetCode
+func (mr *MockStateFactoryMockRecorder) SetCode(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCode", reflect.TypeOf((*MockStateFactory)(nil).SetCode), arg0, arg1)
+}
+
+// Code mocks base method
+func (m *MockStateFactory) Code(arg0 *iotxaddress.Address) ([]byte, error) {
+	ret := m.ctrl.Call(m, "Code", arg0)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Code indicates an expected call of Code
+func (mr *MockStateFactoryMockRecorder) Code(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Code", reflect.TypeOf((*MockStateFactory)(nil).Code), arg0)
+}
+
+// IsContract mocks base method
+func (m *MockStateFactory) IsContract(arg0 *iotxaddress.Address) (bool, error) {
+	ret := m.ctrl.Call(m, "IsContract", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsContract indicates an expected call of IsContract
+func (mr *MockStateFactoryMockRecorder) IsContract(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsContract", reflect.TypeOf((*MockStateFactory)(nil).IsContract), arg0)
+}
+
+// ApplyBatch mocks base method
+func (m *MockStateFactory) ApplyBatch(arg0 []statefactory.StateChange) error {
+	ret := m.ctrl.Call(m, "ApplyBatch", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyBatch indicates an expected call of ApplyBatch
+func (mr *MockStateFactoryMockRecorder) ApplyBatch(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyBatch", reflect.TypeOf((*MockStateFactory)(nil).ApplyBatch), arg0)
+}
+
+// SetHeight mocks base method
+func (m *MockStateFactory) SetHeight(arg0 uint64) {
+	m.ctrl.Call(m, "SetHeight", arg0)
+}
+
+// SetHeight indicates an expected call of SetHeight
+func (mr *MockStateFactoryMockRecorder) SetHeight(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetHeight", reflect.TypeOf((*MockStateFactory)(nil).SetHeight), arg0)
+}
+
+// SetVesting mocks base method
+func (m *MockStateFactory) SetVesting(arg0 *iotxaddress.Address, arg1 []statefactory.VestingPoint) error {
+	ret := m.ctrl.Call(m, "SetVesting", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetVesting indicates an expected call of SetVesting
+func (mr *MockStateFactoryMockRecorder) SetVesting(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVesting", reflect.TypeOf((*MockStateFactory)(nil).SetVesting), arg0, arg1)
+}
+
+// Vested mocks base method
+func (m *MockStateFactory) Vested(arg0 *iotxaddress.Address, arg1 uint64) (*big.Int, error) {
+	ret := m.ctrl.Call(m, "Vested", arg0, arg1)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Vested indicates an expected call of Vested
+func (mr *MockStateFactoryMockRecorder) Vested(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Vested", reflect.TypeOf((*MockStateFactory)(nil).Vested), arg0, arg1)
+}
+
+// Commitment mocks base method
+func (m *MockStateFactory) Commitment(arg0 *big.Int) (*statefactory.StateCommitment, error) {
+	ret := m.ctrl.Call(m, "Commitment", arg0)
+	ret0, _ := ret[0].(*statefactory.StateCommitment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Commitment indicates an expected call of Commitment
+func (mr *MockStateFactoryMockRecorder) Commitment(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Commitment", reflect.TypeOf((*MockStateFactory)(nil).Commitment), arg0)
+}
+
+// SelfCheck mocks base method
+func (m *MockStateFactory) SelfCheck() error {
+	ret := m.ctrl.Call(m, "SelfCheck")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SelfCheck indicates an expected call of SelfCheck
+func (mr *MockStateFactoryMockRecorder) SelfCheck() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelfCheck", reflect.TypeOf((*MockStateFactory)(nil).SelfCheck))
+}
+
+// SetMeta mocks base method
+func (m *MockStateFactory) SetMeta(arg0 *iotxaddress.Address, arg1, arg2 []byte) error {
+	ret := m.ctrl.Call(m, "SetMeta", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMeta indicates an expected call of SetMeta
+func (mr *MockStateFactoryMockRecorder) SetMeta(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMeta", reflect.TypeOf((*MockStateFactory)(nil).SetMeta), arg0, arg1, arg2)
+}
+
+// GetMeta mocks base method
+func (m *MockStateFactory) GetMeta(arg0 *iotxaddress.Address, arg1 []byte) ([]byte, error) {
+	ret := m.ctrl.Call(m, "GetMeta", arg0, arg1)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMeta indicates an expected call of GetMeta
+func (mr *MockStateFactoryMockRecorder) GetMeta(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMeta", reflect.TypeOf((*MockStateFactory)(nil).GetMeta), arg0, arg1)
+}
+
+// Preload mocks base method
+func (m *MockStateFactory) Preload(arg0 []*iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "Preload", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Preload indicates an expected call of Preload
+func (mr *MockStateFactoryMockRecorder) Preload(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Preload", reflect.TypeOf((*MockStateFactory)(nil).Preload), arg0)
+}
+
+// ApplyOnce mocks base method
+func (m *MockStateFactory) ApplyOnce(arg0 common.Hash32B, arg1 func() error) error {
+	ret := m.ctrl.Call(m, "ApplyOnce", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyOnce indicates an expected call of ApplyOnce
+func (mr *MockStateFactoryMockRecorder) ApplyOnce(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyOnce", reflect.TypeOf((*MockStateFactory)(nil).ApplyOnce), arg0, arg1)
+}
+
+// Reserve mocks base method
+func (m *MockStateFactory) Reserve(arg0 int) {
+	m.ctrl.Call(m, "Reserve", arg0)
+}
+
+// Reserve indicates an expected call of Reserve
+func (mr *MockStateFactoryMockRecorder) Reserve(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockStateFactory)(nil).Reserve), arg0)
+}
+
+// Slash mocks base method
+func (m *MockStateFactory) Slash(arg0 *iotxaddress.Address, arg1 *big.Rat) error {
+	ret := m.ctrl.Call(m, "Slash", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Slash indicates an expected call of Slash
+func (mr *MockStateFactoryMockRecorder) Slash(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Slash", reflect.TypeOf((*MockStateFactory)(nil).Slash), arg0, arg1)
+}
+
+// Close mocks base method
+func (m *MockStateFactory) Close() error {
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close
+func (mr *MockStateFactoryMockRecorder) Close() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStateFactory)(nil).Close))
+}
+
+// BalanceOf mocks base method
+func (m *MockStateFactory) BalanceOf(arg0 *iotxaddress.Address, arg1 statefactory.AssetID) (*big.Int, error) {
+	ret := m.ctrl.Call(m, "BalanceOf", arg0, arg1)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceOf indicates an expected call of BalanceOf
+func (mr *MockStateFactoryMockRecorder) BalanceOf(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceOf", reflect.TypeOf((*MockStateFactory)(nil).BalanceOf), arg0, arg1)
+}
+
+// AddBalanceOf mocks base method
+func (m *MockStateFactory) AddBalanceOf(arg0 *iotxaddress.Address, arg1 statefactory.AssetID, arg2 *big.Int) error {
+	ret := m.ctrl.Call(m, "AddBalanceOf", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddBalanceOf indicates an expected call of AddBalanceOf
+func (mr *MockStateFactoryMockRecorder) AddBalanceOf(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddBalanceOf", reflect.TypeOf((*MockStateFactory)(nil).AddBalanceOf), arg0, arg1, arg2)
+}
+
+// SubBalanceOf mocks base method
+func (m *MockStateFactory) SubBalanceOf(arg0 *iotxaddress.Address, arg1 statefactory.AssetID, arg2 *big.Int) error {
+	ret := m.ctrl.Call(m, "SubBalanceOf", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SubBalanceOf indicates an expected call of SubBalanceOf
+func (mr *MockStateFactoryMockRecorder) SubBalanceOf(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubBalanceOf", reflect.TypeOf((*MockStateFactory)(nil).SubBalanceOf), arg0, arg1, arg2)
+}
+
+// SupplyDelta mocks base method
+func (m *MockStateFactory) SupplyDelta() (*big.Int, error) {
+	ret := m.ctrl.Call(m, "SupplyDelta")
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SupplyDelta indicates an expected call of SupplyDelta
+func (mr *MockStateFactoryMockRecorder) SupplyDelta() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SupplyDelta", reflect.TypeOf((*MockStateFactory)(nil).SupplyDelta))
+}
+
+// EffectivePower mocks base method
+func (m *MockStateFactory) EffectivePower(arg0 *iotxaddress.Address) (*big.Int, error) {
+	ret := m.ctrl.Call(m, "EffectivePower", arg0)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EffectivePower indicates an expected call of EffectivePower
+func (mr *MockStateFactoryMockRecorder) EffectivePower(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EffectivePower", reflect.TypeOf((*MockStateFactory)(nil).EffectivePower), arg0)
+}
+
+// ReconcileStakes mocks base method
+func (m *MockStateFactory) ReconcileStakes() ([]statefactory.StakeInconsistency, error) {
+	ret := m.ctrl.Call(m, "ReconcileStakes")
+	ret0, _ := ret[0].([]statefactory.StakeInconsistency)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReconcileStakes indicates an expected call of ReconcileStakes
+func (mr *MockStateFactoryMockRecorder) ReconcileStakes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReconcileStakes", reflect.TypeOf((*MockStateFactory)(nil).ReconcileStakes))
+}
+
+// InvalidateCache mocks base method
+func (m *MockStateFactory) InvalidateCache(arg0 *iotxaddress.Address) error {
+	ret := m.ctrl.Call(m, "InvalidateCache", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InvalidateCache indicates an expected call of InvalidateCache
+func (mr *MockStateFactoryMockRecorder) InvalidateCache(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateCache", reflect.TypeOf((*MockStateFactory)(nil).InvalidateCache), arg0)
+}
+
+// AddStateWithInit mocks base method
+func (m *MockStateFactory) AddStateWithInit(arg0 *iotxaddress.Address, arg1 statefactory.State) (*statefactory.State, error) {
+	ret := m.ctrl.Call(m, "AddStateWithInit", arg0, arg1)
+	ret0, _ := ret[0].(*statefactory.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddStateWithInit indicates an expected call of AddStateWithInit
+func (mr *MockStateFactoryMockRecorder) AddStateWithInit(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddStateWithInit", reflect.TypeOf((*MockStateFactory)(nil).AddStateWithInit), arg0, arg1)
+}
+
+// ApplyTransferTxWithReceipt mocks base method
+func (m *MockStateFactory) ApplyTransferTxWithReceipt(arg0, arg1 *iotxaddress.Address, arg2 *big.Int, arg3 uint64) (*statefactory.TransferReceipt, error) {
+	ret := m.ctrl.Call(m, "ApplyTransferTxWithReceipt", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*statefactory.TransferReceipt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ApplyTransferTxWithReceipt indicates an expected call of ApplyTransferTxWithReceipt
+func (mr *MockStateFactoryMockRecorder) ApplyTransferTxWithReceipt(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyTransferTxWithReceipt", reflect.TypeOf((*MockStateFactory)(nil).ApplyTransferTxWithReceipt), arg0, arg1, arg2, arg3)
+}
+
+// SweepDustVotes mocks base method
+func (m *MockStateFactory) SweepDustVotes() (int, error) {
+	ret := m.ctrl.Call(m, "SweepDustVotes")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SweepDustVotes indicates an expected call of SweepDustVotes
+func (mr *MockStateFactoryMockRecorder) SweepDustVotes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SweepDustVotes", reflect.TypeOf((*MockStateFactory)(nil).SweepDustVotes))
+}
+
+// Airdrop mocks base method
+func (m *MockStateFactory) Airdrop(arg0 *iotxaddress.Address, arg1 map[string]*big.Int) error {
+	ret := m.ctrl.Call(m, "Airdrop", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Airdrop indicates an expected call of Airdrop
+func (mr *MockStateFactoryMockRecorder) Airdrop(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Airdrop", reflect.TypeOf((*MockStateFactory)(nil).Airdrop), arg0, arg1)
+}
+
+// RecentRoots mocks base method
+func (m *MockStateFactory) RecentRoots(arg0 int) ([]statefactory.RootAtHeight, error) {
+	ret := m.ctrl.Call(m, "RecentRoots", arg0)
+	ret0, _ := ret[0].([]statefactory.RootAtHeight)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecentRoots indicates an expected call of RecentRoots
+func (mr *MockStateFactoryMockRecorder) RecentRoots(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecentRoots", reflect.TypeOf((*MockStateFactory)(nil).RecentRoots), arg0)
+}
+
+// InitGenesis mocks base method
+func (m *MockStateFactory) InitGenesis(arg0 []statefactory.GenesisAccount) (common.Hash32B, error) {
+	ret := m.ctrl.Call(m, "InitGenesis", arg0)
+	ret0, _ := ret[0].(common.Hash32B)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InitGenesis indicates an expected call of InitGenesis
+func (mr *MockStateFactoryMockRecorder) InitGenesis(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InitGenesis", reflect.TypeOf((*MockStateFactory)(nil).InitGenesis), arg0)
+}
+
+// EstimateAccess mocks base method
+func (m *MockStateFactory) EstimateAccess(arg0 *iotxaddress.Address) (int, error) {
+	ret := m.ctrl.Call(m, "EstimateAccess", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EstimateAccess indicates an expected call of EstimateAccess
+func (mr *MockStateFactoryMockRecorder) EstimateAccess(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateAccess", reflect.TypeOf((*MockStateFactory)(nil).EstimateAccess), arg0)
+}
+
+// BalanceAtRoot mocks base method
+func (m *MockStateFactory) BalanceAtRoot(arg0 common.Hash32B, arg1 iotxaddress.Address) (*big.Int, error) {
+	ret := m.ctrl.Call(m, "BalanceAtRoot", arg0, arg1)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BalanceAtRoot indicates an expected call of BalanceAtRoot
+func (mr *MockStateFactoryMockRecorder) BalanceAtRoot(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BalanceAtRoot", reflect.TypeOf((*MockStateFactory)(nil).BalanceAtRoot), arg0, arg1)
+}
+
+// TopBalances mocks base method
+func (m *MockStateFactory) TopBalances(arg0 int) ([]*statefactory.State, error) {
+	ret := m.ctrl.Call(m, "TopBalances", arg0)
+	ret0, _ := ret[0].([]*statefactory.State)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopBalances indicates an expected call of TopBalances
+func (mr *MockStateFactoryMockRecorder) TopBalances(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopBalances", reflect.TypeOf((*MockStateFactory)(nil).TopBalances), arg0)
+}
+
+// AccountActivity mocks base method
+func (m *MockStateFactory) AccountActivity(arg0 *iotxaddress.Address) (statefactory.ActivityInfo, error) {
+	ret := m.ctrl.Call(m, "AccountActivity", arg0)
+	ret0, _ := ret[0].(statefactory.ActivityInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AccountActivity indicates an expected call of AccountActivity
+func (mr *MockStateFactoryMockRecorder) AccountActivity(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccountActivity", reflect.TypeOf((*MockStateFactory)(nil).AccountActivity), arg0)
+}
+
+// MockPauser is a mock of Pauser interface
+type MockPauser struct {
+	ctrl     *gomock.Controller
+	recorder *MockPauserMockRecorder
+}
+
+// MockPauserMockRecorder is the mock recorder for MockPauser
+type MockPauserMockRecorder struct {
+	mock *MockPauser
+}
+
+// NewMockPauser creates a new mock instance
+func NewMockPauser(ctrl *gomock.Controller) *MockPauser {
+	mock := &MockPauser{ctrl: ctrl}
+	mock.recorder = &MockPauserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockPauser) EXPECT() *MockPauserMockRecorder {
+	return m.recorder
+}
+
+// Pause mocks base method
+func (m *MockPauser) Pause() {
+	m.ctrl.Call(m, "Pause")
+}
+
+// Pause indicates an expected call of Pause
+func (mr *MockPauserMockRecorder) Pause() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pause", reflect.TypeOf((*MockPauser)(nil).Pause))
+}
+
+// Resume mocks base method
+func (m *MockPauser) Resume() {
+	m.ctrl.Call(m, "Resume")
+}
+
+// Resume indicates an expected call of Resume
+func (mr *MockPauserMockRecorder) Resume() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Resume", reflect.TypeOf((*MockPauser)(nil).Resume))
+}
+
+// MockProver is a mock of Prover interface
+type MockProver struct {
+	ctrl     *gomock.Controller
+	recorder *MockProverMockRecorder
+}
+
+// MockProverMockRecorder is the mock recorder for MockProver
+type MockProverMockRecorder struct {
+	mock *MockProver
+}
+
+// NewMockProver creates a new mock instance
+func NewMockProver(ctrl *gomock.Controller) *MockProver {
+	mock := &MockProver{ctrl: ctrl}
+	mock.recorder = &MockProverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockProver) EXPECT() *MockProverMockRecorder {
+	return m.recorder
+}
+
+// ProofBundle mocks base method
+func (m *MockProver) ProofBundle(arg0 *iotxaddress.Address) (statefactory.ProofBundle, error) {
+	ret := m.ctrl.Call(m, "ProofBundle", arg0)
+	ret0, _ := ret[0].(statefactory.ProofBundle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProofBundle indicates an expected call of ProofBundle
+func (mr *MockProverMockRecorder) ProofBundle(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProofBundle", reflect.TypeOf((*MockProver)(nil).ProofBundle), arg0)
+}
+
+// MultiProof mocks base method
+func (m *MockProver) MultiProof(arg0 []iotxaddress.Address) (statefactory.MultiProof, error) {
+	ret := m.ctrl.Call(m, "MultiProof", arg0)
+	ret0, _ := ret[0].(statefactory.MultiProof)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MultiProof indicates an expected call of MultiProof
+func (mr *MockProverMockRecorder) MultiProof(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MultiProof", reflect.TypeOf((*MockProver)(nil).MultiProof), arg0)
+}
+
+// MockExporter is a mock of Exporter interface
+type MockExporter struct {
+	ctrl     *gomock.Controller
+	recorder *MockExporterMockRecorder
+}
+
+// MockExporterMockRecorder is the mock recorder for MockExporter
+type MockExporterMockRecorder struct {
+	mock *MockExporter
+}
+
+// NewMockExporter creates a new mock instance
+func NewMockExporter(ctrl *gomock.Controller) *MockExporter {
+	mock := &MockExporter{ctrl: ctrl}
+	mock.recorder = &MockExporterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockExporter) EXPECT() *MockExporterMockRecorder {
+	return m.recorder
+}
+
+// ExportState mocks base method
+func (m *MockExporter) ExportState(arg0 io.Writer) (common.Hash32B, error) {
+	ret := m.ctrl.Call(m, "ExportState", arg0)
+	ret0, _ := ret[0].(common.Hash32B)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportState indicates an expected call of ExportState
+func (mr *MockExporterMockRecorder) ExportState(arg0 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportState", reflect.TypeOf((*MockExporter)(nil).ExportState), arg0)
+}
+
+// MockMonitor is a mock of Monitor interface
+type MockMonitor struct {
+	ctrl     *gomock.Controller
+	recorder *MockMonitorMockRecorder
+}
+
+// MockMonitorMockRecorder is the mock recorder for MockMonitor
+type MockMonitorMockRecorder struct {
+	mock *MockMonitor
+}
+
+// NewMockMonitor creates a new mock instance
+func NewMockMonitor(ctrl *gomock.Controller) *MockMonitor {
+	mock := &MockMonitor{ctrl: ctrl}
+	mock.recorder = &MockMonitorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMonitor) EXPECT() *MockMonitorMockRecorder {
+	return m.recorder
+}
+
+// Health mocks base method
+func (m *MockMonitor) Health() statefactory.HealthStatus {
+	ret := m.ctrl.Call(m, "Health")
+	ret0, _ := ret[0].(statefactory.HealthStatus)
+	return ret0
+}
+
+// Health indicates an expected call of Health
+func (mr *MockMonitorMockRecorder) Health() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Health", reflect.TypeOf((*MockMonitor)(nil).Health))
+}
+
+// PendingChanges mocks base method
+func (m *MockMonitor) PendingChanges() []statefactory.AddressChange {
+	ret := m.ctrl.Call(m, "PendingChanges")
+	ret0, _ := ret[0].([]statefactory.AddressChange)
+	return ret0
+}
+
+// PendingChanges indicates an expected call of PendingChanges
+func (mr *MockMonitorMockRecorder) PendingChanges() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingChanges", reflect.TypeOf((*MockMonitor)(nil).PendingChanges))
+}
+
+// LastCommitWrites mocks base method
+func (m *MockMonitor) LastCommitWrites() []statefactory.KVWrite {
+	ret := m.ctrl.Call(m, "LastCommitWrites")
+	ret0, _ := ret[0].([]statefactory.KVWrite)
+	return ret0
+}
+
+// LastCommitWrites indicates an expected call of LastCommitWrites
+func (mr *MockMonitorMockRecorder) LastCommitWrites() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastCommitWrites", reflect.TypeOf((*MockMonitor)(nil).LastCommitWrites))
+}
+
+// MockRandSourcer is a mock of RandSourcer interface
+type MockRandSourcer struct {
+	ctrl     *gomock.Controller
+	recorder *MockRandSourcerMockRecorder
+}
+
+// MockRandSourcerMockRecorder is the mock recorder for MockRandSourcer
+type MockRandSourcerMockRecorder struct {
+	mock *MockRandSourcer
+}
+
+// NewMockRandSourcer creates a new mock instance
+func NewMockRandSourcer(ctrl *gomock.Controller) *MockRandSourcer {
+	mock := &MockRandSourcer{ctrl: ctrl}
+	mock.recorder = &MockRandSourcerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockRandSourcer) EXPECT() *MockRandSourcerMockRecorder {
+	return m.recorder
+}
+
+// RandSource mocks base method
+func (m *MockRandSourcer) RandSource() *rand.Rand {
+	ret := m.ctrl.Call(m, "RandSource")
+	ret0, _ := ret[0].(*rand.Rand)
+	return ret0
+}
+
+// RandSource indicates an expected call of RandSource
+func (mr *MockRandSourcerMockRecorder) RandSource() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RandSource", reflect.TypeOf((*MockRandSourcer)(nil).RandSource))
+}
+
+// MockApprover is a mock of Approver interface
+type MockApprover struct {
+	ctrl     *gomock.Controller
+	recorder *MockApproverMockRecorder
+}
+
+// MockApproverMockRecorder is the mock recorder for MockApprover
+type MockApproverMockRecorder struct {
+	mock *MockApprover
+}
+
+// NewMockApprover creates a new mock instance
+func NewMockApprover(ctrl *gomock.Controller) *MockApprover {
+	mock := &MockApprover{ctrl: ctrl}
+	mock.recorder = &MockApproverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockApprover) EXPECT() *MockApproverMockRecorder {
+	return m.recorder
+}
+
+// Approve mocks base method
+func (m *MockApprover) Approve(arg0, arg1 *iotxaddress.Address, arg2 *big.Int) error {
+	ret := m.ctrl.Call(m, "Approve", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Approve indicates an expected call of Approve
+func (mr *MockApproverMockRecorder) Approve(arg0, arg1, arg2 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Approve", reflect.TypeOf((*MockApprover)(nil).Approve), arg0, arg1, arg2)
+}
+
+// Allowance mocks base method
+func (m *MockApprover) Allowance(arg0, arg1 *iotxaddress.Address) (*big.Int, error) {
+	ret := m.ctrl.Call(m, "Allowance", arg0, arg1)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allowance indicates an expected call of Allowance
+func (mr *MockApproverMockRecorder) Allowance(arg0, arg1 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allowance", reflect.TypeOf((*MockApprover)(nil).Allowance), arg0, arg1)
+}
+
+// TransferFrom mocks base method
+func (m *MockApprover) TransferFrom(arg0, arg1, arg2 *iotxaddress.Address, arg3 *big.Int) error {
+	ret := m.ctrl.Call(m, "TransferFrom", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferFrom indicates an expected call of TransferFrom
+func (mr *MockApproverMockRecorder) TransferFrom(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferFrom", reflect.TypeOf((*MockApprover)(nil).TransferFrom), arg0, arg1, arg2, arg3)
+}
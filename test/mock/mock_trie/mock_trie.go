@@ -7,6 +7,7 @@ package mock_trie
 import (
 	gomock "github.com/golang/mock/gomock"
 	common "github.com/iotexproject/iotex-core/common"
+	trie "github.com/iotexproject/iotex-core/trie"
 	reflect "reflect"
 )
 
@@ -71,10 +72,11 @@ func (mr *MockTrieMockRecorder) Delete(arg0 interface{}) *gomock.Call {
 }
 
 // Commit mocks base method
-func (m *MockTrie) Commit(arg0, arg1 [][]byte) error {
+func (m *MockTrie) Commit(arg0, arg1 [][]byte) (trie.CommitStats, error) {
 	ret := m.ctrl.Call(m, "Commit", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(trie.CommitStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Commit indicates an expected call of Commit
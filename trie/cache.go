@@ -0,0 +1,147 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"bytes"
+	"container/list"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultCacheSize is the number of committed nodes kept in memory
+	defaultCacheSize = 4096
+)
+
+type (
+	// nodeCache is a bounded LRU cache of committed nodes, keyed by node hash
+	nodeCache struct {
+		size  int
+		lru   *list.List
+		items map[string]*list.Element
+	}
+
+	cacheEntry struct {
+		key   string
+		value []byte
+	}
+)
+
+// newNodeCache creates a cache holding up to size nodes
+func newNodeCache(size int) *nodeCache {
+	return &nodeCache{size: size, lru: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the cached node and marks it as most recently used
+func (c *nodeCache) get(key []byte) ([]byte, bool) {
+	e, ok := c.items[string(key)]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+// put adds the node to the cache, evicting the least recently used node when full
+func (c *nodeCache) put(key, value []byte) {
+	if e, ok := c.items[string(key)]; ok {
+		e.Value.(*cacheEntry).value = value
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.items[string(key)] = c.lru.PushFront(&cacheEntry{string(key), value})
+	for c.lru.Len() > c.size {
+		c.remove([]byte(c.lru.Back().Value.(*cacheEntry).key))
+	}
+}
+
+// remove evicts the node from the cache
+func (c *nodeCache) remove(key []byte) {
+	if e, ok := c.items[string(key)]; ok {
+		c.lru.Remove(e)
+		delete(c.items, string(key))
+	}
+}
+
+// len returns the number of cached nodes
+func (c *nodeCache) len() int {
+	return c.lru.Len()
+}
+
+// Evict drops the nodes on the path of the key from the cache, both as of the last commit and as of now, so they are
+// read from DB again
+// Nodes are keyed by hash, so a cached node only differs from DB if DB was changed behind the trie, e.g. a corrupted
+// node repaired by an operator. The nodes on the path are read from DB rather than the cache to find the next one.
+func (t *trie) Evict(key []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, root := range []patricia{t.committed, t.root} {
+		if err := t.evictPath(root, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evictPath drops the nodes below the root on the path of the key from the cache, the path ends where the key does
+// not exist
+func (t *trie) evictPath(ptr patricia, key []byte) error {
+	rest := key
+	for len(rest) > 0 {
+		hashn, match, err := ptr.descend(rest)
+		if err != nil {
+			return nil
+		}
+		if match == len(rest) {
+			// the value is held by the terminal node, or its leaf for a branch
+			if _, ok := ptr.(*branch); ok {
+				t.cache.remove(hashn)
+			}
+			return nil
+		}
+		t.cache.remove(hashn)
+		// a node deleted since the last commit is still in DB until the next one
+		node := t.dirty[string(hashn)]
+		if node == nil {
+			if node, err = t.dao.Get(t.bucket, hashn); err != nil {
+				return errors.Wrapf(err, "failed to get key %x", hashn[:8])
+			}
+		}
+		if ptr, err = decodePatricia(node); err != nil {
+			return err
+		}
+		rest = rest[match:]
+	}
+	return nil
+}
+
+// checkCache returns ErrInvalidTrie if a cached node is not the node stored in DB under its hash
+func (t *trie) checkCache() error {
+	for e := t.cache.lru.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*cacheEntry)
+		node, err := t.dao.Get(t.bucket, []byte(entry.key))
+		if err != nil {
+			return errors.Wrapf(err, "cached node %x is not in DB", entry.key[:8])
+		}
+		if !bytes.Equal(node, entry.value) {
+			return errors.Wrapf(ErrInvalidTrie, "cached node %x differs from DB", entry.key[:8])
+		}
+	}
+	return nil
+}
+
+// verifyCache panics if the cache has drifted from DB in builds with the strictcache tag, otherwise it does nothing
+// Every node in the cache is read from DB again, so the check is only meant for tests.
+func (t *trie) verifyCache() {
+	if !strictCache {
+		return
+	}
+	if err := t.checkCache(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+// +build !strictcache
+
+package trie
+
+// strictCache makes every change to the trie check the cache against DB and panic if they differ
+const strictCache = false
@@ -0,0 +1,57 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"bytes"
+	"sort"
+)
+
+// KVWrite is a record a commit wrote to or deleted from DB
+type KVWrite struct {
+	Namespace string
+	Key       []byte
+	OldValue  []byte // nil if the key was not in DB or could not be read
+	NewValue  []byte // nil if the key was deleted
+}
+
+// CaptureWrites turns recording the records each commit writes on or off, see LastCommitWrites
+// Recording reads every key a commit writes from DB beforehand to learn its old value, so it is meant for debugging.
+func (t *trie) CaptureWrites(enable bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.capture = enable
+	t.writes = nil
+}
+
+// LastCommitWrites returns the records the last commit wrote while recording was on, sorted by key, nil if it was off
+// or the commit failed
+func (t *trie) LastCommitWrites() []KVWrite {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.writes
+}
+
+// readWrites returns the records a commit is about to write with their old values read from DB, sorted by key
+func (t *trie) readWrites(putK, putV, delK [][]byte) []KVWrite {
+	writes := make([]KVWrite, 0, len(putK)+len(delK))
+	for i, k := range putK {
+		writes = append(writes, KVWrite{Namespace: t.bucket, Key: k, NewValue: putV[i]})
+	}
+	for _, k := range delK {
+		writes = append(writes, KVWrite{Namespace: t.bucket, Key: k})
+	}
+	for i := range writes {
+		if old, err := t.dao.Get(t.bucket, writes[i].Key); err == nil {
+			writes[i].OldValue = old
+		}
+	}
+	sort.Slice(writes, func(i, j int) bool {
+		return bytes.Compare(writes[i].Key, writes[j].Key) < 0
+	})
+	return writes
+}
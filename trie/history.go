@@ -0,0 +1,107 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+)
+
+// ErrRootNotFound is the error that the nodes of a root are no longer in DB, or never were
+var ErrRootNotFound = errors.New("root not found")
+
+// RetainCommits keeps the nodes a commit makes stale in DB for the n commits that follow, so the roots of the last n
+// commits can still be read with GetAtRoot
+// By default a commit deletes the nodes it makes stale at once, n = 0 goes back to that. Lowering n prunes the
+// oldest of the retained commits at the next commit.
+func (t *trie) RetainCommits(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	t.keep = n
+}
+
+// GetAtRoot retrieves an entry as of the committed root, which must be the last one or one of the retained commits
+// A root whose nodes have been pruned returns ErrRootNotFound, as does a root that was never committed.
+func (t *trie) GetAtRoot(root common.Hash32B, key []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if root == EmptyRoot {
+		return nil, errors.Wrapf(ErrNotExist, "key = %x not exist", key)
+	}
+	ptr := t.committed
+	if root != t.committed.hash() {
+		var err error
+		if ptr, err = t.getCommittedPatricia(root[:]); err != nil {
+			return nil, rootNotFound(root, err)
+		}
+	}
+	value, err := t.getFrom(ptr, key)
+	return value, rootNotFound(root, err)
+}
+
+// rootNotFound maps a node missing under the root to ErrRootNotFound, a root may have been pruned partially if a
+// deletion failed
+func rootNotFound(root common.Hash32B, err error) error {
+	if errors.Cause(err) == db.ErrNotExist {
+		return errors.Wrapf(ErrRootNotFound, "root = %x", root)
+	}
+	return err
+}
+
+// pruning returns whether the node is stale but still kept in DB, for the retained commits or the snapshots
+func (t *trie) pruning(key []byte) bool {
+	_, ok := t.stale[string(key)]
+	return ok || t.retained[string(key)]
+}
+
+// prune deletes the nodes a commit has made stale, once the retained commits are past it
+// A node deleted while snapshots are pinned is kept for them instead. If deleting fails the stale nodes are left in
+// DB, which does not affect the trie.
+func (t *trie) prune(stale [][]byte) error {
+	t.commits++
+	if t.keep > 0 || len(t.staleLog) > 0 {
+		if t.stale == nil {
+			t.stale = make(map[string]uint64)
+		}
+		for _, k := range stale {
+			t.stale[string(k)] = t.commits
+		}
+		t.staleLog = append(t.staleLog, stale)
+		stale = nil
+		for len(t.staleLog) > t.keep {
+			// the nodes written again or made stale again since belong to a later commit
+			first := t.commits - uint64(len(t.staleLog)) + 1
+			for _, k := range t.staleLog[0] {
+				if n, ok := t.stale[string(k)]; ok && n == first {
+					delete(t.stale, string(k))
+					stale = append(stale, k)
+				}
+			}
+			t.staleLog = t.staleLog[1:]
+		}
+	}
+	for _, k := range stale {
+		if t.pins > 0 {
+			if t.retained == nil {
+				t.retained = make(map[string]bool)
+			}
+			t.retained[string(k)] = true
+			continue
+		}
+		// GetAtRoot or walking a snapshot may have read the node back into the cache
+		t.cache.remove(k)
+		if err := t.dao.Delete(t.bucket, k); err != nil {
+			return errors.Wrapf(err, "failed to delete key = %x", k[:8])
+		}
+	}
+	return nil
+}
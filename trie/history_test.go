@@ -0,0 +1,78 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/common"
+	"github.com/iotexproject/iotex-core/db"
+)
+
+func TestGetAtRoot(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	tri := tr.(*trie)
+	tri.RetainCommits(2)
+	keys := [][]byte{{1, 2, 3, 4}, {1, 2, 3, 5}, {1, 2, 7, 8}, {9, 9, 9, 9}}
+	var roots [4]common.Hash32B
+	for i := range roots {
+		// every commit changes every entry, the value of a key at the commit starts with the commit's number
+		for j, k := range keys {
+			assert.Nil(tr.Upsert(k, []byte{byte(i), byte(j)}))
+		}
+		_, err = tr.Commit(nil, nil)
+		assert.Nil(err)
+		roots[i] = tr.RootHash()
+	}
+
+	// the last commit and the two before are kept, the first is pruned
+	for i := 1; i < len(roots); i++ {
+		for j, k := range keys {
+			v, err := tri.GetAtRoot(roots[i], k)
+			assert.Nil(err)
+			assert.Equal([]byte{byte(i), byte(j)}, v)
+		}
+	}
+	_, err = tri.GetAtRoot(roots[0], keys[0])
+	assert.Equal(ErrRootNotFound, errors.Cause(err))
+	_, err = tri.GetAtRoot(roots[1], []byte{5, 5, 5, 5})
+	assert.Equal(ErrNotExist, errors.Cause(err))
+	_, err = tri.GetAtRoot(EmptyRoot, keys[0])
+	assert.Equal(ErrNotExist, errors.Cause(err))
+
+	// a value going back to that of a retained commit revives its nodes, they are not pruned with the commit
+	for j, k := range keys {
+		assert.Nil(tr.Upsert(k, []byte{1, byte(j)}))
+	}
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	assert.Equal(roots[1], tr.RootHash())
+	tri.RetainCommits(0)
+	assert.Nil(tr.Upsert(keys[3], []byte{7}))
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	for i, root := range roots[2:] {
+		_, err = tri.GetAtRoot(root, keys[0])
+		assert.Equal(ErrRootNotFound, errors.Cause(err), i)
+	}
+	assert.Equal(0, len(tri.stale))
+	tri.cache = newNodeCache(defaultCacheSize)
+	for j, k := range keys[:3] {
+		v, err := tr.Get(k)
+		assert.Nil(err)
+		assert.Equal([]byte{1, byte(j)}, v)
+	}
+	v, err := tr.Get(keys[3])
+	assert.Nil(err)
+	assert.Equal([]byte{7}, v)
+}
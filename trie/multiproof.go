@@ -0,0 +1,97 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+)
+
+// MultiProof returns the serialized nodes on the paths of the keys as of the last commit, each node once
+// The paths share the nodes near the root, so the nodes are fewer than those of the keys' proofs put together. The
+// nodes are in the order they are first met proving the keys in turn.
+func (t *trie) MultiProof(keys [][]byte) ([][]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var nodes [][]byte
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		proof, err := t.proof(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range proof {
+			if !seen[string(node)] {
+				seen[string(node)] = true
+				nodes = append(nodes, node)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// VerifyMultiProof checks the nodes prove every key under the root hash and returns the values, in the order of keys
+// The path of each key is picked out of the nodes by hash and checked as VerifyProof does.
+func VerifyMultiProof(root common.Hash32B, keys [][]byte, nodes [][]byte) ([][]byte, error) {
+	byHash := make(map[common.Hash32B]provenNode, len(nodes))
+	for i, node := range nodes {
+		ptr, err := decodePatricia(node)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidProof, "node %d: %v", i, err)
+		}
+		byHash[ptr.hash()] = provenNode{ptr, node}
+	}
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		var err error
+		if values[i], err = VerifyProof(root, key, pickPath(root, key, byHash)); err != nil {
+			return nil, errors.Wrapf(err, "key %x", key)
+		}
+	}
+	return values, nil
+}
+
+// provenNode is a node of a multiproof, decoded and as serialized
+type provenNode struct {
+	ptr  patricia
+	node []byte
+}
+
+// pickPath returns the nodes leading from the root hash down the key, as far as the nodes go
+// Every node taken consumes part of the key but for an empty extension, so a path is never longer than the key plus
+// the nodes, which bounds the walk whatever the nodes are.
+func pickPath(root common.Hash32B, key []byte, byHash map[common.Hash32B]provenNode) [][]byte {
+	var path [][]byte
+	expected := root
+	rest := key
+	for len(path) <= len(key)+len(byHash) {
+		n, ok := byHash[expected]
+		if !ok {
+			break
+		}
+		path = append(path, n.node)
+		var next []byte
+		switch ptr := n.ptr.(type) {
+		case *branch:
+			if len(rest) > 0 {
+				next = ptr.Path[rest[0]]
+				rest = rest[1:]
+			}
+		case *leaf:
+			if ptr.Ext == 1 && len(rest) >= len(ptr.Path) {
+				next = ptr.Value
+				rest = rest[len(ptr.Path):]
+			}
+		}
+		if len(next) != len(expected) {
+			break
+		}
+		copy(expected[:], next)
+	}
+	return path
+}
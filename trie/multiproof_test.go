@@ -0,0 +1,62 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+func TestMultiProof(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	tri := tr.(*trie)
+	keys := [][]byte{ham, car, cat, rat, egg, dog, fox, cow, ant}
+	values := append(testV[:], []byte("rat"))
+	_, err = tr.Commit(keys, values)
+	assert.Nil(err)
+	root := tr.RootHash()
+
+	// keys sharing their upper nodes take fewer nodes than their proofs together
+	proven := [][]byte{car, cat, cow, ham}
+	nodes, err := tri.MultiProof(proven)
+	assert.Nil(err)
+	separate := 0
+	for _, key := range proven {
+		proof, err := tri.Proof(key)
+		assert.Nil(err)
+		separate += len(proof)
+	}
+	assert.True(len(nodes) < separate)
+	got, err := VerifyMultiProof(root, proven, nodes)
+	assert.Nil(err)
+	assert.Equal([][]byte{testV[1], testV[2], testV[7], testV[0]}, got)
+
+	// every key must be proven under the root
+	_, err = VerifyMultiProof(root, append(proven, dog), nodes)
+	assert.Equal(ErrInvalidProof, errors.Cause(err))
+	assert.Nil(tr.Upsert(cat, []byte("kitten")))
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	_, err = VerifyMultiProof(tr.RootHash(), proven, nodes)
+	assert.Equal(ErrInvalidProof, errors.Cause(err))
+	for j := range nodes {
+		tampered := append([][]byte{}, nodes...)
+		tampered[j] = append([]byte{}, nodes[j]...)
+		tampered[j][len(tampered[j])-2] ^= 1
+		_, err = VerifyMultiProof(root, proven, tampered)
+		assert.Equal(ErrInvalidProof, errors.Cause(err))
+	}
+	_, err = tri.MultiProof([][]byte{car, {9, 9, 9, 9, 9, 9, 9, 9}})
+	assert.Equal(ErrNotExist, errors.Cause(err))
+}
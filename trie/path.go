@@ -0,0 +1,31 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+// PathNodes returns the number of nodes on the path from the root to the key, the leaf holding its value included
+// For a key not in the trie they are the nodes down to where its path diverges, those an insertion would rewrite. The
+// changes made since the last commit are taken into account, nothing is changed.
+func (t *trie) PathNodes(key []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ptr, size, err := t.query(key)
+	defer t.clear()
+	if ptr == nil && err != nil {
+		return 0, err
+	}
+	nodes := 0
+	for e := t.toRoot.Front(); e != nil; e = e.Next() {
+		if _, ok := e.Value.(patricia); ok {
+			nodes++
+		}
+	}
+	// a branch holds the value in the leaf below it
+	if _, ok := ptr.(*branch); ok && size == len(key) {
+		nodes++
+	}
+	return nodes, nil
+}
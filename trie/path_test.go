@@ -0,0 +1,63 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+func TestPathNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	pt := tr.(*trie)
+	shallow := []byte{0x10, 1, 2, 3, 4, 5, 6, 7}
+	deep := []byte{0x20, 1, 2, 3, 4, 5, 6, 7}
+	assert.Nil(tr.Upsert(shallow, []byte("shallow")))
+	assert.Nil(tr.Upsert(deep, []byte("deep")))
+	// the root branch and the leaf
+	nodes, err := pt.PathNodes(shallow)
+	assert.Nil(err)
+	assert.Equal(2, nodes)
+
+	// keys sharing a longer prefix push the deep key further down
+	neighbors := [][]byte{{0x20, 1, 2, 3, 9}, {0x20, 1, 2, 3, 4, 5, 9}, {0x20, 1, 2, 3, 4, 5, 6, 9}}
+	prev := 2
+	for _, k := range neighbors {
+		assert.Nil(tr.Upsert(append(k, make([]byte, 8-len(k))...), k))
+		nodes, err = pt.PathNodes(deep)
+		assert.Nil(err)
+		assert.True(nodes > prev)
+		prev = nodes
+		nodes, err = pt.PathNodes(shallow)
+		assert.Nil(err)
+		assert.Equal(2, nodes)
+	}
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	nodes, err = pt.PathNodes(deep)
+	assert.Nil(err)
+	assert.Equal(prev, nodes)
+
+	// a missing key reports the path down to where it diverges, nothing changes
+	root := tr.RootHash()
+	nodes, err = pt.PathNodes([]byte{0x20, 1, 2, 3, 4, 5, 6, 8})
+	assert.Nil(err)
+	assert.True(nodes > 2)
+	nodes, err = pt.PathNodes([]byte{0x30, 1, 2, 3, 4, 5, 6, 7})
+	assert.Nil(err)
+	assert.Equal(1, nodes)
+	assert.Equal(root, tr.RootHash())
+	value, err := tr.Get(deep)
+	assert.Nil(err)
+	assert.Equal([]byte("deep"), value)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+)
+
+// ErrInvalidProof is the error that a proof does not lead from the root hash to a value of the key
+var ErrInvalidProof = errors.New("invalid proof")
+
+// Proof returns the serialized nodes on the path of the key as of the last commit, from the root down to the leaf
+// holding the value, changes made since the last commit are not proven
+func (t *trie) Proof(key []byte) ([][]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.proof(key)
+}
+
+// proof returns the proof of the key as of the last commit, the trie must be locked
+func (t *trie) proof(key []byte) ([][]byte, error) {
+	root, err := t.committed.serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode root")
+	}
+	proof := [][]byte{root}
+	ptr := t.committed
+	rest := key
+	for len(rest) > 0 {
+		hashn, match, err := ptr.descend(rest)
+		if err != nil {
+			break
+		}
+		if match == len(rest) {
+			// the value is held by the terminal node, or its leaf for a branch
+			if _, ok := ptr.(*branch); ok {
+				node, err := t.getCommittedNode(hashn)
+				if err != nil {
+					return nil, err
+				}
+				proof = append(proof, node)
+				if ptr, err = decodePatricia(node); err != nil {
+					return nil, err
+				}
+			}
+			if _, _, err := ptr.blob(); err != nil {
+				return nil, err
+			}
+			return proof, nil
+		}
+		node, err := t.getCommittedNode(hashn)
+		if err != nil {
+			return nil, err
+		}
+		proof = append(proof, node)
+		if ptr, err = decodePatricia(node); err != nil {
+			return nil, err
+		}
+		rest = rest[match:]
+	}
+	return nil, errors.Wrapf(ErrNotExist, "key = %x not exist", key)
+}
+
+// CommittedRootHash returns the root hash as of the last commit, the root the proofs returned by Proof lead from
+func (t *trie) CommittedRootHash() common.Hash32B {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.committed.hash()
+}
+
+// VerifyProof checks the proof of the key against the root hash and returns the value it proves
+// Every node must hash to the reference its parent holds, the first one to the root, and the path of the nodes must
+// spell the whole key, ending at a leaf. The nodes are decoded from untrusted bytes, so the key is matched against
+// them here rather than through the trie's own traversal.
+func VerifyProof(root common.Hash32B, key []byte, proof [][]byte) ([]byte, error) {
+	expected := root[:]
+	rest := key
+	for i, node := range proof {
+		ptr, err := decodePatricia(node)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidProof, "node %d: %v", i, err)
+		}
+		if hash := ptr.hash(); !bytes.Equal(hash[:], expected) {
+			return nil, errors.Wrapf(ErrInvalidProof, "node %d does not match the hash %x", i, expected)
+		}
+		last := i == len(proof)-1
+		switch n := ptr.(type) {
+		case *branch:
+			if last || len(rest) == 0 || n.Path[rest[0]] == nil {
+				return nil, errors.Wrapf(ErrInvalidProof, "branch %d does not lead to the key", i)
+			}
+			expected = n.Path[rest[0]]
+			rest = rest[1:]
+		case *leaf:
+			if !bytes.HasPrefix(rest, n.Path) {
+				return nil, errors.Wrapf(ErrInvalidProof, "node %d diverges from the key", i)
+			}
+			rest = rest[len(n.Path):]
+			if n.Ext == 1 {
+				if last {
+					return nil, errors.Wrapf(ErrInvalidProof, "extension %d does not lead to the key", i)
+				}
+				expected = n.Value
+				continue
+			}
+			if !last || len(rest) > 0 {
+				return nil, errors.Wrapf(ErrInvalidProof, "leaf %d does not hold the key", i)
+			}
+			return n.Value, nil
+		}
+	}
+	return nil, errors.Wrap(ErrInvalidProof, "proof ends before the key")
+}
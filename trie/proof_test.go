@@ -0,0 +1,57 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+func TestProof(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	tri := tr.(*trie)
+	keys := [][]byte{ham, car, cat, rat, egg, dog, fox, cow, ant}
+	values := append(testV[:], []byte("rat"))
+	_, err = tr.Commit(keys, values)
+	assert.Nil(err)
+	root := tr.RootHash()
+
+	// uncommitted changes are not proven
+	assert.Nil(tr.Upsert(cat, []byte("kitten")))
+	for i, key := range keys {
+		proof, err := tri.Proof(key)
+		assert.Nil(err)
+		value, err := VerifyProof(root, key, proof)
+		assert.Nil(err)
+		assert.Equal(values[i], value)
+
+		// the proof is for its key and root only
+		_, err = VerifyProof(tr.RootHash(), key, proof)
+		assert.Equal(ErrInvalidProof, errors.Cause(err))
+		_, err = VerifyProof(root, keys[(i+1)%len(keys)], proof)
+		assert.Equal(ErrInvalidProof, errors.Cause(err))
+		_, err = VerifyProof(root, key, proof[:len(proof)-1])
+		assert.Equal(ErrInvalidProof, errors.Cause(err))
+		// a changed node no longer matches the hash its parent holds
+		for j := range proof {
+			tampered := append([][]byte{}, proof...)
+			tampered[j] = append([]byte{}, proof[j]...)
+			tampered[j][len(tampered[j])-2] ^= 1
+			_, err = VerifyProof(root, key, tampered)
+			assert.Equal(ErrInvalidProof, errors.Cause(err))
+		}
+	}
+	_, err = tri.Proof([]byte{9, 9, 9, 9, 9, 9, 9, 9})
+	assert.Equal(ErrNotExist, errors.Cause(err))
+}
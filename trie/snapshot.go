@@ -0,0 +1,121 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/common"
+)
+
+// Snapshot is a view of the trie as of a commit, the nodes it needs are kept in DB until it is released
+type Snapshot struct {
+	t       *trie
+	root    patricia
+	release sync.Once
+}
+
+// Snapshot pins the trie as of the last commit, changes made since are not seen
+// Commits go on meanwhile, but the nodes they make stale are only deleted from DB once every snapshot is released.
+// DeleteAll does not wait, walking a snapshot taken before it fails.
+func (t *trie) Snapshot() (*Snapshot, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// keep a copy of the committed root, the trie replaces it on the next commit
+	root, err := t.committed.serialize()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode root")
+	}
+	ptr, err := decodePatricia(root)
+	if err != nil {
+		return nil, err
+	}
+	t.pins++
+	return &Snapshot{t: t, root: ptr}, nil
+}
+
+// RootHash returns the root hash the snapshot was taken at
+func (s *Snapshot) RootHash() common.Hash32B {
+	return s.root.hash()
+}
+
+// Walk calls fn with every entry of the snapshot in ascending key order, stopping at the first error fn returns
+// The trie is only locked while each node is read, so writers are not held up by a long walk. fn must not modify the
+// key or the value.
+func (s *Snapshot) Walk(fn func(key, value []byte) error) error {
+	return s.walk(s.root, nil, fn)
+}
+
+// Release unpins the snapshot, the stale nodes it kept are deleted once no other snapshot is left
+// Releasing a snapshot again is a no-op.
+func (s *Snapshot) Release() error {
+	var err error
+	s.release.Do(func() {
+		err = s.t.unpin()
+	})
+	return err
+}
+
+// walk calls fn with the entries below the node, whose path from the root is prefix
+func (s *Snapshot) walk(ptr patricia, prefix []byte, fn func(key, value []byte) error) error {
+	// the full slice expression makes every append copy, so siblings never share the key
+	prefix = prefix[:len(prefix):len(prefix)]
+	switch n := ptr.(type) {
+	case *branch:
+		for i, hash := range n.Path {
+			if hash == nil {
+				continue
+			}
+			child, err := s.node(hash)
+			if err != nil {
+				return err
+			}
+			if err := s.walk(child, append(prefix, byte(i)), fn); err != nil {
+				return err
+			}
+		}
+	case *leaf:
+		path := append(prefix, n.Path...)
+		if n.Ext == 0 {
+			return fn(path, n.Value)
+		}
+		child, err := s.node(n.Value)
+		if err != nil {
+			return err
+		}
+		return s.walk(child, path, fn)
+	}
+	return nil
+}
+
+// node reads the node with the hash as of the last commit, which a pinned snapshot's nodes always are
+func (s *Snapshot) node(hash []byte) (patricia, error) {
+	s.t.mu.Lock()
+	defer s.t.mu.Unlock()
+	return s.t.getCommittedPatricia(hash)
+}
+
+// unpin releases a snapshot, deleting the stale nodes kept for the snapshots once the last one is released
+func (t *trie) unpin() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pins--
+	if t.pins > 0 {
+		return nil
+	}
+	for k := range t.retained {
+		// walking a snapshot or GetAtRoot may have read the node back into the cache
+		t.cache.remove([]byte(k))
+		if err := t.dao.Delete(t.bucket, []byte(k)); err != nil {
+			return errors.Wrapf(err, "failed to delete key = %x", k[:8])
+		}
+		delete(t.retained, k)
+	}
+	return nil
+}
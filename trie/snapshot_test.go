@@ -0,0 +1,79 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+func TestSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	keys := [][]byte{{1, 2, 3, 4}, {1, 2, 3, 5}, {1, 2, 7, 8}, {9, 9, 9, 9}, {0, 1, 2, 3}}
+	for i, k := range keys {
+		assert.Nil(tr.Upsert(k, []byte{byte(i)}))
+	}
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	root := tr.RootHash()
+	// entries in key order
+	expected := [][]byte{keys[4], keys[0], keys[1], keys[2], keys[3]}
+	values := [][]byte{{4}, {0}, {1}, {2}, {3}}
+	walk := func(s *Snapshot) ([][]byte, [][]byte) {
+		var k, v [][]byte
+		assert.Nil(s.Walk(func(key, value []byte) error {
+			k, v = append(k, key), append(v, value)
+			return nil
+		}))
+		return k, v
+	}
+
+	// uncommitted changes are not seen, commits after the snapshot neither
+	assert.Nil(tr.Upsert(keys[0], []byte{9}))
+	s, err := tr.(*trie).Snapshot()
+	assert.Nil(err)
+	assert.Equal(root, s.RootHash())
+	assert.Nil(tr.Delete(keys[1]))
+	assert.Nil(tr.Upsert([]byte{1, 2, 3, 6}, []byte{6}))
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	// a node made stale and then written again stays after the release
+	assert.Nil(tr.Upsert(keys[0], []byte{0}))
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	k, v := walk(s)
+	assert.Equal(expected, k)
+	assert.Equal(values, v)
+
+	// the stale nodes are deleted once the snapshot is released
+	assert.NotEqual(0, len(tr.(*trie).retained))
+	assert.Nil(s.Release())
+	assert.Nil(s.Release())
+	assert.Equal(0, len(tr.(*trie).retained))
+	tr.(*trie).cache = newNodeCache(defaultCacheSize)
+	s, err = tr.(*trie).Snapshot()
+	assert.Nil(err)
+	assert.Equal(tr.RootHash(), s.RootHash())
+	k, v = walk(s)
+	assert.Equal([][]byte{keys[4], keys[0], {1, 2, 3, 6}, keys[2], keys[3]}, k)
+	assert.Equal([][]byte{{4}, {0}, {6}, {2}, {3}}, v)
+	assert.Nil(s.Release())
+
+	// rebuilding the snapshot's entries gives its root back
+	rebuilt, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	for i := range expected {
+		assert.Nil(rebuilt.Upsert(expected[i], values[i]))
+	}
+	assert.Equal(root, rebuilt.RootHash())
+}
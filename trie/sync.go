@@ -0,0 +1,80 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+// SyncMode is when the nodes a commit writes are flushed to disk, see SyncPolicy
+type SyncMode int
+
+const (
+	// SyncAlways flushes after every commit, a commit that returned survives an OS crash or a power loss
+	SyncAlways SyncMode = iota
+	// SyncPeriodic flushes after a number of commits or a time since the last flush, whichever comes first. A process
+	// crash loses nothing as the OS still holds the writes, but an OS crash or a power loss loses the commits since the
+	// last flush and may leave a BoltDB file corrupted.
+	SyncPeriodic
+	// SyncNever leaves flushing to the OS, with the risks of SyncPeriodic for every commit since the OS last flushed
+	SyncNever
+)
+
+// SyncPolicy is how often the KV store is flushed to disk after commits, see SetSyncPolicy
+type SyncPolicy struct {
+	Mode     SyncMode
+	Commits  int           // SyncPeriodic flushes after this many commits, 0 for no limit
+	Interval time.Duration // SyncPeriodic flushes at the first commit this long after the last flush, 0 for no limit
+}
+
+// SetSyncPolicy makes the KV store skip flushing its own writes and flushes it after commits as the policy says
+// Without a policy the store flushes as it does by default, BoltDB after every write, so even SyncAlways saves the
+// flushes of the several writes a commit makes. The intervals are checked at commits, there is no background flush,
+// and Close flushes the commits SyncPeriodic has not. The store is shared, its other users are affected as well. It
+// is a no-op if the KV store cannot defer flushing.
+func (t *trie) SetSyncPolicy(policy SyncPolicy) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	syncer, ok := t.dao.(db.Syncer)
+	if !ok {
+		return
+	}
+	syncer.SetNoSync(true)
+	t.syncer, t.policy, t.unsynced, t.lastSync = syncer, policy, 0, time.Now()
+}
+
+// syncCommit flushes the KV store after a commit if the sync policy says so
+func (t *trie) syncCommit() error {
+	if t.syncer == nil {
+		return nil
+	}
+	t.unsynced++
+	switch t.policy.Mode {
+	case SyncNever:
+		return nil
+	case SyncPeriodic:
+		due := (t.policy.Commits > 0 && t.unsynced >= t.policy.Commits) ||
+			(t.policy.Interval > 0 && time.Since(t.lastSync) >= t.policy.Interval)
+		if !due {
+			return nil
+		}
+	}
+	return t.syncDB()
+}
+
+// syncDB flushes the KV store to disk
+func (t *trie) syncDB() error {
+	if err := t.syncer.Sync(); err != nil {
+		return errors.Wrap(err, "failed to sync DB")
+	}
+	t.unsynced, t.lastSync = 0, time.Now()
+	return nil
+}
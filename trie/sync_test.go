@@ -0,0 +1,81 @@
+// Copyright (c) 2018 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided ‘as is’ and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package trie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/iotexproject/iotex-core/db"
+)
+
+// syncRecorder is an in-memory KV store recording the calls to defer and force flushing to disk
+type syncRecorder struct {
+	db.KVStore
+	noSync bool
+	syncs  int
+}
+
+func (s *syncRecorder) SetNoSync(noSync bool) { s.noSync = noSync }
+
+func (s *syncRecorder) Sync() error {
+	s.syncs++
+	return nil
+}
+
+func TestSyncPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	// commits the number of times with a new entry each and returns the number of syncs
+	commits := func(tr Trie, dao *syncRecorder, n int) int {
+		before := dao.syncs
+		for i := 0; i < n; i++ {
+			_, err := tr.Commit([][]byte{{byte(i), 1, 2, 3}}, [][]byte{{byte(i)}})
+			assert.Nil(err)
+		}
+		return dao.syncs - before
+	}
+	newTrie := func(policy *SyncPolicy) (Trie, *syncRecorder) {
+		dao := &syncRecorder{KVStore: db.NewMemKVStore()}
+		tr, err := NewTrieSharedDB(dao)
+		assert.Nil(err)
+		if policy != nil {
+			tr.(*trie).SetSyncPolicy(*policy)
+		}
+		return tr, dao
+	}
+
+	// without a policy the store flushes on its own
+	tr, dao := newTrie(nil)
+	assert.Equal(0, commits(tr, dao, 3))
+	assert.False(dao.noSync)
+
+	tr, dao = newTrie(&SyncPolicy{Mode: SyncAlways})
+	assert.True(dao.noSync)
+	assert.Equal(5, commits(tr, dao, 5))
+
+	tr, dao = newTrie(&SyncPolicy{Mode: SyncNever})
+	assert.True(dao.noSync)
+	assert.Equal(0, commits(tr, dao, 5))
+	assert.Nil(tr.Close())
+	assert.Equal(0, dao.syncs)
+
+	// every 3 commits, the last 2 are flushed by Close
+	tr, dao = newTrie(&SyncPolicy{Mode: SyncPeriodic, Commits: 3})
+	assert.Equal(2, commits(tr, dao, 8))
+	assert.Nil(tr.Close())
+	assert.Equal(3, dao.syncs)
+
+	// by time only
+	tr, dao = newTrie(&SyncPolicy{Mode: SyncPeriodic, Interval: time.Hour})
+	assert.Equal(0, commits(tr, dao, 4))
+	tr, dao = newTrie(&SyncPolicy{Mode: SyncPeriodic, Interval: time.Nanosecond})
+	time.Sleep(time.Millisecond)
+	assert.Equal(1, commits(tr, dao, 1))
+}
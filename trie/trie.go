@@ -7,7 +7,10 @@
 package trie
 
 import (
+	"bytes"
 	"container/list"
+	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -16,6 +19,9 @@ import (
 	"github.com/iotexproject/iotex-core/logger"
 )
 
+// dirtyNodesPerEntry is about how many nodes an inserted entry changes, its leaf and the branches on its path
+const dirtyNodesPerEntry = 4
+
 var (
 	trieKVNameSpace = "Trie"
 
@@ -27,26 +33,37 @@ var (
 )
 
 var (
-	// emptyRoot is the root hash of an empty trie
-	emptyRoot = common.Hash32B{0xe, 0x57, 0x51, 0xc0, 0x26, 0xe5, 0x43, 0xb2, 0xe8, 0xab, 0x2e, 0xb0, 0x60, 0x99,
+	// EmptyRoot is the root hash of an empty trie
+	EmptyRoot = common.Hash32B{0xe, 0x57, 0x51, 0xc0, 0x26, 0xe5, 0x43, 0xb2, 0xe8, 0xab, 0x2e, 0xb0, 0x60, 0x99,
 		0xda, 0xa1, 0xd1, 0xe5, 0xdf, 0x47, 0x77, 0x8f, 0x77, 0x87, 0xfa, 0xab, 0x45, 0xcd, 0xf1, 0x2f, 0xe3, 0xa8}
 )
 
 type (
 	// Trie is the interface of Merkle Patricia Trie
 	Trie interface {
-		Upsert([]byte, []byte) error     // insert a new entry
-		Get([]byte) ([]byte, error)      // retrieve an existing entry
-		Delete([]byte) error             // delete an entry
-		Commit([][]byte, [][]byte) error // commit the state changes in a batch
-		Close() error                    // close the trie DB
-		RootHash() common.Hash32B        // returns trie's root hash
+		Upsert([]byte, []byte) error                    // insert a new entry
+		Get([]byte) ([]byte, error)                     // retrieve an existing entry
+		Delete([]byte) error                            // delete an entry
+		Commit([][]byte, [][]byte) (CommitStats, error) // upsert the entries and commit the state changes in a batch
+		Close() error                                   // close the trie DB
+		RootHash() common.Hash32B                       // returns trie's root hash
+	}
+
+	// CommitStats is what a commit has written to DB
+	CommitStats struct {
+		Leaves int // number of leaf nodes written
+		Nodes  int // number of branch and extension nodes written
+		Bytes  int // total bytes of the nodes written
 	}
 
 	// trie implements the Trie interface
 	trie struct {
+		mu        sync.Mutex // guards all the fields below, even reads move toRoot
 		dao       db.KVStore
+		dirty     map[string][]byte // nodes written since last commit, nil value means the node is deleted
+		cache     *nodeCache        // committed nodes recently read from or written to DB
 		root      patricia
+		committed patricia   // copy of the root at last commit
 		toRoot    *list.List // stores the path from root to diverging node
 		bucket    string     // bucket name to store the nodes
 		clpsK     []byte     // path if the node can collapse after deleting an entry
@@ -55,6 +72,18 @@ type (
 		numBranch uint64
 		numExt    uint64
 		numLeaf   uint64
+		capture   bool      // commits record what they write, see CaptureWrites
+		writes    []KVWrite // records the last commit wrote, if capture is set
+		syncer    db.Syncer // flushes dao as policy says, nil if no sync policy is set
+		policy    SyncPolicy
+		unsynced  int               // commits since dao was last flushed
+		lastSync  time.Time         // time dao was last flushed
+		pins      int               // snapshots not released yet, see Snapshot
+		retained  map[string]bool   // stale nodes kept in DB for the snapshots
+		keep      int               // commits whose stale nodes are kept in DB, see RetainCommits
+		commits   uint64            // commits made, numbering the stale nodes
+		stale     map[string]uint64 // commit each node kept for the retained commits went stale at
+		staleLog  [][][]byte        // nodes made stale by each of the retained commits, oldest first
 	}
 )
 
@@ -64,20 +93,170 @@ func NewTrie(path string) (Trie, error) {
 	if dao == nil {
 		return nil, errors.New("Cannot create boltDB file")
 	}
-	t := trie{dao: dao, root: &branch{}, toRoot: list.New(), bucket: trieKVNameSpace, numEntry: 1, numBranch: 1}
 	if err := dao.Start(); err != nil {
 		return nil, err
 	}
+	return NewTrieSharedDB(dao)
+}
+
+// NewTrieSharedDB creates a trie on an already started KV store, which may be shared with other namespaces
+func NewTrieSharedDB(dao db.KVStore) (Trie, error) {
+	if dao == nil {
+		return nil, errors.Wrap(ErrInvalidTrie, "KV store is nil")
+	}
+	t := trie{dao: dao, dirty: make(map[string][]byte), cache: newNodeCache(defaultCacheSize), root: &branch{},
+		committed: &branch{}, toRoot: list.New(), bucket: trieKVNameSpace, numEntry: 1, numBranch: 1}
 	return &t, nil
 }
 
-// Close close the DB
+// Close close the DB, flushing the commits a periodic sync policy has not flushed yet
 func (t *trie) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.syncer != nil && t.policy.Mode == SyncPeriodic && t.unsynced > 0 {
+		if err := t.syncDB(); err != nil {
+			return err
+		}
+	}
 	return t.dao.Stop()
 }
 
+// Compact compacts the DB to reclaim the space of deleted nodes, it is a no-op if the DB does not support compaction
+func (t *trie) Compact() error {
+	if c, ok := t.dao.(db.Compacter); ok {
+		return c.Compact()
+	}
+	return nil
+}
+
+// ReadOnly returns whether the KV store rejects writes, a trie on such a store can only be read
+func (t *trie) ReadOnly() bool {
+	r, ok := t.dao.(db.ReadOnlyStore)
+	return ok && r.ReadOnly()
+}
+
+// DeleteAll removes every node of the trie from the DB, leaving the trie empty, other namespaces are untouched
+func (t *trie) DeleteAll() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	d, ok := t.dao.(db.NamespaceDeleter)
+	if !ok {
+		return errors.Wrap(ErrInvalidTrie, "DB cannot delete a namespace")
+	}
+	if err := d.DeleteNamespace(t.bucket); err != nil {
+		return err
+	}
+	t.dirty = make(map[string][]byte)
+	t.cache = newNodeCache(defaultCacheSize)
+	t.root = &branch{}
+	t.committed = &branch{}
+	t.toRoot = list.New()
+	t.clpsK, t.clpsV = nil, nil
+	t.numEntry, t.numBranch, t.numExt, t.numLeaf = 1, 1, 0, 0
+	t.retained = nil
+	t.stale, t.staleLog = nil, nil
+	return nil
+}
+
 // Upsert a new entry
 func (t *trie) Upsert(key, value []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.verifyCache()
+	return t.upsert(key, value)
+}
+
+// Get an existing entry
+func (t *trie) Get(key []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.get(key)
+}
+
+// Delete an entry
+func (t *trie) Delete(key []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.verifyCache()
+	return t.delEntry(key)
+}
+
+// Commit upserts an array of entries <k[], v[]>, then persists all nodes changed since last commit as a batch
+func (t *trie) Commit(k, v [][]byte) (CommitStats, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer t.verifyCache()
+	if len(k) != len(v) {
+		return CommitStats{}, errors.Wrap(ErrInvalidTrie, "commit <k, v> size not match")
+	}
+	for i := range k {
+		if err := t.upsert(k[i], v[i]); err != nil {
+			return CommitStats{}, err
+		}
+	}
+	stats, err := t.flush()
+	if err != nil {
+		return stats, err
+	}
+	return stats, t.syncCommit()
+}
+
+// GetCommitted retrieves an entry as of the last commit, ignoring the changes made since
+func (t *trie) GetCommitted(key []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.getFrom(t.committed, key)
+}
+
+// getFrom retrieves an entry below the committed node ptr
+func (t *trie) getFrom(ptr patricia, key []byte) ([]byte, error) {
+	rest := key
+	for len(rest) > 0 {
+		hashn, match, err := ptr.descend(rest)
+		if err != nil {
+			break
+		}
+		if match == len(rest) {
+			// the value is held by the terminal node, or its leaf for a branch
+			if br, ok := ptr.(*branch); ok {
+				if ptr, err = t.getCommittedPatricia(br.Path[rest[len(rest)-1]]); err != nil {
+					return nil, err
+				}
+			}
+			_, v, err := ptr.blob()
+			return v, err
+		}
+		if ptr, err = t.getCommittedPatricia(hashn); err != nil {
+			return nil, err
+		}
+		rest = rest[match:]
+	}
+	return nil, errors.Wrapf(ErrNotExist, "key = %x not exist", key)
+}
+
+// RootHash returns the root hash of merkle patricia trie
+func (t *trie) RootHash() common.Hash32B {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.root.hash()
+}
+
+// Reserve pre-sizes the set of nodes changed since last commit for about n more entries to be inserted before the next
+// commit, it only saves reallocation and has no effect on the content of the trie
+func (t *trie) Reserve(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n <= 0 || len(t.dirty) > 0 {
+		return
+	}
+	t.dirty = make(map[string][]byte, n*dirtyNodesPerEntry)
+}
+
+//======================================
+// private functions
+//======================================
+// upsert inserts or updates an entry
+func (t *trie) upsert(key, value []byte) error {
 	var ptr patricia
 	var size int
 	var err error
@@ -149,8 +328,8 @@ func (t *trie) Upsert(key, value []byte) error {
 	return t.updateInsert(hashChild[:])
 }
 
-// Get an existing entry
-func (t *trie) Get(key []byte) ([]byte, error) {
+// get retrieves an existing entry
+func (t *trie) get(key []byte) ([]byte, error) {
 	ptr, size, err := t.query(key)
 	t.clear()
 	if size != len(key) {
@@ -164,8 +343,8 @@ func (t *trie) Get(key []byte) ([]byte, error) {
 	return t.getValue(ptr, key[size-1])
 }
 
-// Delete an entry
-func (t *trie) Delete(key []byte) error {
+// delEntry deletes an entry
+func (t *trie) delEntry(key []byte) error {
 	var ptr patricia
 	var size int
 	var err error
@@ -206,19 +385,6 @@ func (t *trie) Delete(key []byte) error {
 	return t.updateDelete(ptr, childClps, clpsType)
 }
 
-// Commit writes an array <k[], v[]> as a batch
-func (t *trie) Commit(k, v [][]byte) error {
-	return t.dao.BatchPut(t.bucket, k, v)
-}
-
-// RootHash returns the root hash of merkle patricia trie
-func (t *trie) RootHash() common.Hash32B {
-	return t.root.hash()
-}
-
-//======================================
-// private functions
-//======================================
 // query returns the diverging patricia node, and length of matching path in bytes
 func (t *trie) query(key []byte) (patricia, int, error) {
 	ptr := t.root
@@ -387,10 +553,40 @@ func (t *trie) updateDelete(curr patricia, currClps bool, clpsType byte) error {
 //======================================
 // getPatricia retrieves the patricia node from DB according to key
 func (t *trie) getPatricia(key []byte) (patricia, error) {
-	node, err := t.dao.Get(t.bucket, key)
+	node, err := t.getNode(key)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get key %x", key[:8])
 	}
+	return decodePatricia(node)
+}
+
+// getCommittedPatricia retrieves the patricia node as of the last commit, skipping the nodes changed since
+func (t *trie) getCommittedPatricia(key []byte) (patricia, error) {
+	node, err := t.getCommittedNode(key)
+	if err != nil {
+		return nil, err
+	}
+	return decodePatricia(node)
+}
+
+// getCommittedNode returns the serialized node as of the last commit, skipping the nodes changed since
+func (t *trie) getCommittedNode(key []byte) ([]byte, error) {
+	node, ok := t.cache.get(key)
+	if !ok {
+		var err error
+		if node, err = t.dao.Get(t.bucket, key); err != nil {
+			return nil, errors.Wrapf(err, "failed to get key %x", key[:8])
+		}
+		t.cache.put(key, node)
+	}
+	return node, nil
+}
+
+// decodePatricia de-serializes a patricia node
+func decodePatricia(node []byte) (patricia, error) {
+	if len(node) == 0 {
+		return nil, errors.Wrap(ErrInvalidPatricia, "empty node")
+	}
 	var ptr patricia
 	// first byte of serialized data is type
 	switch node[0] {
@@ -417,24 +613,33 @@ func (t *trie) putPatricia(ptr patricia) error {
 		return errors.Wrapf(err, "failed to encode node")
 	}
 	key := ptr.hash()
-	if err := t.dao.Put(t.bucket, key[:], value); err != nil {
-		return errors.Wrapf(err, "failed to put key = %x", key[:8])
-	}
+	t.dirty[string(key[:])] = value
 	logger.Debug().Hex("key", key[:8]).Msg("put")
 	return nil
 }
 
 // putPatriciaNew stores a new patricia node into DB
-// it is expected the node does not exist yet, will return error if already exist
+// it is expected the node does not exist yet, storing an identical node again is a no-op, will return error if a
+// different node already exists under the hash
 func (t *trie) putPatriciaNew(ptr patricia) error {
 	value, err := ptr.serialize()
 	if err != nil {
 		return err
 	}
 	key := ptr.hash()
-	if err := t.dao.PutIfNotExists(t.bucket, key[:], value); err != nil {
-		return errors.Wrapf(err, "failed to put non-existing key = %x", key[:8])
+	if node, err := t.getNode(key[:]); err == nil {
+		// nodes are keyed by hash, the same node may come back when an entry returns to a previous value or a stale
+		// node has been left in DB
+		if bytes.Equal(node, value) {
+			if t.pruning(key[:]) {
+				// the stale node is live again, writing it keeps it from being pruned
+				t.dirty[string(key[:])] = value
+			}
+			return nil
+		}
+		return errors.Wrapf(db.ErrAlreadyExist, "failed to put non-existing key = %x", key[:8])
 	}
+	t.dirty[string(key[:])] = value
 	logger.Debug().Hex("key", key[:8]).Msg("putnew")
 	return nil
 }
@@ -442,13 +647,85 @@ func (t *trie) putPatriciaNew(ptr patricia) error {
 // delPatricia deletes the patricia node from DB
 func (t *trie) delPatricia(ptr patricia) error {
 	key := ptr.hash()
-	if err := t.dao.Delete(t.bucket, key[:]); err != nil {
-		return errors.Wrapf(err, "failed to delete key = %x", key[:8])
-	}
+	t.dirty[string(key[:])] = nil
 	logger.Debug().Hex("key", key[:8]).Msg("del")
 	return nil
 }
 
+// getNode returns the serialized node, nodes changed since last commit and recently used nodes are served from
+// memory, other nodes are read through from DB
+func (t *trie) getNode(key []byte) ([]byte, error) {
+	if node, ok := t.dirty[string(key)]; ok {
+		if node == nil {
+			return nil, errors.Wrapf(db.ErrNotExist, "key = %x", key)
+		}
+		return node, nil
+	}
+	if node, ok := t.cache.get(key); ok {
+		return node, nil
+	}
+	node, err := t.dao.Get(t.bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	t.cache.put(key, node)
+	return node, nil
+}
+
+// flush persists the nodes changed since last commit, new nodes are written in a single batch
+func (t *trie) flush() (CommitStats, error) {
+	var putK, putV, delK [][]byte
+	var stats CommitStats
+	for k, v := range t.dirty {
+		if v == nil {
+			delK = append(delK, []byte(k))
+			continue
+		}
+		putK = append(putK, []byte(k))
+		putV = append(putV, v)
+		// first byte of serialized leaf node is 0
+		if v[0] == 0 {
+			stats.Leaves++
+		} else {
+			stats.Nodes++
+		}
+		stats.Bytes += len(v)
+	}
+	t.writes = nil
+	var writes []KVWrite
+	if t.capture {
+		writes = t.readWrites(putK, putV, delK)
+	}
+	if len(putK) > 0 {
+		if err := t.dao.BatchPut(t.bucket, putK, putV); err != nil {
+			return CommitStats{}, errors.Wrap(err, "failed to commit nodes")
+		}
+	}
+	for i := range putK {
+		t.cache.put(putK[i], putV[i])
+		// a node written again is live, it must outlive the snapshots and the retained commits
+		delete(t.retained, string(putK[i]))
+		delete(t.stale, string(putK[i]))
+	}
+	for _, k := range delK {
+		t.cache.remove(k)
+	}
+	if err := t.prune(delK); err != nil {
+		return stats, err
+	}
+	t.writes = writes
+	t.dirty = make(map[string][]byte)
+	// keep a copy of the root, later changes to the root are made in place
+	root, err := t.root.serialize()
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to encode root")
+	}
+	if t.committed, err = decodePatricia(root); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
 // getValue returns the actual value stored in patricia node
 func (t *trie) getValue(ptr patricia, index byte) ([]byte, error) {
 	br, isBranch := ptr.(*branch)
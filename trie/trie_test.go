@@ -1,11 +1,14 @@
 package trie
 
 import (
+	"bytes"
 	"container/list"
+	"math/big"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/blake2b"
 
@@ -21,7 +24,7 @@ func TestEmptyTrie(t *testing.T) {
 	defer os.Remove(testTriePath)
 	tr, err := NewTrie(testTriePath)
 	assert.Nil(err)
-	assert.Equal(tr.RootHash(), emptyRoot)
+	assert.Equal(tr.RootHash(), EmptyRoot)
 	assert.Nil(tr.Close())
 }
 
@@ -29,8 +32,9 @@ func TestInsert(t *testing.T) {
 	assert := assert.New(t)
 	logger.UseDebugLogger()
 
-	tr := trie{dao: db.NewMemKVStore(), root: &branch{}, toRoot: list.New(), numEntry: 1, numBranch: 1}
-	root := emptyRoot
+	tr := trie{dao: db.NewMemKVStore(), dirty: make(map[string][]byte), cache: newNodeCache(defaultCacheSize),
+		root: &branch{}, toRoot: list.New(), numEntry: 1, numBranch: 1}
+	root := EmptyRoot
 	assert.Equal(uint64(1), tr.numBranch)
 	// query non-existing entry
 	ptr, match, err := tr.query(cat)
@@ -217,7 +221,7 @@ func TestInsert(t *testing.T) {
 	logger.Info().Msg("Del[cat]")
 	err = tr.Delete(cat)
 	assert.Nil(err)
-	assert.Equal(emptyRoot, tr.RootHash())
+	assert.Equal(EmptyRoot, tr.RootHash())
 	assert.Equal(uint64(1), tr.numEntry)
 }
 
@@ -228,7 +232,7 @@ func Test1kEntries(t *testing.T) {
 	defer os.Remove(testTriePath)
 	tr, err := NewTrie(testTriePath)
 	assert.Nil(err)
-	root := emptyRoot
+	root := EmptyRoot
 	seed := time.Now().Nanosecond()
 	// insert 64k entries
 	var k [32]byte
@@ -243,7 +247,7 @@ func Test1kEntries(t *testing.T) {
 		err := tr.Upsert(k[:8], v)
 		assert.Nil(err)
 		newRoot := tr.RootHash()
-		assert.NotEqual(newRoot, emptyRoot)
+		assert.NotEqual(newRoot, EmptyRoot)
 		assert.NotEqual(newRoot, root)
 		root = newRoot
 		b, err := tr.Get(k[:8])
@@ -274,7 +278,7 @@ func Test1kEntries(t *testing.T) {
 	assert.Nil(tr.Delete(d2[:8]))
 	assert.Nil(tr.Delete(d3[:8]))
 	// trie should fallback to empty
-	assert.Equal(emptyRoot, tr.RootHash())
+	assert.Equal(EmptyRoot, tr.RootHash())
 }
 
 func TestPressure(t *testing.T) {
@@ -288,7 +292,7 @@ func TestPressure(t *testing.T) {
 	defer os.Remove(testTriePath)
 	tr, err := NewTrie(testTriePath)
 	assert.Nil(err)
-	root := emptyRoot
+	root := EmptyRoot
 	seed := time.Now().Nanosecond()
 	// insert 64k entries
 	var k [32]byte
@@ -303,7 +307,7 @@ func TestPressure(t *testing.T) {
 		err := tr.Upsert(k[:8], v)
 		assert.Nil(err)
 		newRoot := tr.RootHash()
-		assert.NotEqual(newRoot, emptyRoot)
+		assert.NotEqual(newRoot, EmptyRoot)
 		assert.NotEqual(newRoot, root)
 		root = newRoot
 		b, err := tr.Get(k[:8])
@@ -327,5 +331,200 @@ func TestPressure(t *testing.T) {
 	assert.Nil(tr.Delete(d2[:8]))
 	assert.Nil(tr.Delete(d3[:8]))
 	// trie should fallback to empty
-	assert.Equal(emptyRoot, tr.RootHash())
+	assert.Equal(EmptyRoot, tr.RootHash())
+}
+
+func TestCommit(t *testing.T) {
+	assert := assert.New(t)
+
+	dao := db.NewMemKVStore()
+	tr, err := NewTrieSharedDB(dao)
+	assert.Nil(err)
+
+	// nodes are kept in memory until commit
+	assert.Nil(tr.Upsert(cat, testV[2]))
+	root := tr.RootHash()
+	_, err = dao.Get(trieKVNameSpace, root[:])
+	assert.NotNil(err)
+	b, err := tr.Get(cat)
+	assert.Nil(err)
+	assert.Equal(testV[2], b)
+
+	// commit upserts the entries and persists all changed nodes
+	_, err = tr.Commit([][]byte{rat}, [][]byte{testV[3]})
+	assert.Nil(err)
+	root = tr.RootHash()
+	_, err = dao.Get(trieKVNameSpace, root[:])
+	assert.Nil(err)
+	b, err = tr.Get(rat)
+	assert.Nil(err)
+	assert.Equal(testV[3], b)
+
+	// deleted nodes are removed from DB on commit
+	assert.Nil(tr.Delete(rat))
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	_, err = dao.Get(trieKVNameSpace, root[:])
+	assert.NotNil(err)
+	_, err = tr.Get(rat)
+	assert.NotNil(err)
+
+	_, err = tr.Commit([][]byte{rat}, nil)
+	assert.NotNil(err)
+}
+
+func TestCaptureWrites(t *testing.T) {
+	assert := assert.New(t)
+
+	dao := db.NewMemKVStore()
+	tr, err := NewTrieSharedDB(dao)
+	assert.Nil(err)
+	tri := tr.(*trie)
+	_, err = tr.Commit([][]byte{cat}, [][]byte{testV[2]})
+	assert.Nil(err)
+	assert.Nil(tri.LastCommitWrites())
+
+	// the new leaf and the nodes above it are written, the replaced ones deleted
+	tri.CaptureWrites(true)
+	_, err = tr.Commit([][]byte{cat, rat}, [][]byte{testV[0], testV[3]})
+	assert.Nil(err)
+	writes := tri.LastCommitWrites()
+	assert.NotEqual(0, len(writes))
+	var catPut, ratPut, catDeleted bool
+	for i, w := range writes {
+		if i > 0 {
+			assert.Equal(-1, bytes.Compare(writes[i-1].Key, w.Key))
+		}
+		stored, err := dao.Get(w.Namespace, w.Key)
+		if w.NewValue == nil {
+			assert.NotNil(err)
+			catDeleted = catDeleted || bytes.Contains(w.OldValue, testV[2])
+			continue
+		}
+		assert.Equal(w.NewValue, stored)
+		catPut = catPut || bytes.Contains(w.NewValue, testV[0])
+		ratPut = ratPut || bytes.Contains(w.NewValue, testV[3])
+	}
+	assert.True(catPut)
+	assert.True(ratPut)
+	assert.True(catDeleted)
+
+	tri.CaptureWrites(false)
+	_, err = tr.Commit([][]byte{cat}, [][]byte{testV[1]})
+	assert.Nil(err)
+	assert.Nil(tri.LastCommitWrites())
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	assert := assert.New(t)
+
+	defer os.Remove(testTriePath)
+	tr, err := NewTrie(testTriePath)
+	assert.Nil(err)
+	balance := big.NewInt(1234567).Bytes()
+	_, err = tr.Commit([][]byte{cat, rat}, [][]byte{testV[2], balance})
+	assert.Nil(err)
+	tri := tr.(*trie)
+	assert.NotEqual(0, tri.cache.len())
+
+	// evict all nodes, reading a cold entry loads the nodes on its path from DB
+	tri.cache = newNodeCache(defaultCacheSize)
+	b, err := tr.Get(rat)
+	assert.Nil(err)
+	assert.Equal(0, new(big.Int).SetBytes(b).Cmp(big.NewInt(1234567)))
+	assert.NotEqual(0, tri.cache.len())
+
+	// the cache stays bounded
+	tri.cache = newNodeCache(1)
+	b, err = tr.Get(cat)
+	assert.Nil(err)
+	assert.Equal(testV[2], b)
+	assert.Equal(1, tri.cache.len())
+	b, err = tr.Get(rat)
+	assert.Nil(err)
+	assert.Equal(balance, b)
+	assert.Nil(tr.Close())
+}
+
+func TestEvict(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	balance := big.NewInt(1234567).Bytes()
+	_, err = tr.Commit([][]byte{cat, rat, dog}, [][]byte{testV[2], balance, testV[3]})
+	assert.Nil(err)
+	tri := tr.(*trie)
+	assert.Nil(tri.checkCache())
+
+	// desync the cache by holding cat's leaf under the hash of rat's leaf
+	ratProof, err := tri.Proof(rat)
+	assert.Nil(err)
+	catProof, err := tri.Proof(cat)
+	assert.Nil(err)
+	ratLeaf, err := decodePatricia(ratProof[len(ratProof)-1])
+	assert.Nil(err)
+	ratHash := ratLeaf.hash()
+	tri.cache.put(ratHash[:], catProof[len(catProof)-1])
+	assert.Equal(ErrInvalidTrie, errors.Cause(tri.checkCache()))
+	b, err := tr.Get(rat)
+	assert.Nil(err)
+	assert.NotEqual(balance, b)
+
+	// evicting the path of another key leaves the stale node
+	assert.Nil(tri.Evict(dog))
+	assert.NotNil(tri.checkCache())
+	assert.Nil(tri.Evict(rat))
+	assert.Nil(tri.checkCache())
+	b, err = tr.Get(rat)
+	assert.Nil(err)
+	assert.Equal(balance, b)
+
+	// changes since the last commit and keys not in the trie are fine to evict too
+	assert.Nil(tr.Upsert(rat, testV[1]))
+	assert.Nil(tri.Evict(rat))
+	assert.Nil(tri.Evict(fox))
+	b, err = tr.Get(rat)
+	assert.Nil(err)
+	assert.Equal(testV[1], b)
+	assert.Nil(tri.checkCache())
+}
+
+func TestGetCommitted(t *testing.T) {
+	assert := assert.New(t)
+
+	tr, err := NewTrieSharedDB(db.NewMemKVStore())
+	assert.Nil(err)
+	tri := tr.(*trie)
+	_, err = tri.GetCommitted(cat)
+	assert.Equal(ErrNotExist, errors.Cause(err))
+
+	_, err = tr.Commit([][]byte{cat, rat}, [][]byte{testV[2], testV[3]})
+	assert.Nil(err)
+	// uncommitted changes are seen by Get but not by GetCommitted
+	assert.Nil(tr.Upsert(cat, testV[4]))
+	assert.Nil(tr.Upsert(car, testV[1]))
+	assert.Nil(tr.Delete(rat))
+	b, err := tr.Get(cat)
+	assert.Nil(err)
+	assert.Equal(testV[4], b)
+	b, err = tri.GetCommitted(cat)
+	assert.Nil(err)
+	assert.Equal(testV[2], b)
+	b, err = tri.GetCommitted(rat)
+	assert.Nil(err)
+	assert.Equal(testV[3], b)
+	_, err = tri.GetCommitted(car)
+	assert.Equal(ErrNotExist, errors.Cause(err))
+
+	_, err = tr.Commit(nil, nil)
+	assert.Nil(err)
+	b, err = tri.GetCommitted(cat)
+	assert.Nil(err)
+	assert.Equal(testV[4], b)
+	b, err = tri.GetCommitted(car)
+	assert.Nil(err)
+	assert.Equal(testV[1], b)
+	_, err = tri.GetCommitted(rat)
+	assert.Equal(ErrNotExist, errors.Cause(err))
 }
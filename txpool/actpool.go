@@ -140,6 +140,15 @@ func (ap *actPool) validateTx(tx *trx.Tx) error {
 	if nonce > tx.Nonce {
 		return errors.Wrapf(ErrNonce, "nonce too low")
 	}
+	// Reject transaction claiming to be signed by a contract account
+	isContract, err := ap.pendingSF.IsContract(from)
+	if err != nil {
+		glog.Errorf("Error when validating Tx: %v\n", err)
+		return err
+	}
+	if isContract {
+		return errors.Wrapf(ErrActPool, "sender is a contract account")
+	}
 	return nil
 }
 